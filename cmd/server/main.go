@@ -14,11 +14,19 @@ import (
 	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
 	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/cache"
 	"github.com/ChaseRain/img2ppt/internal/service/gemini"
 	"github.com/ChaseRain/img2ppt/internal/service/imagegen"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
+	"github.com/ChaseRain/img2ppt/internal/service/ollama"
+	"github.com/ChaseRain/img2ppt/internal/service/openai"
 	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
 	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
 	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/stub"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
 )
 
 func main() {
@@ -35,26 +43,152 @@ func main() {
 	}
 	defer zapLogger.Sync()
 
-	// Init HTTP client
+	// Init HTTP client, optionally recording or replaying outbound model traffic
+	var transport http.RoundTripper
+	if mode := cfg.HTTPClient.CassetteMode; mode != "" && mode != httpclient.CassetteOff {
+		cassettes, err := httpclient.NewCassetteTransport(mode, cfg.HTTPClient.CassetteDir, nil)
+		if err != nil {
+			log.Fatalf("failed to init cassette transport: %v", err)
+		}
+		transport = cassettes
+		zapLogger.Info("http cassette enabled", "mode", mode, "dir", cfg.HTTPClient.CassetteDir)
+	}
 	httpClient := httpclient.New(httpclient.Options{
 		Timeout:    time.Duration(cfg.HTTPClient.TimeoutSeconds) * time.Second,
 		MaxRetries: cfg.HTTPClient.MaxRetries,
+		Transport:  transport,
 	})
 
 	// Init limiter
 	lim := limiter.New(cfg.Limiter.MaxConcurrent, cfg.Limiter.RatePerSecond)
 
+	// Init model providers
+	providers := provider.NewRegistry()
+	providers.RegisterAnalyzer("gemini", gemini.NewAnalyzer)
+	providers.RegisterImageGenerator("gemini", imagegen.NewGenerator)
+	providers.RegisterAnalyzer("openai", openai.NewAnalyzer)
+	providers.RegisterImageGenerator("openai", openai.NewImageGenerator)
+	providers.RegisterAnalyzer("ollama", ollama.NewAnalyzer)
+	providers.RegisterAnalyzer("stub", stub.NewAnalyzer)
+	providers.RegisterImageGenerator("stub", stub.NewImageGenerator)
+
+	analyzer, err := providers.Analyzer(cfg.Analyzer.Provider, provider.Settings{
+		APIKey:     cfg.Analyzer.APIKey,
+		Model:      cfg.Analyzer.Model,
+		BaseURL:    cfg.Analyzer.BaseURL,
+		HTTPClient: httpClient,
+		Logger:     zapLogger,
+	})
+	if err != nil {
+		log.Fatalf("failed to init analyzer: %v", err)
+	}
+	imageGen, err := providers.ImageGenerator(cfg.ImageGen.Provider, provider.Settings{
+		APIKey:     cfg.ImageGen.APIKey,
+		Model:      cfg.ImageGen.Model,
+		BaseURL:    cfg.ImageGen.BaseURL,
+		ImageSize:  cfg.ImageGen.Size,
+		HTTPClient: httpClient,
+		Logger:     zapLogger,
+	})
+	if err != nil {
+		log.Fatalf("failed to init image generator: %v", err)
+	}
+
 	// Init services
-	geminiSvc := gemini.New(cfg.Gemini.APIKey, cfg.Gemini.Model, httpClient, zapLogger)
-	imageGenSvc := imagegen.New(cfg.ImageGen.APIKey, cfg.ImageGen.Model, httpClient, zapLogger)
 	pptSvc := ppt.New(zapLogger)
-	storageSvc := storage.New(cfg.Storage.Type, cfg.Storage.BasePath, cfg.Storage.BaseURL, zapLogger)
+	storageClient := httpclient.New(httpclient.Options{
+		Timeout:    time.Duration(cfg.HTTPClient.TimeoutSeconds) * time.Second,
+		MaxRetries: cfg.HTTPClient.MaxRetries,
+	})
+	storageSvc, err := storage.New(storage.Options{
+		Type:     cfg.Storage.Type,
+		BasePath: cfg.Storage.BasePath,
+		BaseURL:  cfg.Storage.BaseURL,
+		S3: storage.S3Options{
+			Bucket:          cfg.Storage.S3.Bucket,
+			Region:          cfg.Storage.S3.Region,
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			PathStyle:       cfg.Storage.S3.UsePathStyle,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			SessionToken:    cfg.Storage.S3.SessionToken,
+			Prefix:          cfg.Storage.S3.Prefix,
+			PartSize:        int64(cfg.Storage.S3.PartSizeMB) << 20,
+		},
+		GCS: storage.GCSOptions{
+			Bucket:          cfg.Storage.GCS.Bucket,
+			Endpoint:        cfg.Storage.GCS.Endpoint,
+			CredentialsFile: cfg.Storage.GCS.CredentialsFile,
+			Prefix:          cfg.Storage.GCS.Prefix,
+			URLMode:         cfg.Storage.GCS.URLMode,
+		},
+		URLSecret: cfg.Storage.URLSecret,
+		URLTTL:    time.Duration(cfg.Storage.URLTTLSeconds) * time.Second,
+	}, storageClient, zapLogger)
+	if err != nil {
+		log.Fatalf("failed to init storage: %v", err)
+	}
+
+	// Init result cache
+	resultCache, err := cache.New(cfg.Cache.Type, cfg.Cache.Path, cfg.Cache.MaxEntries, int64(cfg.Cache.MaxSizeMB)<<20)
+	if err != nil {
+		log.Fatalf("failed to init cache: %v", err)
+	}
+
+	// Init usage accounting
+	ledger, err := usage.NewLedger(cfg.Usage.Store, cfg.Usage.StorePath)
+	if err != nil {
+		log.Fatalf("failed to init usage store: %v", err)
+	}
+	pricing := make(usage.Pricing, len(cfg.Usage.Pricing))
+	for model, price := range cfg.Usage.Pricing {
+		pricing[model] = usage.Price{
+			InputPerMillion:  price.InputPerMillion,
+			OutputPerMillion: price.OutputPerMillion,
+			PerImage:         price.PerImage,
+		}
+	}
+	tracker := usage.NewTracker(pricing, ledger, zapLogger)
 
 	// Init orchestrator
-	orch := orchestrator.New(geminiSvc, imageGenSvc, pptSvc, storageSvc, lim, zapLogger)
+	orch := orchestrator.New(analyzer, imageGen, pptSvc, storageSvc, resultCache, tracker, lim, zapLogger)
+
+	// Init webhook delivery, retries are driven by the webhook service itself
+	// and the transport re-checks every dialed address against private networks
+	webhookClient := httpclient.New(httpclient.Options{
+		Timeout:    time.Duration(cfg.Webhook.TimeoutSeconds) * time.Second,
+		MaxRetries: 0,
+		Transport:  webhook.NewTransport(cfg.Webhook.AllowPrivateNetworks),
+	})
+	webhookStore, err := webhook.NewStore(cfg.Webhook.Store, cfg.Webhook.StorePath)
+	if err != nil {
+		log.Fatalf("failed to init webhook store: %v", err)
+	}
+	if len(cfg.Auth.APIKeys) == 0 && len(cfg.Webhook.ClientSecrets) > 0 {
+		zapLogger.Warn("webhook client_secrets are configured but auth.api_keys is empty, only default_secret will be used")
+	}
+	webhookSvc := webhook.New(webhookClient, webhookStore, webhook.Options{
+		MaxAttempts:   cfg.Webhook.MaxAttempts,
+		Backoff:       time.Duration(cfg.Webhook.BackoffSeconds) * time.Second,
+		DefaultSecret: cfg.Webhook.DefaultSecret,
+		ClientSecrets: cfg.Webhook.ClientSecrets,
+
+		AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+	}, zapLogger)
+
+	// Init async job manager
+	jobStore, err := job.NewStore(cfg.Job.Store, cfg.Job.StorePath)
+	if err != nil {
+		log.Fatalf("failed to init job store: %v", err)
+	}
+	jobs := job.NewManager(jobStore, orch, api.NewWebhookNotifier(webhookSvc, zapLogger), job.Options{
+		Workers:        cfg.Job.Workers,
+		QueueSize:      cfg.Job.QueueSize,
+		IdempotencyTTL: time.Duration(cfg.Job.IdempotencyTTLSeconds) * time.Second,
+	}, zapLogger)
 
 	// Init router
-	router := api.NewRouter(orch, zapLogger)
+	router := api.NewRouter(jobs, webhookSvc, ledger, storageSvc, cfg.Auth.APIKeys, zapLogger)
 
 	// Create server
 	srv := &http.Server{
@@ -84,5 +218,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		zapLogger.Error("server forced to shutdown", "error", err)
 	}
+	jobs.Close()
+	webhookSvc.Close()
 	zapLogger.Info("server stopped")
 }
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// 鉴权请求头，也接受 Authorization: Bearer <key>
+const HeaderAPIKey = "X-API-Key"
+
+const clientIDKey = "client_id"
+
+// authenticate 按 API Key 识别调用方，client_id 只能由此得到，不信任请求体中的值。
+// apiKeys 为 API Key 到 client_id 的映射，为空时不鉴权，所有请求视为匿名客户端（client_id 为空）
+func authenticate(apiKeys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(apiKeys) == 0 {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(HeaderAPIKey)
+		if key == "" {
+			if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				key = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+
+		clientID, ok := lookupAPIKey(apiKeys, key)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+				Error: &GeneratePPTError{
+					Code:    errors.ErrCodeUnauthorized,
+					Message: "missing or invalid API key",
+				},
+			})
+			return
+		}
+		c.Set(clientIDKey, clientID)
+		c.Next()
+	}
+}
+
+// lookupAPIKey 逐个比较全部 API Key，耗时与匹配位置无关
+func lookupAPIKey(apiKeys map[string]string, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	clientID, found := "", false
+	for candidate, id := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			clientID, found = id, true
+		}
+	}
+	return clientID, found
+}
+
+// authenticatedClient 当前请求的调用方，未启用鉴权时为空
+func authenticatedClient(c *gin.Context) string {
+	return c.GetString(clientIDKey)
+}
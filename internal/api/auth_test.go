@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authenticate(map[string]string{"key-a": "client-a", "key-b": "client-b"}))
+	r.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, authenticatedClient(c))
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+		client string
+	}{
+		{"missing key", "", "", http.StatusUnauthorized, ""},
+		{"unknown key", HeaderAPIKey, "key-c", http.StatusUnauthorized, ""},
+		{"api key header", HeaderAPIKey, "key-a", http.StatusOK, "client-a"},
+		{"bearer token", "Authorization", "Bearer key-b", http.StatusOK, "client-b"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && w.Body.String() != tt.client {
+			t.Errorf("%s: client = %q, want %q", tt.name, w.Body.String(), tt.client)
+		}
+	}
+}
+
+func TestAuthenticateDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authenticate(nil))
+	r.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, authenticatedClient(c))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "" {
+		t.Errorf("anonymous request = %d %q, want 200 with empty client", w.Code, w.Body.String())
+	}
+}
@@ -1,15 +1,63 @@
 package api
 
+import (
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
+)
+
 type GeneratePPTRequest struct {
-	ImageBase64     string `json:"image_base64" binding:"required"`
-	Language        string `json:"language"`
-	Style           string `json:"style"`
-	Stream          bool   `json:"stream"`
+	// ImageBase64 与 Images 二选一；Images 中每张图片生成一页，并自动添加封面页
+	ImageBase64 string   `json:"image_base64"`
+	Images      []string `json:"images"`
+	Language    string   `json:"language"`
+	Style       string   `json:"style"`
+	Stream      bool     `json:"stream"`
+	// Async 为 true 时立即返回 202 和 job_id，通过 GET /v1/jobs/{id} 查询结果
+	Async           bool   `json:"async"`
 	ClientRequestID string `json:"client_request_id"`
+	// Mode 为 single（默认）、deck 或 images；deck 模式生成封面页 + SlideCount 页内容页 + 总结页
+	Mode       string `json:"mode"`
+	SlideCount int    `json:"slide_count"`
+	// ClientID 调用方标识，以 API Key 鉴权得到的身份为准，填写时必须与之一致；
+	// CallbackURL 非空时任务结束后以该客户端的密钥签名回调
+	ClientID    string `json:"client_id"`
+	CallbackURL string `json:"callback_url"`
+	// ImageFidelity 配图对原图构图与配色的还原程度：none（默认）、loose 或 strict
+	ImageFidelity string `json:"image_fidelity"`
+	// NoCache 为 true 时跳过结果缓存，重新分析图片并生成配图
+	NoCache bool `json:"no_cache"`
+	// Variants 每页配图的候选数量（1 到 4，0 为默认的 1），大于 1 时可通过 POST /v1/jobs/{id}/select-image 换用其他候选
+	Variants int `json:"variants"`
+}
+
+// ReviseRequest 按自然语言修改意见调整第 Slide 页（从 1 开始，缺省为 1），
+// Target 为 text、image 或 both（默认），修改后生成新版本的 .pptx
+type ReviseRequest struct {
+	Slide       int    `json:"slide"`
+	Instruction string `json:"instruction"`
+	Target      string `json:"target"`
+}
+
+// JobCommandRequest 发往 POST /v1/jobs/{id}/commands 的控制命令
+type JobCommandRequest struct {
+	Command string `json:"command" binding:"required"`
+}
+
+// 任务控制命令
+const (
+	JobCommandCancel = "cancel"
+)
+
+// SelectImageRequest 选择第 Slide 页（从 1 开始，缺省为 1）的第 Candidate 个候选配图（从 0 开始）
+type SelectImageRequest struct {
+	Slide     int `json:"slide"`
+	Candidate int `json:"candidate"`
 }
 
 type GeneratePPTResponse struct {
 	RequestID string            `json:"request_id"`
+	JobID     string            `json:"job_id,omitempty"`
 	Status    string            `json:"status"`
 	PPTURL    string            `json:"ppt_url,omitempty"`
 	Meta      *GeneratePPTMeta  `json:"meta,omitempty"`
@@ -20,6 +68,33 @@ type GeneratePPTMeta struct {
 	Title    string   `json:"title,omitempty"`
 	Subtitle string   `json:"subtitle,omitempty"`
 	Bullets  []string `json:"bullets,omitempty"`
+	Notes    string   `json:"notes,omitempty"`
+	// deck 模式下每页的内容
+	Slides []GeneratePPTSlideMeta `json:"slides,omitempty"`
+	// Cache 分析结果与配图的缓存命中情况
+	Cache *orchestrator.CacheStats `json:"cache,omitempty"`
+	// Usage token 用量、配图张数与估算费用（美元）
+	Usage *usage.Summary `json:"usage,omitempty"`
+	// Version 当前版本号，Versions 为修改与改选配图产生的全部版本，旧版本的地址仍可下载
+	Version  int                       `json:"version,omitempty"`
+	Versions []orchestrator.PPTVersion `json:"versions,omitempty"`
+}
+
+type GeneratePPTSlideMeta struct {
+	Title    string   `json:"title"`
+	Subtitle string   `json:"subtitle,omitempty"`
+	Bullets  []string `json:"bullets,omitempty"`
+	Notes    string   `json:"notes,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// Candidates 配图候选，SelectedCandidate 为当前使用的候选序号
+	Candidates        []GeneratePPTCandidateMeta `json:"candidates,omitempty"`
+	SelectedCandidate int                        `json:"selected_candidate,omitempty"`
+}
+
+type GeneratePPTCandidateMeta struct {
+	Index int    `json:"index"`
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error,omitempty"`
 }
 
 type GeneratePPTError struct {
@@ -27,6 +102,39 @@ type GeneratePPTError struct {
 	Message string `json:"message"`
 }
 
+type JobResponse struct {
+	JobID     string            `json:"job_id"`
+	RequestID string            `json:"request_id"`
+	Status    string            `json:"status"`
+	Stage     string            `json:"stage,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Progress  int               `json:"progress"`
+	PPTURL    string            `json:"ppt_url,omitempty"`
+	Meta      *GeneratePPTMeta  `json:"meta,omitempty"`
+	Error     *GeneratePPTError `json:"error,omitempty"`
+	CreatedAt int64             `json:"created_at"`
+	UpdatedAt int64             `json:"updated_at"`
+}
+
+type WebhookDeliveriesResponse struct {
+	RequestID  string             `json:"request_id"`
+	Deliveries []webhook.Delivery `json:"deliveries"`
+}
+
+// UsageResponse GET /v1/usage 的结果，只包含鉴权得到的调用方 ClientID 的用量；
+// From/To 为查询的 UTC 日期区间（含两端），为空表示不限
+type UsageResponse struct {
+	ClientID string           `json:"client_id,omitempty"`
+	From     string           `json:"from,omitempty"`
+	To       string           `json:"to,omitempty"`
+	Total    usage.Totals     `json:"total"`
+	Keys     []usage.KeyUsage `json:"keys"`
+}
+
+type ErrorResponse struct {
+	Error *GeneratePPTError `json:"error"`
+}
+
 type HealthResponse struct {
 	Status string `json:"status"`
 }
@@ -40,8 +148,17 @@ type StreamEvent struct {
 
 // 各阶段事件数据
 type EventStart struct {
-	Message   string `json:"message"`
-	Timestamp int64  `json:"timestamp"`
+	Message string `json:"message"`
+	// JobID 可用于 DELETE /v1/jobs/{id} 中止本次生成；也可向 CommandsURL 发送 {"command":"cancel"}
+	JobID       string `json:"job_id"`
+	CommandsURL string `json:"commands_url"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// EventCancelled 生成被客户端取消，流随后结束
+type EventCancelled struct {
+	Message string `json:"message"`
+	JobID   string `json:"job_id"`
 }
 
 type EventAnalyzing struct {
@@ -54,9 +171,40 @@ type EventAnalyzed struct {
 	Title    string   `json:"title"`
 	Subtitle string   `json:"subtitle"`
 	Bullets  []string `json:"bullets"`
+	Notes    string   `json:"notes"`
 	Progress int      `json:"progress"`
 }
 
+type EventOutlined struct {
+	Message  string                 `json:"message"`
+	Slides   []GeneratePPTSlideMeta `json:"slides"`
+	Progress int                    `json:"progress"`
+}
+
+type EventSlideProgress struct {
+	Message     string `json:"message"`
+	Index       int    `json:"index"`
+	Total       int    `json:"total"`
+	Title       string `json:"title"`
+	ImagePrompt string `json:"image_prompt,omitempty"`
+	HasImage    bool   `json:"has_image"`
+	Error       string `json:"error,omitempty"`
+	ErrorCode   string `json:"error_code,omitempty"`
+	Progress    int    `json:"progress"`
+}
+
+type EventCandidate struct {
+	Message   string `json:"message"`
+	Index     int    `json:"index"`
+	Total     int    `json:"total"`
+	Variant   int    `json:"variant"`
+	Variants  int    `json:"variants"`
+	URL       string `json:"url,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Progress  int    `json:"progress"`
+}
+
 type EventGenerating struct {
 	Message     string `json:"message"`
 	ImagePrompt string `json:"image_prompt"`
@@ -66,6 +214,9 @@ type EventGenerating struct {
 type EventGenerated struct {
 	Message  string `json:"message"`
 	Progress int    `json:"progress"`
+	// 配图失败时的错误码与信息，如 CONTENT_BLOCKED
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
 type EventRendering struct {
@@ -86,16 +237,26 @@ type EventError struct {
 
 const (
 	StatusPending   = "PENDING"
+	StatusRunning   = "RUNNING"
 	StatusSucceeded = "SUCCEEDED"
 	StatusFailed    = "FAILED"
+	StatusCancelled = "CANCELLED"
 
 	// SSE 事件类型
-	EventTypeStart      = "start"
-	EventTypeAnalyzing  = "analyzing"
-	EventTypeAnalyzed   = "analyzed"
-	EventTypeGenerating = "generating"
-	EventTypeGenerated  = "generated"
-	EventTypeRendering  = "rendering"
-	EventTypeComplete   = "complete"
-	EventTypeError      = "error"
+	EventTypeStart           = "start"
+	EventTypeAnalyzing       = "analyzing"
+	EventTypeAnalyzed        = "analyzed"
+	EventTypeOutlined        = "outlined"
+	EventTypeSlideAnalyzed   = "slide_analyzed"
+	EventTypeGenerating      = "generating"
+	EventTypeGenerated       = "generated"
+	EventTypeSlideGenerating = "slide_generating"
+	EventTypeSlideGenerated  = "slide_generated"
+	EventTypeRendering       = "rendering"
+	EventTypeComplete        = "complete"
+	EventTypeError           = "error"
+	EventTypeCancelled       = "cancelled"
+
+	// EventTypeCandidateGenerated 每张候选配图生成后立即发送
+	EventTypeCandidateGenerated = "candidate_generated"
 )
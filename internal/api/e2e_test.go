@@ -0,0 +1,362 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/cache"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/stub"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
+)
+
+const (
+	e2eKeyA = "key-a"
+	e2eKeyB = "key-b"
+)
+
+// e2eServer 与 cmd/server 相同的装配方式，模型使用离线桩实现，文件保存在临时目录
+type e2eServer struct {
+	*httptest.Server
+	outputDir string
+}
+
+func newE2EServer(t *testing.T) *e2eServer {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers := provider.NewRegistry()
+	providers.RegisterAnalyzer("stub", stub.NewAnalyzer)
+	providers.RegisterImageGenerator("stub", stub.NewImageGenerator)
+	analyzer, err := providers.Analyzer("stub", provider.Settings{Logger: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageGen, err := providers.ImageGenerator("stub", provider.Settings{Logger: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	client := httpclient.New(httpclient.Options{Timeout: 5 * time.Second})
+	files, err := storage.New(storage.Options{
+		Type:      "local",
+		BasePath:  outputDir,
+		BaseURL:   "/files",
+		URLSecret: "e2e-secret",
+		URLTTL:    time.Hour,
+	}, client, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := usage.NewMemoryLedger()
+	orch := orchestrator.New(analyzer, imageGen, ppt.New(log), files, cache.NewMemoryCache(100, 64<<20),
+		usage.NewTracker(usage.Pricing{}, ledger, log), limiter.New(4, 100), log)
+
+	webhooks := webhook.New(httpclient.New(httpclient.Options{Transport: webhook.NewTransport(false)}),
+		webhook.NewMemoryStore(), webhook.Options{MaxAttempts: 1, DefaultSecret: "secret"}, log)
+	t.Cleanup(webhooks.Close)
+	jobs := job.NewManager(job.NewMemoryStore(), orch, NewWebhookNotifier(webhooks, log), job.Options{
+		Workers:        2,
+		QueueSize:      10,
+		IdempotencyTTL: time.Hour,
+	}, log)
+	t.Cleanup(jobs.Close)
+
+	router := NewRouter(jobs, webhooks, ledger, files, map[string]string{e2eKeyA: "client-a", e2eKeyB: "client-b"}, log)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return &e2eServer{Server: srv, outputDir: outputDir}
+}
+
+func (s *e2eServer) do(t *testing.T, method, path, apiKey string, body interface{}) *http.Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set(HeaderAPIKey, apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeJSON(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+// testImage 纯色 PNG，桩分析按尺寸与主色生成内容
+func testImage(t *testing.T, c color.Color) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// downloadDeck 通过返回的签名地址下载 .pptx，并返回各页 XML
+func (s *e2eServer) downloadDeck(t *testing.T, pptURL string) []string {
+	t.Helper()
+	if !strings.HasPrefix(pptURL, "/files/") {
+		t.Fatalf("ppt_url = %q, want a local /files/ url", pptURL)
+	}
+	resp := s.do(t, http.MethodGet, pptURL, "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download status = %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("downloaded file is not a pptx package: %v", err)
+	}
+
+	var slides []string
+	for i := 1; ; i++ {
+		f, err := archive.Open("ppt/slides/slide" + strconv.Itoa(i) + ".xml")
+		if err != nil {
+			break
+		}
+		xml, _ := io.ReadAll(f)
+		f.Close()
+		slides = append(slides, string(xml))
+	}
+	return slides
+}
+
+func TestE2ESyncSingleSlide(t *testing.T) {
+	s := newE2EServer(t)
+
+	resp := s.do(t, http.MethodPost, "/v1/image-to-ppt", e2eKeyA, GeneratePPTRequest{
+		ImageBase64: testImage(t, color.RGBA{R: 200, A: 255}),
+		Language:    "en",
+	})
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	var result GeneratePPTResponse
+	decodeJSON(t, resp, &result)
+	if result.Status != StatusSucceeded || result.JobID == "" || result.Meta == nil || result.Meta.Title == "" {
+		t.Fatalf("response = %+v", result)
+	}
+
+	slides := s.downloadDeck(t, result.PPTURL)
+	if len(slides) != 1 || !strings.Contains(slides[0], result.Meta.Title) {
+		t.Fatalf("deck slides = %d, want one slide titled %q", len(slides), result.Meta.Title)
+	}
+	if _, err := os.Stat(filepath.Join(s.outputDir, result.JobID+".pptx")); err != nil {
+		t.Errorf("deck not stored under the job id: %v", err)
+	}
+
+	// 签名地址被篡改后拒绝下载
+	if resp := s.do(t, http.MethodGet, result.PPTURL+"0", "", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("tampered url status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestE2EAsyncDeck(t *testing.T) {
+	s := newE2EServer(t)
+
+	resp := s.do(t, http.MethodPost, "/v1/image-to-ppt", e2eKeyA, GeneratePPTRequest{
+		ImageBase64: testImage(t, color.RGBA{B: 200, A: 255}),
+		Async:       true,
+		SlideCount:  2,
+	})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", resp.StatusCode)
+	}
+	var accepted GeneratePPTResponse
+	decodeJSON(t, resp, &accepted)
+	if accepted.JobID == "" {
+		t.Fatal("no job_id in accepted response")
+	}
+
+	// 其他客户端看不到该任务
+	if resp := s.do(t, http.MethodGet, "/v1/jobs/"+accepted.JobID, e2eKeyB, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("other client status = %d, want 404", resp.StatusCode)
+	}
+
+	var j JobResponse
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp := s.do(t, http.MethodGet, "/v1/jobs/"+accepted.JobID, e2eKeyA, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("job status = %d", resp.StatusCode)
+		}
+		decodeJSON(t, resp, &j)
+		if j.Status == StatusSucceeded || j.Status == StatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", j.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if j.Status != StatusSucceeded || j.Progress != 100 || j.Meta == nil {
+		t.Fatalf("job = %+v", j)
+	}
+
+	// 封面 + 2 页内容 + 总结
+	slides := s.downloadDeck(t, j.PPTURL)
+	if len(slides) != 4 || len(j.Meta.Slides) != 4 {
+		t.Fatalf("deck has %d slides, meta %d, want 4", len(slides), len(j.Meta.Slides))
+	}
+	for i, slide := range j.Meta.Slides {
+		if !strings.Contains(slides[i], slide.Title) {
+			t.Errorf("slide %d does not contain its title %q", i+1, slide.Title)
+		}
+	}
+}
+
+func TestE2ERevise(t *testing.T) {
+	s := newE2EServer(t)
+
+	resp := s.do(t, http.MethodPost, "/v1/image-to-ppt", e2eKeyA, GeneratePPTRequest{
+		ImageBase64: testImage(t, color.RGBA{G: 200, A: 255}),
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var result GeneratePPTResponse
+	decodeJSON(t, resp, &result)
+	revisePath := "/v1/jobs/" + result.JobID + "/revise"
+
+	if resp := s.do(t, http.MethodPost, revisePath, e2eKeyA, ReviseRequest{Slide: 2, Instruction: "更正式一些"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("out of range slide status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodPost, revisePath, e2eKeyA, ReviseRequest{Instruction: "更正式一些", Target: "text"})
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("revise status = %d: %s", resp.StatusCode, body)
+	}
+	var j JobResponse
+	decodeJSON(t, resp, &j)
+	if j.Meta == nil || j.Meta.Version != 2 || len(j.Meta.Versions) != 2 || j.PPTURL == result.PPTURL {
+		t.Fatalf("revised job = %+v", j)
+	}
+	if !strings.Contains(j.Meta.Notes, "更正式一些") {
+		t.Errorf("notes = %q, want the instruction applied", j.Meta.Notes)
+	}
+
+	// 新旧版本都可以通过签名地址下载
+	if len(s.downloadDeck(t, j.PPTURL)) != 1 || len(s.downloadDeck(t, j.Meta.Versions[0].URL)) != 1 {
+		t.Error("every version should be downloadable")
+	}
+}
+
+func TestE2EStreamImages(t *testing.T) {
+	s := newE2EServer(t)
+
+	resp := s.do(t, http.MethodPost, "/v1/image-to-ppt", e2eKeyA, GeneratePPTRequest{
+		Images: []string{
+			testImage(t, color.RGBA{G: 200, A: 255}),
+			testImage(t, color.RGBA{R: 200, G: 200, A: 255}),
+		},
+		Stream: true,
+	})
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status = %d, content type = %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var events []StreamEvent
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) < 2 || events[0].Event != EventTypeStart {
+		t.Fatalf("events = %+v", events)
+	}
+	last := events[len(events)-1]
+	if last.Event != EventTypeComplete {
+		t.Fatalf("last event = %+v, want complete", last)
+	}
+
+	data, _ := json.Marshal(last.Data)
+	var complete EventComplete
+	json.Unmarshal(data, &complete)
+	// 封面 + 每张图片一页
+	if slides := s.downloadDeck(t, complete.PPTURL); len(slides) != 3 {
+		t.Errorf("deck has %d slides, want 3", len(slides))
+	}
+}
+
+func TestE2ERejectsUnauthenticated(t *testing.T) {
+	s := newE2EServer(t)
+
+	resp := s.do(t, http.MethodPost, "/v1/image-to-ppt", "", GeneratePPTRequest{ImageBase64: testImage(t, color.White)})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPost, "/v1/image-to-ppt", e2eKeyA, GeneratePPTRequest{
+		ImageBase64: testImage(t, color.White),
+		ClientID:    "client-b",
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("mismatched client_id status = %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// maxFilenameRunes 下载文件名中标题部分的最大长度
+const maxFilenameRunes = 80
+
+// GetFile 下载生成的文件：地址须带有效签名，支持 Range 与 If-None-Match，下载文件名取自幻灯片标题
+func (h *Handler) GetFile(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.files.Verify(name, c.Request.URL.Query()); err != nil {
+		h.fileError(c, name, err)
+		return
+	}
+	info, err := h.files.Stat(c.Request.Context(), name)
+	if err != nil {
+		h.fileError(c, name, err)
+		return
+	}
+	data, err := h.files.GetFile(c.Request.Context(), name)
+	if err != nil {
+		h.fileError(c, name, err)
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = storage.ContentType(name)
+	}
+	// 图片在浏览器中直接显示，演示文稿等作为附件下载
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	// ETag 取保存时记录的校验和，没有记录时按内容计算
+	checksum := info.Checksum
+	if len(checksum) < 32 {
+		sum := sha256.Sum256(data)
+		checksum = hex.EncodeToString(sum[:])
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("ETag", `"`+checksum[:32]+`"`)
+	// 文件内容按版本保存、不再改写，但下载文件名取自任务当前的标题，修改后会变化；要求客户端每次用 ETag 重新验证以取得最新的响应头
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Content-Disposition", contentDisposition(disposition, h.downloadName(name), name))
+
+	http.ServeContent(c.Writer, c.Request, name, info.CreatedAt, bytes.NewReader(data))
+}
+
+// downloadName 由文件所属任务的标题生成下载文件名，找不到任务时沿用存储文件名
+func (h *Handler) downloadName(name string) string {
+	ref := orchestrator.ParseFileName(name)
+	j, err := h.jobs.Get(ref.JobID)
+	if err != nil || j.Result == nil {
+		return name
+	}
+	title := sanitizeFilename(ref.FileTitle(j.Result))
+	if title == "" {
+		return name
+	}
+	return title + filepath.Ext(name)
+}
+
+func (h *Handler) fileError(c *gin.Context, name string, err error) {
+	code := errors.ErrCodeInternal
+	status := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.AppError); ok {
+		code = appErr.Code
+		switch appErr.Code {
+		case errors.ErrCodeNotFound:
+			status = http.StatusNotFound
+		case errors.ErrCodeInvalidReq:
+			status = http.StatusBadRequest
+		case errors.ErrCodeForbidden:
+			status = http.StatusForbidden
+		}
+	}
+	if status == http.StatusInternalServerError {
+		h.logger.Error("failed to read file", "name", name, "error", err)
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error: &GeneratePPTError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
+
+// signedResult 返回文件地址按当前时间重新签名的结果副本，避免查询较早完成的任务时拿到已过期的地址
+func (h *Handler) signedResult(result *orchestrator.GeneratePPTResponse) *orchestrator.GeneratePPTResponse {
+	signed := *result
+	signed.PPTURL = h.files.RefreshURL(result.PPTURL)
+	signed.Versions = make([]orchestrator.PPTVersion, len(result.Versions))
+	for i, version := range result.Versions {
+		version.URL = h.files.RefreshURL(version.URL)
+		signed.Versions[i] = version
+	}
+	signed.Slides = make([]orchestrator.SlideSpecData, len(result.Slides))
+	for i, slide := range result.Slides {
+		candidates := make([]orchestrator.ImageCandidate, len(slide.Candidates))
+		for j, candidate := range slide.Candidates {
+			candidate.URL = h.files.RefreshURL(candidate.URL)
+			candidates[j] = candidate
+		}
+		slide.Candidates = candidates
+		signed.Slides[i] = slide
+	}
+	return &signed
+}
+
+// sanitizeFilename 去掉文件名中不允许的字符与控制字符，合并空白并限制长度
+func sanitizeFilename(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return ' '
+		}
+		return r
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) > maxFilenameRunes {
+		title = strings.TrimSpace(string([]rune(title)[:maxFilenameRunes]))
+	}
+	return strings.Trim(title, ". ")
+}
+
+// contentDisposition 同时给出 ASCII 文件名与 RFC 5987 编码的 UTF-8 文件名，
+// 文件名含非 ASCII 字符（如中文标题）时 ASCII 部分退回存储文件名
+func contentDisposition(disposition, filename, fallback string) string {
+	ascii := filename
+	for _, r := range filename {
+		if r >= utf8.RuneSelf {
+			ascii = fallback
+			break
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, ascii, encodeRFC5987(filename))
+}
+
+// encodeRFC5987 按 RFC 5987 的 attr-char 规则百分号编码
+func encodeRFC5987(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte("!#$&+-.^_`|~", c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
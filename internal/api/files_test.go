@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+const filesSecret = "files-secret"
+
+// newFilesRouter 只挂载下载接口，文件保存在临时目录：任务 job-1 的演示文稿 job-1.pptx 与第 1 页配图，
+// 返回路由与配图的签名地址
+func newFilesRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := storage.New(storage.Options{
+		Type:      "local",
+		BasePath:  t.TempDir(),
+		BaseURL:   "/files",
+		URLSecret: filesSecret,
+		URLTTL:    time.Hour,
+	}, httpclient.New(httpclient.Options{}), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedURL, _, err := files.SaveImage(context.Background(), "job-1_slide1", []byte("\x89PNG\r\n\x1a\n0000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.SavePPT(context.Background(), "job-1", []byte("PK\x03\x04deck")); err != nil {
+		t.Fatal(err)
+	}
+	store := job.NewMemoryStore()
+	if err := store.Create(&job.Job{
+		ID:     "job-1",
+		Status: job.StatusSucceeded,
+		Result: &orchestrator.GeneratePPTResponse{
+			JobID:  "job-1",
+			Title:  "季度回顾",
+			Slides: []orchestrator.SlideSpecData{{Title: "季度回顾"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	jobs := job.NewManager(store, nil, nil, job.Options{Workers: 1, QueueSize: 1, IdempotencyTTL: time.Hour}, log)
+	t.Cleanup(jobs.Close)
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(jobs, nil, nil, files, log)
+	r := gin.New()
+	r.GET(files.URLPath()+"/:name", h.GetFile)
+	r.HEAD(files.URLPath()+"/:name", h.GetFile)
+	return r, signedURL
+}
+
+// signFileURL 按下载地址的签名规则独立计算：HMAC-SHA256(name + "\n" + expires)
+func signFileURL(name string, deadline time.Time) string {
+	expires := strconv.FormatInt(deadline.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(filesSecret))
+	mac.Write([]byte(name + "\n" + expires))
+	return "/files/" + name + "?expires=" + expires + "&signature=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGetFileSignedURL(t *testing.T) {
+	r, signedURL := newFilesRouter(t)
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := strings.TrimPrefix(u.Path, "/files/")
+	query := u.Query()
+	expires, signature := query.Get("expires"), query.Get("signature")
+	otherSignature := signFileURL("job-2_slide1.png", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		status  int
+		message string
+	}{
+		{"valid", http.MethodGet, signedURL, http.StatusOK, ""},
+		{"valid head", http.MethodHead, signedURL, http.StatusOK, ""},
+		{"independently signed", http.MethodGet, signFileURL(name, time.Now().Add(time.Minute)), http.StatusOK, ""},
+		{"missing signature", http.MethodGet, "/files/" + name, http.StatusForbidden, "missing or malformed url signature"},
+		{"missing expires", http.MethodGet, "/files/" + name + "?signature=" + signature, http.StatusForbidden, "missing or malformed url signature"},
+		{"malformed expires", http.MethodGet, "/files/" + name + "?expires=soon&signature=" + signature, http.StatusForbidden, "missing or malformed url signature"},
+		{"extended expiry", http.MethodGet, "/files/" + name + "?expires=" + strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10) + "&signature=" + signature, http.StatusForbidden, "invalid url signature"},
+		{"tampered signature", http.MethodGet, "/files/" + name + "?expires=" + expires + "&signature=" + strings.Repeat("0", len(signature)), http.StatusForbidden, "invalid url signature"},
+		{"signature of another file", http.MethodGet, "/files/" + name + otherSignature[strings.Index(otherSignature, "?"):], http.StatusForbidden, "invalid url signature"},
+		{"other file with this signature", http.MethodGet, "/files/job-2_slide1.png?" + u.RawQuery, http.StatusForbidden, "invalid url signature"},
+		{"expired", http.MethodGet, signFileURL(name, time.Now().Add(-time.Second)), http.StatusForbidden, "url expired"},
+		{"expired head", http.MethodHead, signFileURL(name, time.Now().Add(-time.Hour)), http.StatusForbidden, ""},
+		{"missing file", http.MethodGet, signFileURL("job-9_slide1.png", time.Now().Add(time.Minute)), http.StatusNotFound, "file not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK {
+				if w.Header().Get("Content-Type") != "image/png" || w.Header().Get("ETag") == "" {
+					t.Errorf("headers = %v", w.Header())
+				}
+				return
+			}
+			if tt.method == http.MethodHead {
+				return
+			}
+			if tt.status == http.StatusForbidden && !strings.Contains(w.Body.String(), errors.ErrCodeForbidden) {
+				t.Errorf("body = %s, want %s", w.Body, errors.ErrCodeForbidden)
+			}
+			if !strings.Contains(w.Body.String(), tt.message) {
+				t.Errorf("body = %s, want %q", w.Body, tt.message)
+			}
+		})
+	}
+}
+
+func TestGetFileServesContent(t *testing.T) {
+	r, signedURL := newFilesRouter(t)
+	get := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("range", func(t *testing.T) {
+		w := get(signedURL, map[string]string{"Range": "bytes=0-3"})
+		if w.Code != http.StatusPartialContent || w.Header().Get("Content-Range") != "bytes 0-3/12" || w.Body.String() != "\x89PNG" {
+			t.Errorf("status = %d, Content-Range = %q, body = %q", w.Code, w.Header().Get("Content-Range"), w.Body)
+		}
+	})
+
+	t.Run("if-none-match", func(t *testing.T) {
+		etag := get(signedURL, nil).Header().Get("ETag")
+		w := get(signedURL, map[string]string{"If-None-Match": etag})
+		if etag == "" || w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("ETag = %q, status = %d, body = %d bytes", etag, w.Code, w.Body.Len())
+		}
+		if w := get(signedURL, map[string]string{"If-None-Match": `"other"`}); w.Code != http.StatusOK {
+			t.Errorf("stale ETag status = %d, want 200", w.Code)
+		}
+	})
+
+	t.Run("image disposition", func(t *testing.T) {
+		want := `inline; filename="job-1_slide1.png"; filename*=UTF-8''%E5%AD%A3%E5%BA%A6%E5%9B%9E%E9%A1%BE-1.png`
+		if got := get(signedURL, nil).Header().Get("Content-Disposition"); got != want {
+			t.Errorf("Content-Disposition = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("pptx", func(t *testing.T) {
+		w := get(signFileURL("job-1.pptx", time.Now().Add(time.Minute)), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/vnd.openxmlformats-officedocument.presentationml.presentation" {
+			t.Errorf("Content-Type = %s", got)
+		}
+		want := `attachment; filename="job-1.pptx"; filename*=UTF-8''%E5%AD%A3%E5%BA%A6%E5%9B%9E%E9%A1%BE.pptx`
+		if got := w.Header().Get("Content-Disposition"); got != want {
+			t.Errorf("Content-Disposition = %s, want %s", got, want)
+		}
+	})
+}
+
+func TestGetFileRejectsUnsafeNames(t *testing.T) {
+	r, _ := newFilesRouter(t)
+	tests := []struct {
+		name   string
+		status int
+	}{
+		// 编码后的路径分隔符解码后不再匹配 /files/:name
+		{"../x", http.StatusNotFound},
+		{".meta/job-1.pptx.json", http.StatusNotFound},
+		{"..", http.StatusBadRequest},
+		{".meta", http.StatusBadRequest},
+		{".job-1.pptx", http.StatusBadRequest},
+		{`..\x`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 签名有效，由路由或文件名校验拒绝
+			signed := signFileURL(tt.name, time.Now().Add(time.Minute))
+			target := "/files/" + url.PathEscape(tt.name) + signed[strings.Index(signed, "?"):]
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("%s status = %d, want %d: %s", target, w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), "invalid file name") {
+				t.Errorf("body = %s", w.Body)
+			}
+		})
+	}
+}
@@ -9,21 +9,32 @@ import (
 	"time"
 
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
 	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
 	"github.com/ChaseRain/img2ppt/pkg/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type Handler struct {
-	orchestrator *orchestrator.Orchestrator
-	logger       *logger.Logger
+	jobs     *job.Manager
+	webhooks *webhook.Service
+	usage    usage.Ledger
+	files    *storage.Service
+	logger   *logger.Logger
 }
 
-func NewHandler(orch *orchestrator.Orchestrator, log *logger.Logger) *Handler {
+func NewHandler(jobs *job.Manager, webhooks *webhook.Service, ledger usage.Ledger, files *storage.Service, log *logger.Logger) *Handler {
 	return &Handler{
-		orchestrator: orch,
-		logger:       log,
+		jobs:     jobs,
+		webhooks: webhooks,
+		usage:    ledger,
+		files:    files,
+		logger:   log,
 	}
 }
 
@@ -46,51 +57,159 @@ func (h *Handler) GeneratePPT(c *gin.Context) {
 		requestID = uuid.New().String()
 	}
 
+	// client_id 以鉴权身份为准，请求体中的值只能与之一致
+	clientID := authenticatedClient(c)
+	if req.ClientID != "" && req.ClientID != clientID {
+		h.handleError(c, requestID, errors.New(errors.ErrCodeForbidden, "client_id does not match the authenticated client"))
+		return
+	}
+
 	if req.Language == "" {
 		req.Language = "zh-CN"
 	}
 	if req.Style == "" {
 		req.Style = "consulting_minimal"
 	}
+	if len(req.Images) > 0 {
+		if req.ImageBase64 != "" {
+			h.badRequest(c, requestID, "image_base64 and images cannot be used together")
+			return
+		}
+		if len(req.Images) > orchestrator.MaxImages {
+			h.badRequest(c, requestID, fmt.Sprintf("at most %d images are allowed", orchestrator.MaxImages))
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = orchestrator.ModeImages
+		}
+	} else if req.ImageBase64 == "" {
+		h.badRequest(c, requestID, "image_base64 or images is required")
+		return
+	}
+	if req.Mode == "" && req.SlideCount > 0 {
+		req.Mode = orchestrator.ModeDeck
+	}
+	if req.Mode == "" {
+		req.Mode = orchestrator.ModeSingle
+	}
+	switch {
+	case req.Mode == orchestrator.ModeImages && len(req.Images) == 0:
+		h.badRequest(c, requestID, "images is required in images mode")
+		return
+	case req.Mode != orchestrator.ModeImages && len(req.Images) > 0:
+		h.badRequest(c, requestID, fmt.Sprintf("images cannot be used in %s mode", req.Mode))
+		return
+	case req.Mode != orchestrator.ModeSingle && req.Mode != orchestrator.ModeDeck && req.Mode != orchestrator.ModeImages:
+		h.badRequest(c, requestID, fmt.Sprintf("unsupported mode %q", req.Mode))
+		return
+	}
+	if req.Async && req.Stream {
+		h.badRequest(c, requestID, "async and stream cannot be used together")
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := h.webhooks.Validate(c.Request.Context(), clientID, req.CallbackURL); err != nil {
+			h.handleError(c, requestID, err)
+			return
+		}
+	}
+	if !provider.ValidFidelity(req.ImageFidelity) {
+		h.badRequest(c, requestID, fmt.Sprintf("unsupported image_fidelity %q", req.ImageFidelity))
+		return
+	}
+	if req.Variants < 0 || req.Variants > orchestrator.MaxVariants {
+		h.badRequest(c, requestID, fmt.Sprintf("variants must be between 1 and %d, or 0 for the default", orchestrator.MaxVariants))
+		return
+	}
+	if req.SlideCount < 0 || req.SlideCount > orchestrator.MaxDeckSlides {
+		h.badRequest(c, requestID, fmt.Sprintf("slide_count must be between 1 and %d, or 0 for the default", orchestrator.MaxDeckSlides))
+		return
+	}
 
-	// 处理 Data URL 格式: data:image/png;base64,xxxxx
-	imageBase64 := req.ImageBase64
-	if strings.Contains(imageBase64, ",") {
-		parts := strings.SplitN(imageBase64, ",", 2)
-		if len(parts) == 2 {
-			imageBase64 = parts[1]
+	var imageBytes []byte
+	var images [][]byte
+	if req.ImageBase64 != "" {
+		data, err := decodeImageBase64(req.ImageBase64)
+		if err != nil {
+			h.invalidImage(c, requestID, "failed to decode base64 image", err)
+			return
+		}
+		imageBytes = data
+	}
+	for i, img := range req.Images {
+		data, err := decodeImageBase64(img)
+		if err != nil {
+			h.invalidImage(c, requestID, fmt.Sprintf("failed to decode base64 image at index %d", i), err)
+			return
 		}
+		images = append(images, data)
 	}
 
-	imageBytes, err := base64.StdEncoding.DecodeString(imageBase64)
-	if err != nil {
-		h.logger.Error("failed to decode image", "error", err)
-		c.JSON(http.StatusBadRequest, GeneratePPTResponse{
+	orchReq := &orchestrator.GeneratePPTRequest{
+		RequestID:     requestID,
+		ImageBytes:    imageBytes,
+		Images:        images,
+		Language:      req.Language,
+		Style:         req.Style,
+		Mode:          req.Mode,
+		SlideCount:    req.SlideCount,
+		ClientID:      clientID,
+		CallbackURL:   req.CallbackURL,
+		ImageFidelity: req.ImageFidelity,
+		NoCache:       req.NoCache,
+		Variants:      req.Variants,
+	}
+
+	// client_request_id 同时作为幂等键：相同内容的重复请求返回已有任务的结果或等待其完成
+	key := req.ClientRequestID
+
+	// 异步任务
+	if req.Async {
+		j, reused, err := h.jobs.Submit(orchReq, key)
+		if err != nil {
+			h.handleError(c, requestID, err)
+			return
+		}
+		if reused && j.Status == job.StatusSucceeded {
+			result := h.signedResult(j.Result)
+			c.JSON(http.StatusOK, GeneratePPTResponse{
+				RequestID: requestID,
+				JobID:     j.ID,
+				Status:    StatusSucceeded,
+				PPTURL:    result.PPTURL,
+				Meta:      buildMeta(result),
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, GeneratePPTResponse{
 			RequestID: requestID,
-			Status:    StatusFailed,
-			Error: &GeneratePPTError{
-				Code:    "INVALID_IMAGE",
-				Message: "failed to decode base64 image",
-			},
+			JobID:     j.ID,
+			Status:    j.Status,
 		})
 		return
 	}
 
-	orchReq := &orchestrator.GeneratePPTRequest{
-		RequestID:  requestID,
-		ImageBytes: imageBytes,
-		Language:   req.Language,
-		Style:      req.Style,
-	}
-
 	// 流式输出
 	if req.Stream {
-		h.handleStreamingResponse(c, requestID, orchReq)
+		h.handleStreamingResponse(c, requestID, key, orchReq)
 		return
 	}
 
 	// 非流式输出（保持兼容）
-	result, err := h.orchestrator.GenerateSingleSlidePPT(c.Request.Context(), orchReq)
+	j, reused, err := h.jobs.Start(orchReq, key)
+	if err != nil {
+		h.handleError(c, requestID, err)
+		return
+	}
+	var result *orchestrator.GeneratePPTResponse
+	if reused {
+		result, err = h.waitJob(c, j.ID, nil)
+		if err == nil {
+			result = h.signedResult(result)
+		}
+	} else {
+		result, err = h.jobs.Execute(c.Request.Context(), j.ID, orchReq, nil)
+	}
 	if err != nil {
 		h.handleError(c, requestID, err)
 		return
@@ -98,15 +217,77 @@ func (h *Handler) GeneratePPT(c *gin.Context) {
 
 	c.JSON(http.StatusOK, GeneratePPTResponse{
 		RequestID: requestID,
+		JobID:     j.ID,
 		Status:    StatusSucceeded,
 		PPTURL:    result.PPTURL,
-		Meta: &GeneratePPTMeta{
-			Title: result.Title,
-		},
+		Meta:      buildMeta(result),
 	})
 }
 
-func (h *Handler) handleStreamingResponse(c *gin.Context, requestID string, req *orchestrator.GeneratePPTRequest) {
+// waitJob 等待幂等命中的已有任务结束，并将任务的最终状态转换为结果或错误
+func (h *Handler) waitJob(c *gin.Context, jobID string, onProgress orchestrator.ProgressCallback) (*orchestrator.GeneratePPTResponse, error) {
+	j, err := h.jobs.Watch(c.Request.Context(), jobID, onProgress)
+	if err != nil {
+		return nil, err
+	}
+	if j.Status == job.StatusSucceeded && j.Result != nil {
+		return j.Result, nil
+	}
+	code := j.ErrorCode
+	if code == "" {
+		code = errors.ErrCodeInternal
+	}
+	return nil, errors.New(code, j.ErrorMessage)
+}
+
+func buildMeta(result *orchestrator.GeneratePPTResponse) *GeneratePPTMeta {
+	return &GeneratePPTMeta{
+		Title:    result.Title,
+		Subtitle: result.Subtitle,
+		Bullets:  result.Bullets,
+		Notes:    result.Notes,
+		Slides:   slideMetas(result.Slides),
+		Cache:    result.Cache,
+		Usage:    result.Usage,
+		Version:  result.Version,
+		Versions: result.Versions,
+	}
+}
+
+func slideMetas(slides []orchestrator.SlideSpecData) []GeneratePPTSlideMeta {
+	if len(slides) == 0 {
+		return nil
+	}
+	metas := make([]GeneratePPTSlideMeta, len(slides))
+	for i, slide := range slides {
+		metas[i] = GeneratePPTSlideMeta{
+			Title:    slide.Title,
+			Subtitle: slide.Subtitle,
+			Bullets:  slide.Bullets,
+			Notes:    slide.Notes,
+			Error:    slide.Error,
+
+			SelectedCandidate: slide.SelectedCandidate,
+		}
+		for _, candidate := range slide.Candidates {
+			metas[i].Candidates = append(metas[i].Candidates, GeneratePPTCandidateMeta{
+				Index: candidate.Index,
+				URL:   candidate.URL,
+				Error: candidate.Error,
+			})
+		}
+	}
+	return metas
+}
+
+func (h *Handler) handleStreamingResponse(c *gin.Context, requestID, key string, req *orchestrator.GeneratePPTRequest) {
+	// 登记为任务，客户端可通过 DELETE /v1/jobs/{id} 或向 POST /v1/jobs/{id}/commands 发送 cancel 中止生成
+	j, reused, err := h.jobs.Start(req, key)
+	if err != nil {
+		h.handleError(c, requestID, err)
+		return
+	}
+
 	// 设置 SSE headers
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -126,11 +307,30 @@ func (h *Handler) handleStreamingResponse(c *gin.Context, requestID string, req
 		fmt.Fprintf(c.Writer, "data: %s\n\n", jsonData)
 		c.Writer.Flush()
 	}
+	sendError := func(err error) {
+		code := "INTERNAL_ERROR"
+		if appErr, ok := err.(*errors.AppError); ok {
+			code = appErr.Code
+		}
+		if code == errors.ErrCodeCancelled {
+			sendEvent(EventTypeCancelled, EventCancelled{
+				Message: "已取消生成",
+				JobID:   j.ID,
+			})
+			return
+		}
+		sendEvent(EventTypeError, EventError{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
 
 	// 发送开始事件
 	sendEvent(EventTypeStart, EventStart{
-		Message:   "开始处理您的请求...",
-		Timestamp: time.Now().Unix(),
+		Message:     "开始处理您的请求...",
+		JobID:       j.ID,
+		CommandsURL: "/v1/jobs/" + j.ID + "/commands",
+		Timestamp:   time.Now().Unix(),
 	})
 
 	// 进度回调
@@ -148,9 +348,46 @@ func (h *Handler) handleStreamingResponse(c *gin.Context, requestID string, req
 					Title:    specData.Title,
 					Subtitle: specData.Subtitle,
 					Bullets:  specData.Bullets,
+					Notes:    specData.Notes,
+					Progress: event.Progress,
+				})
+			}
+		case "outlined":
+			if slides, ok := event.Data.([]orchestrator.SlideSpecData); ok {
+				sendEvent(EventTypeOutlined, EventOutlined{
+					Message:  event.Message,
+					Slides:   slideMetas(slides),
 					Progress: event.Progress,
 				})
 			}
+		case "slide_analyzed", "slide_generating", "slide_generated":
+			if slide, ok := event.Data.(orchestrator.SlideProgressData); ok {
+				sendEvent(event.Stage, EventSlideProgress{
+					Message:     event.Message,
+					Index:       slide.Index,
+					Total:       slide.Total,
+					Title:       slide.Title,
+					ImagePrompt: slide.ImagePrompt,
+					HasImage:    slide.HasImage,
+					Error:       slide.Error,
+					ErrorCode:   slide.ErrorCode,
+					Progress:    event.Progress,
+				})
+			}
+		case "candidate_generated":
+			if candidate, ok := event.Data.(orchestrator.CandidateProgressData); ok {
+				sendEvent(EventTypeCandidateGenerated, EventCandidate{
+					Message:   event.Message,
+					Index:     candidate.Index,
+					Total:     candidate.Total,
+					Variant:   candidate.Variant,
+					Variants:  candidate.Variants,
+					URL:       candidate.URL,
+					Error:     candidate.Error,
+					ErrorCode: candidate.ErrorCode,
+					Progress:  event.Progress,
+				})
+			}
 		case "generating":
 			prompt := ""
 			if m, ok := event.Data.(map[string]string); ok {
@@ -162,10 +399,15 @@ func (h *Handler) handleStreamingResponse(c *gin.Context, requestID string, req
 				Progress:    event.Progress,
 			})
 		case "generated":
-			sendEvent(EventTypeGenerated, EventGenerated{
+			generated := EventGenerated{
 				Message:  event.Message,
 				Progress: event.Progress,
-			})
+			}
+			if m, ok := event.Data.(map[string]string); ok {
+				generated.ErrorCode = m["error_code"]
+				generated.Error = m["error"]
+			}
+			sendEvent(EventTypeGenerated, generated)
 		case "rendering":
 			sendEvent(EventTypeRendering, EventRendering{
 				Message:  event.Message,
@@ -186,18 +428,64 @@ func (h *Handler) handleStreamingResponse(c *gin.Context, requestID string, req
 		}
 	}
 
+	// 幂等命中时订阅已有任务的后续进度，任务已结束则直接补发完成事件
+	if reused {
+		completed := false
+		result, err := h.waitJob(c, j.ID, func(event orchestrator.ProgressEvent) {
+			completed = completed || event.Stage == "complete"
+			onProgress(event)
+		})
+		if err != nil {
+			sendError(err)
+		} else if !completed {
+			sendEvent(EventTypeComplete, EventComplete{
+				Message: "生成完成！",
+				PPTURL:  h.files.RefreshURL(result.PPTURL),
+				Title:   result.Title,
+			})
+		}
+		return
+	}
+
 	// 执行生成
-	_, err := h.orchestrator.GenerateSingleSlidePPTWithProgress(c.Request.Context(), req, onProgress)
+	_, err = h.jobs.Execute(c.Request.Context(), j.ID, req, onProgress)
 	if err != nil {
-		code := "INTERNAL_ERROR"
-		if appErr, ok := err.(*errors.AppError); ok {
-			code = appErr.Code
+		sendError(err)
+	}
+}
+
+// decodeImageBase64 解码 base64 图片，兼容 Data URL 格式: data:image/png;base64,xxxxx
+func decodeImageBase64(imageBase64 string) ([]byte, error) {
+	if strings.Contains(imageBase64, ",") {
+		parts := strings.SplitN(imageBase64, ",", 2)
+		if len(parts) == 2 {
+			imageBase64 = parts[1]
 		}
-		sendEvent(EventTypeError, EventError{
-			Code:    code,
-			Message: err.Error(),
-		})
 	}
+	return base64.StdEncoding.DecodeString(imageBase64)
+}
+
+func (h *Handler) invalidImage(c *gin.Context, requestID, message string, err error) {
+	h.logger.Error("failed to decode image", "error", err)
+	c.JSON(http.StatusBadRequest, GeneratePPTResponse{
+		RequestID: requestID,
+		Status:    StatusFailed,
+		Error: &GeneratePPTError{
+			Code:    "INVALID_IMAGE",
+			Message: message,
+		},
+	})
+}
+
+func (h *Handler) badRequest(c *gin.Context, requestID, message string) {
+	c.JSON(http.StatusBadRequest, GeneratePPTResponse{
+		RequestID: requestID,
+		Status:    StatusFailed,
+		Error: &GeneratePPTError{
+			Code:    errors.ErrCodeInvalidReq,
+			Message: message,
+		},
+	})
 }
 
 func (h *Handler) handleError(c *gin.Context, requestID string, err error) {
@@ -208,8 +496,17 @@ func (h *Handler) handleError(c *gin.Context, requestID string, err error) {
 
 	if appErr, ok := err.(*errors.AppError); ok {
 		code = appErr.Code
-		if appErr.Code == errors.ErrCodeRateLimited {
+		switch appErr.Code {
+		case errors.ErrCodeRateLimited:
 			status = http.StatusTooManyRequests
+		case errors.ErrCodeInvalidReq:
+			status = http.StatusBadRequest
+		case errors.ErrCodeConflict:
+			status = http.StatusConflict
+		case errors.ErrCodeContentBlocked:
+			status = http.StatusUnprocessableEntity
+		case errors.ErrCodeForbidden:
+			status = http.StatusForbidden
 		}
 	}
 
@@ -223,6 +520,275 @@ func (h *Handler) handleError(c *gin.Context, requestID string, err error) {
 	})
 }
 
+func (h *Handler) GetJob(c *gin.Context) {
+	j, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.buildJobResponse(j))
+}
+
+// ownJob 读取路径中的任务，其他客户端的任务视为不存在；失败时已写入响应
+func (h *Handler) ownJob(c *gin.Context) (*job.Job, bool) {
+	j, err := h.jobs.Get(c.Param("id"))
+	if err == nil && j.ClientID != authenticatedClient(c) {
+		err = errors.New(errors.ErrCodeNotFound, "job not found")
+	}
+	if err != nil {
+		h.handleJobError(c, err)
+		return nil, false
+	}
+	return j, true
+}
+
+func (h *Handler) CancelJob(c *gin.Context) {
+	if _, ok := h.ownJob(c); !ok {
+		return
+	}
+	j, err := h.jobs.Cancel(c.Param("id"))
+	if err != nil {
+		h.handleJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.buildJobResponse(j))
+}
+
+// JobCommand 流式生成的控制通道：SSE 只能由服务端推送，客户端通过开始事件中的 commands_url 发送命令。
+// 目前支持 cancel，效果与 DELETE /v1/jobs/{id} 相同，流中随后收到 cancelled 事件
+func (h *Handler) JobCommand(c *gin.Context) {
+	var req JobCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidQuery(c, "invalid request body: "+err.Error())
+		return
+	}
+
+	switch req.Command {
+	case JobCommandCancel:
+		h.CancelJob(c)
+	default:
+		h.invalidQuery(c, fmt.Sprintf("unsupported command %q", req.Command))
+	}
+}
+
+// SelectImage 换用已成功任务某页的候选配图并重新渲染 PPT，不重新分析图片
+func (h *Handler) SelectImage(c *gin.Context) {
+	var req SelectImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidQuery(c, "invalid request body: "+err.Error())
+		return
+	}
+	if req.Slide == 0 {
+		req.Slide = 1
+	}
+	if _, ok := h.ownJob(c); !ok {
+		return
+	}
+
+	j, err := h.jobs.SelectImage(c.Request.Context(), c.Param("id"), req.Slide, req.Candidate)
+	if err != nil {
+		h.handleJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.buildJobResponse(j))
+}
+
+// Revise 按修改意见调整已成功任务的一页，只重新生成变化的部分，返回包含新版本的任务
+func (h *Handler) Revise(c *gin.Context) {
+	var req ReviseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidQuery(c, "invalid request body: "+err.Error())
+		return
+	}
+	req.Instruction = strings.TrimSpace(req.Instruction)
+	if req.Instruction == "" {
+		h.invalidQuery(c, "instruction is required")
+		return
+	}
+	if !provider.ValidReviseTarget(req.Target) {
+		h.invalidQuery(c, fmt.Sprintf("unsupported target %q", req.Target))
+		return
+	}
+	if req.Slide == 0 {
+		req.Slide = 1
+	}
+	if _, ok := h.ownJob(c); !ok {
+		return
+	}
+
+	j, err := h.jobs.Revise(c.Request.Context(), c.Param("id"), &orchestrator.ReviseRequest{
+		Slide:       req.Slide,
+		Instruction: req.Instruction,
+		Target:      req.Target,
+	})
+	if err != nil {
+		h.handleJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.buildJobResponse(j))
+}
+
+func (h *Handler) buildJobResponse(j *job.Job) JobResponse {
+	resp := JobResponse{
+		JobID:     j.ID,
+		RequestID: j.RequestID,
+		Status:    j.Status,
+		Stage:     j.Stage,
+		Message:   j.Message,
+		Progress:  j.Progress,
+		CreatedAt: j.CreatedAt.Unix(),
+		UpdatedAt: j.UpdatedAt.Unix(),
+	}
+	if j.Result != nil {
+		result := h.signedResult(j.Result)
+		resp.PPTURL = result.PPTURL
+		resp.Meta = buildMeta(result)
+	}
+	if j.ErrorCode != "" {
+		resp.Error = &GeneratePPTError{
+			Code:    j.ErrorCode,
+			Message: j.ErrorMessage,
+		}
+	}
+	return resp
+}
+
+func (h *Handler) handleJobError(c *gin.Context, err error) {
+	code := "INTERNAL_ERROR"
+	status := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.AppError); ok {
+		code = appErr.Code
+		switch appErr.Code {
+		case errors.ErrCodeNotFound:
+			status = http.StatusNotFound
+		case errors.ErrCodeConflict:
+			status = http.StatusConflict
+		case errors.ErrCodeInvalidReq:
+			status = http.StatusBadRequest
+		case errors.ErrCodeRateLimited:
+			status = http.StatusTooManyRequests
+		case errors.ErrCodeContentBlocked:
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	if status == http.StatusInternalServerError {
+		h.logger.Error("failed to load job", "job_id", c.Param("id"), "error", err)
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error: &GeneratePPTError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
+
+// GetWebhookDeliveries 返回当前调用方某个请求的回调投递记录
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	requestID := c.Param("request_id")
+	deliveries, err := h.webhooks.Deliveries(authenticatedClient(c), requestID)
+	if err != nil {
+		h.logger.Error("failed to list webhook deliveries", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: &GeneratePPTError{
+				Code:    errors.ErrCodeStorage,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, WebhookDeliveriesResponse{
+		RequestID:  requestID,
+		Deliveries: deliveries,
+	})
+}
+
+// GetUsage 按 API Key 汇总当前调用方的用量与估算费用，from/to 为 UTC 日期（YYYY-MM-DD，含两端），缺省不限
+func (h *Handler) GetUsage(c *gin.Context) {
+	from, err := parseDay(c.Query("from"))
+	if err != nil {
+		h.invalidQuery(c, "invalid from date, expected YYYY-MM-DD")
+		return
+	}
+	to, err := parseDay(c.Query("to"))
+	if err != nil {
+		h.invalidQuery(c, "invalid to date, expected YYYY-MM-DD")
+		return
+	}
+	if !to.IsZero() {
+		to = to.Add(24 * time.Hour)
+	}
+
+	clientID := authenticatedClient(c)
+	records, err := h.usage.List(clientID, from, to)
+	if err != nil {
+		h.usageError(c, err)
+		return
+	}
+
+	keys := usage.AggregateByKey(records)
+	c.JSON(http.StatusOK, UsageResponse{
+		ClientID: clientID,
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Total:    usage.Sum(keys),
+		Keys:     keys,
+	})
+}
+
+// ExportUsageCSV 导出当前调用方某一 UTC 日期（默认当天）的用量明细 CSV
+func (h *Handler) ExportUsageCSV(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().UTC().Format(usage.DateLayout)
+	}
+	day, err := parseDay(date)
+	if err != nil {
+		h.invalidQuery(c, "invalid date, expected YYYY-MM-DD")
+		return
+	}
+
+	records, err := h.usage.List(authenticatedClient(c), day, day.Add(24*time.Hour))
+	if err != nil {
+		h.usageError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="usage-%s.csv"`, date))
+	c.Status(http.StatusOK)
+	if err := usage.WriteCSV(c.Writer, records); err != nil {
+		h.logger.Error("failed to write usage csv", "date", date, "error", err)
+	}
+}
+
+// parseDay 解析 UTC 日期，空字符串返回零值
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(usage.DateLayout, value)
+}
+
+func (h *Handler) usageError(c *gin.Context, err error) {
+	h.logger.Error("failed to list usage", "error", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: &GeneratePPTError{
+			Code:    errors.ErrCodeStorage,
+			Message: err.Error(),
+		},
+	})
+}
+
+// invalidQuery 查询参数错误
+func (h *Handler) invalidQuery(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Error: &GeneratePPTError{
+			Code:    errors.ErrCodeInvalidReq,
+			Message: message,
+		},
+	})
+}
+
 func (h *Handler) Health(c *gin.Context) {
 	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
 }
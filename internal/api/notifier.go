@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
+)
+
+// WebhookNotifier 任务结束后将 GeneratePPTResponse 投递到请求携带的 callback_url
+type WebhookNotifier struct {
+	webhooks *webhook.Service
+	logger   *logger.Logger
+}
+
+func NewWebhookNotifier(webhooks *webhook.Service, log *logger.Logger) *WebhookNotifier {
+	return &WebhookNotifier{
+		webhooks: webhooks,
+		logger:   log,
+	}
+}
+
+func (n *WebhookNotifier) JobFinished(j *job.Job) {
+	if j.CallbackURL == "" {
+		return
+	}
+
+	resp := GeneratePPTResponse{
+		RequestID: j.RequestID,
+		JobID:     j.ID,
+		Status:    j.Status,
+	}
+	if j.Result != nil {
+		resp.PPTURL = j.Result.PPTURL
+		resp.Meta = buildMeta(j.Result)
+	}
+	if j.ErrorCode != "" {
+		resp.Error = &GeneratePPTError{
+			Code:    j.ErrorCode,
+			Message: j.ErrorMessage,
+		}
+	}
+
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		n.logger.Error("failed to encode webhook payload", "request_id", j.RequestID, "error", err)
+		return
+	}
+	n.webhooks.Deliver(j.ID, j.RequestID, j.ClientID, j.CallbackURL, payload)
+}
@@ -2,24 +2,39 @@ package api
 
 import (
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
-	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/internal/service/job"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/internal/service/webhook"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(orch *orchestrator.Orchestrator, log *logger.Logger) *gin.Engine {
+// NewRouter apiKeys 为 API Key 到 client_id 的映射，为空时 /v1 接口不鉴权
+func NewRouter(jobs *job.Manager, webhooks *webhook.Service, ledger usage.Ledger, files *storage.Service, apiKeys map[string]string, log *logger.Logger) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
 	r.Use(gin.Recovery())
 	r.Use(requestLogger(log))
 
-	handler := NewHandler(orch, log)
+	handler := NewHandler(jobs, webhooks, ledger, files, log)
 
 	r.GET("/health", handler.Health)
+	// storage.base_url 指向本服务时，生成结果中的地址由此下载
+	r.GET(files.URLPath()+"/:name", handler.GetFile)
+	r.HEAD(files.URLPath()+"/:name", handler.GetFile)
 
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", authenticate(apiKeys))
 	{
 		v1.POST("/image-to-ppt", handler.GeneratePPT)
+		v1.GET("/jobs/:id", handler.GetJob)
+		v1.DELETE("/jobs/:id", handler.CancelJob)
+		v1.POST("/jobs/:id/commands", handler.JobCommand)
+		v1.POST("/jobs/:id/select-image", handler.SelectImage)
+		v1.POST("/jobs/:id/revise", handler.Revise)
+		v1.GET("/webhooks/:request_id/deliveries", handler.GetWebhookDeliveries)
+		v1.GET("/usage", handler.GetUsage)
+		v1.GET("/usage/daily.csv", handler.ExportUsageCSV)
 	}
 
 	return r
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/gin-gonic/gin"
+)
+
+func newUsageRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	ledger := usage.NewMemoryLedger()
+	ledger.Append([]usage.Record{
+		{Time: day, RequestID: "req-a", ClientID: "client-a", Status: usage.StatusSucceeded,
+			ModelUsage: usage.ModelUsage{Model: "gemini", KeyID: "k1", Totals: usage.Totals{Calls: 1, TotalTokens: 100, Cost: 0.1}}},
+		{Time: day, RequestID: "req-b", ClientID: "client-b", Status: usage.StatusSucceeded,
+			ModelUsage: usage.ModelUsage{Model: "gemini", KeyID: "k1", Totals: usage.Totals{Calls: 1, TotalTokens: 900, Cost: 0.9}}},
+	})
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, nil, ledger, nil, log)
+	r := gin.New()
+	r.Use(authenticate(map[string]string{"key-a": "client-a", "key-b": "client-b"}))
+	r.GET("/v1/usage", h.GetUsage)
+	r.GET("/v1/usage/daily.csv", h.ExportUsageCSV)
+	return r
+}
+
+func getWithKey(r *gin.Engine, path, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(HeaderAPIKey, apiKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetUsageScopedToAuthenticatedClient(t *testing.T) {
+	r := newUsageRouter(t)
+
+	w := getWithKey(r, "/v1/usage?client_id=client-b", "key-a")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp UsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	// 查询参数不能指定其他调用方
+	if resp.ClientID != "client-a" || resp.Total.TotalTokens != 100 || len(resp.Keys) != 1 || resp.Keys[0].Requests != 1 {
+		t.Errorf("usage = %+v, want only client-a's records", resp)
+	}
+}
+
+func TestExportUsageCSVScopedToAuthenticatedClient(t *testing.T) {
+	r := newUsageRouter(t)
+
+	w := getWithKey(r, "/v1/usage/daily.csv?date=2026-10-01", "key-b")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][2] != "req-b" || rows[1][3] != "client-b" {
+		t.Errorf("rows = %v, want the header and client-b's record", rows)
+	}
+}
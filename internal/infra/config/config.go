@@ -8,12 +8,20 @@ import (
 
 type Config struct {
 	Server     ServerConfig     `yaml:"server"`
+	Auth       AuthConfig       `yaml:"auth"`
 	Log        LogConfig        `yaml:"log"`
 	HTTPClient HTTPClientConfig `yaml:"http_client"`
 	Limiter    LimiterConfig    `yaml:"limiter"`
-	Gemini     GeminiConfig     `yaml:"gemini"`
+	Analyzer   AnalyzerConfig   `yaml:"analyzer"`
 	ImageGen   ImageGenConfig   `yaml:"image_gen"`
 	Storage    StorageConfig    `yaml:"storage"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Job        JobConfig        `yaml:"job"`
+	Webhook    WebhookConfig    `yaml:"webhook"`
+	Usage      UsageConfig      `yaml:"usage"`
+
+	// Gemini 旧版分析模型配置，仅在 analyzer.provider 为 gemini 且 analyzer 未填写对应字段时使用
+	Gemini GeminiConfig `yaml:"gemini"`
 }
 
 type ServerConfig struct {
@@ -22,6 +30,11 @@ type ServerConfig struct {
 	WriteTimeoutSeconds int    `yaml:"write_timeout_seconds"`
 }
 
+// AuthConfig APIKeys 为 API Key 到 client_id 的映射，client_id 只由鉴权得到；为空时不鉴权
+type AuthConfig struct {
+	APIKeys map[string]string `yaml:"api_keys"`
+}
+
 type LogConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
@@ -30,6 +43,9 @@ type LogConfig struct {
 type HTTPClientConfig struct {
 	TimeoutSeconds int `yaml:"timeout_seconds"`
 	MaxRetries     int `yaml:"max_retries"`
+	// CassetteMode 为 off、record 或 replay，录制文件按任务 ID 分目录保存在 CassetteDir 下，每次调用一个文件
+	CassetteMode string `yaml:"cassette_mode"`
+	CassetteDir  string `yaml:"cassette_dir"`
 }
 
 type LimiterConfig struct {
@@ -37,20 +53,116 @@ type LimiterConfig struct {
 	RatePerSecond float64 `yaml:"rate_per_second"`
 }
 
+// AnalyzerConfig 图片分析模型配置，Provider 选择供应商实现（默认 gemini）；
+// Model、BaseURL 为空时使用所选供应商的默认值
+type AnalyzerConfig struct {
+	Provider string `yaml:"provider"`
+	APIKey   string `yaml:"api_key"`
+	Model    string `yaml:"model"`
+	BaseURL  string `yaml:"base_url"`
+}
+
+// GeminiConfig 旧版配置中的 gemini 段，已由 AnalyzerConfig 取代
 type GeminiConfig struct {
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	APIKey  string `yaml:"api_key"`
+	Model   string `yaml:"model"`
+	BaseURL string `yaml:"base_url"`
 }
 
+// ImageGenConfig 配图模型配置，Provider 选择供应商实现（默认 gemini）；
+// Model、BaseURL 为空时使用所选供应商的默认值
 type ImageGenConfig struct {
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	Provider string `yaml:"provider"`
+	APIKey   string `yaml:"api_key"`
+	Model    string `yaml:"model"`
+	BaseURL  string `yaml:"base_url"`
+	// Size 配图尺寸，openai 供应商使用，如 1792x1024
+	Size string `yaml:"size"`
 }
 
 type StorageConfig struct {
-	Type     string `yaml:"type"`
-	BasePath string `yaml:"base_path"`
-	BaseURL  string `yaml:"base_url"`
+	// Type 为 local、s3 或 gcs
+	Type     string    `yaml:"type"`
+	BasePath string    `yaml:"base_path"`
+	BaseURL  string    `yaml:"base_url"`
+	S3       S3Config  `yaml:"s3"`
+	GCS      GCSConfig `yaml:"gcs"`
+	// URLTTLSeconds 返回的下载地址的有效期，最长 7 天
+	URLTTLSeconds int `yaml:"url_ttl_seconds"`
+	// URLSecret 本地下载地址的 HMAC 签名密钥，多实例部署需一致；为空时每次启动随机生成
+	URLSecret string `yaml:"url_secret"`
+}
+
+// S3Config S3 兼容对象存储，Endpoint 为空时使用 AWS 区域端点；MinIO 需设置 Endpoint 并开启 UsePathStyle
+type S3Config struct {
+	Bucket          string `yaml:"bucket"`
+	Region          string `yaml:"region"`
+	Endpoint        string `yaml:"endpoint"`
+	UsePathStyle    bool   `yaml:"use_path_style"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	Prefix          string `yaml:"prefix"`
+	// PartSizeMB 超过该大小的文件使用分片上传，不小于 5
+	PartSizeMB int `yaml:"part_size_mb"`
+}
+
+// GCSConfig Google Cloud Storage，CredentialsFile 为服务账号 JSON 密钥文件；
+// 使用 fake-gcs-server 时设置 Endpoint，可不提供密钥文件
+type GCSConfig struct {
+	Bucket          string `yaml:"bucket"`
+	Endpoint        string `yaml:"endpoint"`
+	CredentialsFile string `yaml:"credentials_file"`
+	Prefix          string `yaml:"prefix"`
+	// URLMode 为 signed（签名地址，有效期为 url_ttl_seconds）或 public（公开对象地址）；
+	// 为空时提供了密钥文件则签名
+	URLMode string `yaml:"url_mode"`
+}
+
+type CacheConfig struct {
+	// Type 为 none、memory 或 disk
+	Type       string `yaml:"type"`
+	Path       string `yaml:"path"`
+	MaxEntries int    `yaml:"max_entries"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+}
+
+type JobConfig struct {
+	Workers   int    `yaml:"workers"`
+	QueueSize int    `yaml:"queue_size"`
+	Store     string `yaml:"store"`
+	StorePath string `yaml:"store_path"`
+	// IdempotencyTTLSeconds client_request_id 作为幂等键的有效期
+	IdempotencyTTLSeconds int `yaml:"idempotency_ttl_seconds"`
+}
+
+type WebhookConfig struct {
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+	MaxAttempts    int `yaml:"max_attempts"`
+	BackoffSeconds int `yaml:"backoff_seconds"`
+	// DefaultSecret 未在 ClientSecrets 中配置的客户端使用的签名密钥
+	DefaultSecret string            `yaml:"default_secret"`
+	ClientSecrets map[string]string `yaml:"client_secrets"`
+	// Store 投递记录存储，memory 或 file
+	Store     string `yaml:"store"`
+	StorePath string `yaml:"store_path"`
+	// AllowPrivateNetworks 允许回调内网地址，仅用于本地开发
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// UsageConfig 用量账本与价格表，Pricing 以模型名为键，未配置的模型费用记为 0
+type UsageConfig struct {
+	// Store 为 memory 或 file，file 按 UTC 日期每天一个文件
+	Store     string                `yaml:"store"`
+	StorePath string                `yaml:"store_path"`
+	Pricing   map[string]ModelPrice `yaml:"pricing"`
+}
+
+// ModelPrice 模型单价（美元）
+type ModelPrice struct {
+	InputPerMillion  float64 `yaml:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million"`
+	PerImage         float64 `yaml:"per_image"`
 }
 
 func Load() (*Config, error) {
@@ -90,21 +202,53 @@ func defaultConfig() *Config {
 		HTTPClient: HTTPClientConfig{
 			TimeoutSeconds: 60,
 			MaxRetries:     2,
+			CassetteMode:   "off",
+			CassetteDir:    "./data/cassettes",
 		},
 		Limiter: LimiterConfig{
 			MaxConcurrent: 10,
 			RatePerSecond: 5,
 		},
-		Gemini: GeminiConfig{
-			Model: "gemini-3-pro-image-preview",
+		Analyzer: AnalyzerConfig{
+			Provider: "gemini",
 		},
 		ImageGen: ImageGenConfig{
-			Model: "gemini-3-pro-image-preview",
+			Provider: "gemini",
 		},
 		Storage: StorageConfig{
 			Type:     "local",
 			BasePath: "./output",
 			BaseURL:  "/files",
+			S3: S3Config{
+				Region:     "us-east-1",
+				PartSizeMB: 8,
+			},
+			URLTTLSeconds: 3600,
+		},
+		Cache: CacheConfig{
+			Type:       "memory",
+			Path:       "./data/cache",
+			MaxEntries: 1000,
+			MaxSizeMB:  256,
+		},
+		Job: JobConfig{
+			Workers:   4,
+			QueueSize: 100,
+			Store:     "memory",
+			StorePath: "./data/jobs",
+
+			IdempotencyTTLSeconds: 86400,
+		},
+		Webhook: WebhookConfig{
+			TimeoutSeconds: 10,
+			MaxAttempts:    4,
+			BackoffSeconds: 2,
+			Store:          "memory",
+			StorePath:      "./data/webhooks",
+		},
+		Usage: UsageConfig{
+			Store:     "memory",
+			StorePath: "./data/usage",
 		},
 	}
 }
@@ -113,18 +257,45 @@ func applyEnvOverrides(cfg *Config) *Config {
 	if v := os.Getenv("SERVER_ADDR"); v != "" {
 		cfg.Server.Addr = v
 	}
+	if v := os.Getenv("HTTP_CASSETTE_MODE"); v != "" {
+		cfg.HTTPClient.CassetteMode = v
+	}
+	if v := os.Getenv("HTTP_CASSETTE_DIR"); v != "" {
+		cfg.HTTPClient.CassetteDir = v
+	}
+	if v := os.Getenv("ANALYZER_PROVIDER"); v != "" {
+		cfg.Analyzer.Provider = v
+	}
+	if v := os.Getenv("ANALYZER_API_KEY"); v != "" {
+		cfg.Analyzer.APIKey = v
+	}
+	if v := os.Getenv("ANALYZER_MODEL"); v != "" {
+		cfg.Analyzer.Model = v
+	}
+	if v := os.Getenv("ANALYZER_BASE_URL"); v != "" {
+		cfg.Analyzer.BaseURL = v
+	}
 	if v := os.Getenv("GEMINI_API_KEY"); v != "" {
 		cfg.Gemini.APIKey = v
 	}
 	if v := os.Getenv("GEMINI_MODEL"); v != "" {
 		cfg.Gemini.Model = v
 	}
+	if v := os.Getenv("GEMINI_BASE_URL"); v != "" {
+		cfg.Gemini.BaseURL = v
+	}
+	if v := os.Getenv("IMAGEGEN_PROVIDER"); v != "" {
+		cfg.ImageGen.Provider = v
+	}
 	if v := os.Getenv("IMAGEGEN_API_KEY"); v != "" {
 		cfg.ImageGen.APIKey = v
 	}
 	if v := os.Getenv("IMAGEGEN_MODEL"); v != "" {
 		cfg.ImageGen.Model = v
 	}
+	if v := os.Getenv("IMAGEGEN_BASE_URL"); v != "" {
+		cfg.ImageGen.BaseURL = v
+	}
 	if v := os.Getenv("STORAGE_TYPE"); v != "" {
 		cfg.Storage.Type = v
 	}
@@ -134,5 +305,89 @@ func applyEnvOverrides(cfg *Config) *Config {
 	if v := os.Getenv("STORAGE_BASE_URL"); v != "" {
 		cfg.Storage.BaseURL = v
 	}
+	if v := os.Getenv("STORAGE_URL_SECRET"); v != "" {
+		cfg.Storage.URLSecret = v
+	}
+	if v := os.Getenv("S3_BUCKET"); v != "" {
+		cfg.Storage.S3.Bucket = v
+	}
+	if v := os.Getenv("S3_REGION"); v != "" {
+		cfg.Storage.S3.Region = v
+	}
+	if v := os.Getenv("S3_ENDPOINT"); v != "" {
+		cfg.Storage.S3.Endpoint = v
+	}
+	if v := os.Getenv("S3_USE_PATH_STYLE"); v != "" {
+		cfg.Storage.S3.UsePathStyle = v == "true" || v == "1"
+	}
+	if v := os.Getenv("AWS_ACCESS_KEY_ID"); v != "" {
+		cfg.Storage.S3.AccessKeyID = v
+	}
+	if v := os.Getenv("AWS_SECRET_ACCESS_KEY"); v != "" {
+		cfg.Storage.S3.SecretAccessKey = v
+	}
+	if v := os.Getenv("AWS_SESSION_TOKEN"); v != "" {
+		cfg.Storage.S3.SessionToken = v
+	}
+	if v := os.Getenv("GCS_BUCKET"); v != "" {
+		cfg.Storage.GCS.Bucket = v
+	}
+	if v := os.Getenv("GCS_ENDPOINT"); v != "" {
+		cfg.Storage.GCS.Endpoint = v
+	}
+	if v := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); v != "" {
+		cfg.Storage.GCS.CredentialsFile = v
+	}
+	if v := os.Getenv("GCS_URL_MODE"); v != "" {
+		cfg.Storage.GCS.URLMode = v
+	}
+	if v := os.Getenv("CACHE_TYPE"); v != "" {
+		cfg.Cache.Type = v
+	}
+	if v := os.Getenv("CACHE_PATH"); v != "" {
+		cfg.Cache.Path = v
+	}
+	if v := os.Getenv("WEBHOOK_SECRET"); v != "" {
+		cfg.Webhook.DefaultSecret = v
+	}
+	if v := os.Getenv("WEBHOOK_STORE"); v != "" {
+		cfg.Webhook.Store = v
+	}
+	if v := os.Getenv("WEBHOOK_STORE_PATH"); v != "" {
+		cfg.Webhook.StorePath = v
+	}
+	if v := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); v != "" {
+		cfg.Webhook.AllowPrivateNetworks = v == "true" || v == "1"
+	}
+	if v := os.Getenv("JOB_STORE"); v != "" {
+		cfg.Job.Store = v
+	}
+	if v := os.Getenv("JOB_STORE_PATH"); v != "" {
+		cfg.Job.StorePath = v
+	}
+	if v := os.Getenv("USAGE_STORE"); v != "" {
+		cfg.Usage.Store = v
+	}
+	if v := os.Getenv("USAGE_STORE_PATH"); v != "" {
+		cfg.Usage.StorePath = v
+	}
+	applyLegacyGemini(cfg)
 	return cfg
 }
+
+// applyLegacyGemini 兼容旧版 gemini 段与 GEMINI_* 环境变量，只作用于 gemini 供应商，
+// 避免切换到其他供应商后仍把 Gemini 的地址和密钥发给它
+func applyLegacyGemini(cfg *Config) {
+	if cfg.Analyzer.Provider != "gemini" {
+		return
+	}
+	if cfg.Analyzer.APIKey == "" {
+		cfg.Analyzer.APIKey = cfg.Gemini.APIKey
+	}
+	if cfg.Analyzer.Model == "" {
+		cfg.Analyzer.Model = cfg.Gemini.Model
+	}
+	if cfg.Analyzer.BaseURL == "" {
+		cfg.Analyzer.BaseURL = cfg.Gemini.BaseURL
+	}
+}
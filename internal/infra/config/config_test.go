@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadYAML(t *testing.T, yaml string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLegacyGeminiSectionOnlyAppliesToGemini(t *testing.T) {
+	legacy := `
+gemini:
+  api_key: "gemini-key"
+  model: "gemini-2.0-flash"
+  base_url: "https://gemini.example.com/v1beta"
+`
+	cfg := loadYAML(t, legacy)
+	if cfg.Analyzer.Provider != "gemini" || cfg.Analyzer.APIKey != "gemini-key" || cfg.Analyzer.Model != "gemini-2.0-flash" || cfg.Analyzer.BaseURL != "https://gemini.example.com/v1beta" {
+		t.Errorf("legacy gemini section not applied: %+v", cfg.Analyzer)
+	}
+
+	cfg = loadYAML(t, legacy+`
+analyzer:
+  provider: "openai"
+  api_key: "sk-openai"
+`)
+	if cfg.Analyzer.APIKey != "sk-openai" || cfg.Analyzer.Model != "" || cfg.Analyzer.BaseURL != "" {
+		t.Errorf("gemini settings leaked into the openai analyzer: %+v", cfg.Analyzer)
+	}
+}
+
+func TestAnalyzerSectionOverridesLegacy(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "legacy-key")
+	t.Setenv("ANALYZER_MODEL", "gemini-2.5-pro")
+	cfg := loadYAML(t, `
+analyzer:
+  api_key: "analyzer-key"
+`)
+	if cfg.Analyzer.APIKey != "analyzer-key" || cfg.Analyzer.Model != "gemini-2.5-pro" || cfg.Analyzer.BaseURL != "" {
+		t.Errorf("analyzer = %+v", cfg.Analyzer)
+	}
+}
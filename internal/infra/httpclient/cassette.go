@@ -0,0 +1,443 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 录制/回放模式
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// sensitiveParams 录制时从 URL 中去掉的查询参数
+var sensitiveParams = []string{"key", "api_key", "access_token"}
+
+// recordedHeaders 录制的请求/响应头，Authorization 等凭据不落盘
+var recordedHeaders = []string{"Content-Type"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+type cassetteIDKey struct{}
+
+// WithCassetteID 将任务 ID 写入 ctx，录制时该任务的出站调用归档到同一个 cassette 目录。
+// 任务 ID 由服务端生成，客户端重试或重复提交相同的 client_request_id 不会写入同一个 cassette
+func WithCassetteID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, cassetteIDKey{}, id)
+}
+
+func cassetteIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(cassetteIDKey{}).(string)
+	return id
+}
+
+// Cassette 一个任务的全部出站调用，按录制时间排序
+type Cassette struct {
+	ID           string        `json:"id"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// RecordedRequest 脱敏后的请求；Body 为 JSON 时原样保存，否则以 base64 保存在 RawBody 中
+type RecordedRequest struct {
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	RawBody  []byte            `json:"raw_body,omitempty"`
+	BodyHash string            `json:"body_hash"`
+}
+
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	RawBody    []byte            `json:"raw_body,omitempty"`
+}
+
+// matchKey 回放时按方法、URL 与请求体匹配
+func (r RecordedRequest) matchKey() string {
+	return r.Method + " " + r.URL + " " + r.BodyHash
+}
+
+// CassetteTransport 录制模式下转发请求，并把每次调用脱敏后写成 <dir>/<cassette_id>/ 下的一个文件；
+// ctx 中没有 cassette ID 的调用直接转发，不录制。
+// 回放模式下不访问网络：ctx 中的 cassette ID 对应已录制的目录时在该目录内匹配，
+// 先按方法、URL 与请求体，再只按方法与 URL（如提示词有改动）；否则在全部录制中按方法、URL 与请求体匹配，
+// 因此以相同输入重新提交的新任务也能回放之前任务的录制。
+type CassetteTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+
+	mu sync.Mutex
+	// index 回放模式下全部录制按 matchKey 的索引，未命中时重新扫描目录
+	index map[string][]string
+	// used 回放模式下各 cassette ID 已使用的录制文件，同一请求重复调用时依次返回
+	used map[string]map[string]bool
+}
+
+// NewCassetteTransport 创建录制/回放 transport，next 为空时使用 http.DefaultTransport
+func NewCassetteTransport(mode, dir string, next http.RoundTripper) (*CassetteTransport, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CassetteTransport{
+		mode: mode,
+		dir:  dir,
+		next: next,
+		used: make(map[string]map[string]bool),
+	}, nil
+}
+
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := cassetteIDFrom(req.Context())
+	if id == "" && t.mode == CassetteRecord {
+		return t.next.RoundTrip(req)
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := recordRequest(req, body)
+
+	if t.mode == CassetteReplay {
+		return t.replay(req, id, recorded)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	response := RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    pickHeaders(resp.Header),
+	}
+	response.Body, response.RawBody = splitBody(respBody)
+
+	if err := t.record(id, Interaction{
+		Request:    recorded,
+		Response:   response,
+		RecordedAt: time.Now().UTC(),
+	}); err != nil {
+		return nil, fmt.Errorf("cassette record: %w", err)
+	}
+	return resp, nil
+}
+
+// record 将一次调用写成单独的文件，文件名以录制时间开头，写入开销与已录制的调用数无关，
+// 并发调用各自写入不需要加锁
+func (t *CassetteTransport) record(id string, interaction Interaction) error {
+	dir := t.cassetteDir(id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	hash := interaction.Request.BodyHash
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%020d-%s-*.tmp", interaction.RecordedAt.UnixNano(), hash))
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, strings.TrimSuffix(tmp, ".tmp")+".json")
+}
+
+// replay 在 cassette ID 对应的录制中匹配，没有该录制时在全部录制中按请求内容匹配
+func (t *CassetteTransport) replay(req *http.Request, id string, recorded RecordedRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var (
+		path string
+		err  error
+	)
+	if id != "" {
+		path, err = t.matchCassette(id, recorded)
+	}
+	if path == "" && err == nil {
+		path, err = t.matchAll(id, recorded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassette replay: %w", err)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("cassette replay: no recorded response for %s %s", recorded.Method, recorded.URL)
+	}
+	t.markUsed(id, path)
+
+	interaction, err := loadInteraction(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette replay: %w", err)
+	}
+	recordedResp := interaction.Response
+	body := []byte(recordedResp.Body)
+	if len(body) == 0 {
+		body = recordedResp.RawBody
+	}
+	header := make(http.Header)
+	for k, v := range recordedResp.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recordedResp.StatusCode, http.StatusText(recordedResp.StatusCode)),
+		StatusCode:    recordedResp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// matchCassette 在指定 cassette 内按录制顺序取第一条未使用的匹配条目，先比较请求体再放宽到只比较方法与 URL；
+// 该 cassette 不存在时返回空路径
+func (t *CassetteTransport) matchCassette(id string, recorded RecordedRequest) (string, error) {
+	paths, err := interactionFiles(t.cassetteDir(id))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	interactions := make([]*Interaction, len(paths))
+	for i, path := range paths {
+		if interactions[i], err = loadInteraction(path); err != nil {
+			return "", err
+		}
+	}
+	used := t.used[id]
+	for pass := 0; pass < 2; pass++ {
+		for i, interaction := range interactions {
+			r := interaction.Request
+			if used[paths[i]] || r.Method != recorded.Method || r.URL != recorded.URL {
+				continue
+			}
+			if pass == 0 && r.BodyHash != recorded.BodyHash {
+				continue
+			}
+			return paths[i], nil
+		}
+	}
+	return "", fmt.Errorf("no recorded response for %s %s in %s", recorded.Method, recorded.URL, id)
+}
+
+// matchAll 在全部录制中按方法、URL 与请求体匹配；同一请求有多条录制时依次返回，用完后重复最后一条
+func (t *CassetteTransport) matchAll(id string, recorded RecordedRequest) (string, error) {
+	key := recorded.matchKey()
+	if t.index == nil || len(t.index[key]) == 0 {
+		if err := t.reindex(); err != nil {
+			return "", err
+		}
+	}
+	paths := t.index[key]
+	if len(paths) == 0 {
+		return "", nil
+	}
+	for _, path := range paths {
+		if !t.used[id][path] {
+			return path, nil
+		}
+	}
+	return paths[len(paths)-1], nil
+}
+
+// reindex 扫描全部录制，按 matchKey 建立索引，同一键下按录制时间排序
+func (t *CassetteTransport) reindex() error {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return err
+	}
+	index := make(map[string][]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		paths, err := interactionFiles(filepath.Join(t.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			interaction, err := loadInteraction(path)
+			if err != nil {
+				return err
+			}
+			key := interaction.Request.matchKey()
+			index[key] = append(index[key], path)
+		}
+	}
+	for _, paths := range index {
+		sort.Slice(paths, func(i, j int) bool { return filepath.Base(paths[i]) < filepath.Base(paths[j]) })
+	}
+	t.index = index
+	return nil
+}
+
+func (t *CassetteTransport) markUsed(id, path string) {
+	used := t.used[id]
+	if used == nil {
+		used = make(map[string]bool)
+		t.used[id] = used
+	}
+	used[path] = true
+}
+
+// Load 读取指定任务的 cassette，用于排查问题
+func (t *CassetteTransport) Load(id string) (*Cassette, error) {
+	paths, err := interactionFiles(t.cassetteDir(id))
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{ID: id}
+	for _, path := range paths {
+		interaction, err := loadInteraction(path)
+		if err != nil {
+			return nil, err
+		}
+		cassette.Interactions = append(cassette.Interactions, *interaction)
+	}
+	return cassette, nil
+}
+
+func (t *CassetteTransport) cassetteDir(id string) string {
+	return filepath.Join(t.dir, unsafeFileChars.ReplaceAllString(id, "_"))
+}
+
+// interactionFiles 目录下已录制完成的调用文件，文件名以录制时间开头，排序即录制顺序
+func interactionFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func loadInteraction(path string) (*Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interaction Interaction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &interaction, nil
+}
+
+// readBody 读出请求体并重新放回，使后续转发与重试仍可读取
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func recordRequest(req *http.Request, body []byte) RecordedRequest {
+	sum := sha256.Sum256(body)
+	recorded := RecordedRequest{
+		Method:   req.Method,
+		URL:      sanitizeURL(req.URL),
+		Headers:  pickHeaders(req.Header),
+		BodyHash: hex.EncodeToString(sum[:]),
+	}
+	recorded.Body, recorded.RawBody = splitBody(body)
+	return recorded
+}
+
+// sanitizeURL 去掉 URL 中的凭据参数与 userinfo
+func sanitizeURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	query := clean.Query()
+	for _, param := range sensitiveParams {
+		query.Del(param)
+	}
+	clean.RawQuery = query.Encode()
+	return clean.String()
+}
+
+func pickHeaders(header http.Header) map[string]string {
+	picked := make(map[string]string)
+	for _, name := range recordedHeaders {
+		if v := header.Get(name); v != "" {
+			picked[name] = v
+		}
+	}
+	return picked
+}
+
+func splitBody(body []byte) (json.RawMessage, []byte) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body), nil
+	}
+	return nil, body
+}
@@ -0,0 +1,197 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newCassetteClient(t *testing.T, mode, dir string) (*Client, *CassetteTransport) {
+	t.Helper()
+	transport, err := NewCassetteTransport(mode, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(Options{Transport: transport}), transport
+}
+
+func post(t *testing.T, client *Client, ctx context.Context, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// echoServer 以请求体与调用序号应答，便于区分录制的响应
+func echoServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"call":%d,"echo":%q}`, n, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// sameJSON 录制时 JSON 响应体会被重新缩进，按紧凑形式比较
+func sameJSON(a, b string) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, []byte(a)) != nil || json.Compact(&cb, []byte(b)) != nil {
+		return a == b
+	}
+	return ca.String() == cb.String()
+}
+
+func TestCassetteRecordWritesOneFilePerInteraction(t *testing.T) {
+	srv, _ := echoServer(t)
+	dir := t.TempDir()
+	client, transport := newCassetteClient(t, CassetteRecord, dir)
+
+	ctx := WithCassetteID(context.Background(), "job-1")
+	for i := 0; i < 3; i++ {
+		post(t, client, ctx, srv.URL+"/models/m:generateContent?key=secret", fmt.Sprintf(`{"n":%d}`, i))
+	}
+	// 没有 cassette ID 的调用不录制
+	post(t, client, context.Background(), srv.URL+"/other", `{}`)
+
+	files, err := interactionFiles(filepath.Join(dir, "job-1"))
+	if err != nil || len(files) != 3 {
+		t.Fatalf("files = %v, %v, want 3 interaction files", files, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("cassette dir has %d entries, want only job-1", len(entries))
+	}
+
+	cassette, err := transport.Load("job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, interaction := range cassette.Interactions {
+		if strings.Contains(interaction.Request.URL, "secret") {
+			t.Errorf("api key recorded in %s", interaction.Request.URL)
+		}
+		if want := fmt.Sprintf(`{"n":%d}`, i); !sameJSON(string(interaction.Request.Body), want) {
+			t.Errorf("interaction %d body = %s, want %s in recording order", i, interaction.Request.Body, want)
+		}
+	}
+}
+
+func TestCassetteConcurrentRecording(t *testing.T) {
+	srv, _ := echoServer(t)
+	dir := t.TempDir()
+	client, transport := newCassetteClient(t, CassetteRecord, dir)
+
+	ctx := WithCassetteID(context.Background(), "job-1")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			post(t, client, ctx, srv.URL, fmt.Sprintf(`{"n":%d}`, i))
+		}(i)
+	}
+	wg.Wait()
+
+	cassette, err := transport.Load("job-1")
+	if err != nil || len(cassette.Interactions) != 20 {
+		t.Fatalf("recorded %d interactions, %v, want 20", len(cassette.Interactions), err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "job-1", "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestCassetteRepeatedRequestsUseSeparateJobs(t *testing.T) {
+	srv, _ := echoServer(t)
+	dir := t.TempDir()
+	client, transport := newCassetteClient(t, CassetteRecord, dir)
+
+	// 同一 client_request_id 的重试由不同任务执行，各自归档
+	for _, job := range []string{"job-1", "job-2"} {
+		post(t, client, WithCassetteID(context.Background(), job), srv.URL, `{"prompt":"same"}`)
+	}
+	for _, job := range []string{"job-1", "job-2"} {
+		cassette, err := transport.Load(job)
+		if err != nil || len(cassette.Interactions) != 1 {
+			t.Errorf("%s has %+v, %v, want exactly one interaction", job, cassette, err)
+		}
+	}
+}
+
+func TestCassetteReplayMatchesByContent(t *testing.T) {
+	srv, calls := echoServer(t)
+	dir := t.TempDir()
+	recorder, _ := newCassetteClient(t, CassetteRecord, dir)
+
+	ctx := WithCassetteID(context.Background(), "job-1")
+	_, first := post(t, recorder, ctx, srv.URL+"/a", `{"prompt":"x"}`)
+	_, second := post(t, recorder, ctx, srv.URL+"/a", `{"prompt":"x"}`)
+	_, other := post(t, recorder, ctx, srv.URL+"/a", `{"prompt":"y"}`)
+	srv.Close()
+	recorded := calls.Load()
+
+	replayer, _ := newCassetteClient(t, CassetteReplay, dir)
+	// 新任务以相同输入重新提交，按请求内容命中之前任务的录制
+	replayCtx := WithCassetteID(context.Background(), "job-new")
+	if _, got := post(t, replayer, replayCtx, srv.URL+"/a", `{"prompt":"y"}`); !sameJSON(got, other) {
+		t.Errorf("replay y = %s, want %s", got, other)
+	}
+	if _, got := post(t, replayer, replayCtx, srv.URL+"/a", `{"prompt":"x"}`); !sameJSON(got, first) {
+		t.Errorf("first replay x = %s, want %s", got, first)
+	}
+	if _, got := post(t, replayer, replayCtx, srv.URL+"/a", `{"prompt":"x"}`); !sameJSON(got, second) {
+		t.Errorf("second replay x = %s, want %s", got, second)
+	}
+	// 录制用完后重复最后一条
+	if _, got := post(t, replayer, replayCtx, srv.URL+"/a", `{"prompt":"x"}`); !sameJSON(got, second) {
+		t.Errorf("third replay x = %s, want %s", got, second)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/a", strings.NewReader(`{"prompt":"z"}`))
+	if _, err := replayer.Do(replayCtx, req); err == nil {
+		t.Error("unrecorded request should fail in replay mode")
+	}
+	if calls.Load() != recorded {
+		t.Error("replay reached the network")
+	}
+}
+
+func TestCassetteReplayNamedCassetteFallsBackToURL(t *testing.T) {
+	srv, _ := echoServer(t)
+	dir := t.TempDir()
+	recorder, _ := newCassetteClient(t, CassetteRecord, dir)
+	_, recorded := post(t, recorder, WithCassetteID(context.Background(), "case-1"), srv.URL+"/a", `{"prompt":"old"}`)
+
+	replayer, _ := newCassetteClient(t, CassetteReplay, dir)
+	// 指定录制时，提示词有改动也按方法与 URL 回放
+	status, got := post(t, replayer, WithCassetteID(context.Background(), "case-1"), srv.URL+"/a", `{"prompt":"new"}`)
+	if status != http.StatusOK || !sameJSON(got, recorded) {
+		t.Errorf("replay = %d %s, want %s", status, got, recorded)
+	}
+	// 条目用完后不再匹配
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/a", strings.NewReader(`{"prompt":"new"}`))
+	if _, err := replayer.Do(WithCassetteID(context.Background(), "case-1"), req); err == nil {
+		t.Error("exhausted cassette should fail")
+	}
+}
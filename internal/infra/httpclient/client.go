@@ -12,6 +12,18 @@ import (
 type Options struct {
 	Timeout    time.Duration
 	MaxRetries int
+	// Transport 为空时使用 http.DefaultTransport，可替换为 CassetteTransport 录制或回放出站请求
+	Transport http.RoundTripper
+}
+
+// StatusError 服务端返回 5xx 且重试耗尽，调用方可用 errors.As 取得状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server error: %d, body: %s", e.StatusCode, e.Body)
 }
 
 type Client struct {
@@ -22,7 +34,8 @@ type Client struct {
 func New(opts Options) *Client {
 	return &Client{
 		client: &http.Client{
-			Timeout: opts.Timeout,
+			Timeout:   opts.Timeout,
+			Transport: opts.Transport,
 		},
 		maxRetries: opts.MaxRetries,
 	}
@@ -38,11 +51,23 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 				return nil, ctx.Err()
 			case <-time.After(time.Duration(attempt) * time.Second):
 			}
+			// 上一次尝试已读完请求体，重试前重新获取
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
 		}
 
 		req = req.WithContext(ctx)
 		resp, err := c.client.Do(req)
 		if err != nil {
+			// 请求被取消或超时后不再重试
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			lastErr = err
 			continue
 		}
@@ -50,7 +75,7 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 		if resp.StatusCode >= 500 {
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
-			lastErr = fmt.Errorf("server error: %d, body: %s", resp.StatusCode, string(body))
+			lastErr = &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 			continue
 		}
 
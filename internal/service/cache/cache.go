@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Cache 内容寻址的结果缓存，键由 Key 计算，值为序列化后的结果
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// New 按类型创建缓存：none、memory 或 disk；none 返回 nil，表示不使用缓存
+func New(cacheType, path string, maxEntries int, maxBytes int64) (Cache, error) {
+	switch cacheType {
+	case "none":
+		return nil, nil
+	case "", "memory":
+		return NewMemoryCache(maxEntries, maxBytes), nil
+	case "disk":
+		return NewDiskCache(path, maxBytes)
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", cacheType)
+	}
+}
+
+// Key 对各部分以长度前缀拼接后计算 SHA-256，避免相邻字段拼接产生歧义
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryCache 进程内 LRU 缓存，条目数或总字节数超限时淘汰最久未使用的条目；限制为 0 表示不限
+type MemoryCache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+func NewMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	if c.maxBytes > 0 && int64(len(value)) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		c.size += int64(len(value)) - int64(len(entry.value))
+		entry.value = value
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value})
+		c.size += int64(len(value))
+	}
+
+	for c.order.Len() > 0 && c.overLimit() {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*memoryEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.value))
+	}
+}
+
+func (c *MemoryCache) overLimit() bool {
+	return (c.maxEntries > 0 && c.order.Len() > c.maxEntries) ||
+		(c.maxBytes > 0 && c.size > c.maxBytes)
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache 磁盘缓存，每个条目一个文件（按键前两位分目录）。
+// 总大小超过 maxBytes 时按最近访问时间淘汰；访问时间记录在文件 mtime 中，重启后可恢复淘汰顺序。
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*diskEntry
+}
+
+type diskEntry struct {
+	size     int64
+	accessed time.Time
+}
+
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*diskEntry),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load 扫描缓存目录重建索引，并清理上次写入中断留下的临时文件
+func (c *DiskCache) load() error {
+	err := filepath.WalkDir(c.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(d.Name(), ".tmp") {
+			os.Remove(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		c.entries[d.Name()] = &diskEntry{size: info.Size(), accessed: info.ModTime()}
+		c.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	return nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		entry.accessed = now
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(key, entry)
+		return nil, false
+	}
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (c *DiskCache) Set(key string, value []byte) {
+	if c.maxBytes > 0 && int64(len(value)) > c.maxBytes {
+		return
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// 每次写入使用独立的临时文件，同一键并发写入时互不干扰
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	// 换入文件、更新索引与淘汰在同一把锁内完成，避免 evict 删除刚换入的文件而索引仍指向它
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	c.entries[key] = &diskEntry{size: int64(len(value)), accessed: time.Now()}
+	c.size += int64(len(value))
+	c.evict()
+}
+
+// evict 淘汰最久未访问的条目直到总大小不超过上限，调用方需持有 c.mu
+func (c *DiskCache) evict() {
+	if c.maxBytes <= 0 || c.size <= c.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].accessed.Before(c.entries[keys[j]].accessed)
+	})
+
+	for _, key := range keys {
+		if c.size <= c.maxBytes {
+			break
+		}
+		c.size -= c.entries[key].size
+		delete(c.entries, key)
+		os.Remove(c.path(key))
+	}
+}
+
+// remove 读取失败时移除索引；条目已被并发的 Set 替换时保留新条目
+func (c *DiskCache) remove(key string, stale *diskEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && entry == stale {
+		c.size -= entry.size
+		delete(c.entries, key)
+	}
+}
+
+func (c *DiskCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
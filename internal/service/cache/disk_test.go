@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestDiskCache(t *testing.T, dir string, maxBytes int64) *DiskCache {
+	t.Helper()
+	c, err := NewDiskCache(dir, maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// set 写入后稍作等待，保证各条目的访问时间可区分
+func set(c *DiskCache, key string, size int) {
+	c.Set(key, bytes.Repeat([]byte{key[len(key)-1]}, size))
+	time.Sleep(2 * time.Millisecond)
+}
+
+func cached(c *DiskCache, key string) bool {
+	_, ok := c.Get(key)
+	return ok
+}
+
+// diskFiles 统计缓存目录中的条目文件与残留的临时文件
+func diskFiles(t *testing.T, dir string) (entries, temps int) {
+	t.Helper()
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(d.Name(), ".tmp") {
+			temps++
+		} else {
+			entries++
+		}
+		return nil
+	})
+	return entries, temps
+}
+
+func TestDiskCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 30)
+
+	set(c, "key-a", 10)
+	set(c, "key-b", 10)
+	set(c, "key-c", 10)
+	// 访问 a 后 b 成为最久未使用的条目
+	if !cached(c, "key-a") {
+		t.Fatal("key-a missing before eviction")
+	}
+	time.Sleep(2 * time.Millisecond)
+	set(c, "key-d", 10)
+
+	for key, want := range map[string]bool{"key-a": true, "key-b": false, "key-c": true, "key-d": true} {
+		if got := cached(c, key); got != want {
+			t.Errorf("cached(%s) = %v, want %v", key, got, want)
+		}
+	}
+	if entries, _ := diskFiles(t, dir); entries != 3 {
+		t.Errorf("cache directory holds %d entries, want 3", entries)
+	}
+}
+
+func TestDiskCacheSizeBound(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 25)
+
+	set(c, "key-a", 10)
+	set(c, "key-b", 10)
+	// 超过上限的值不缓存，也不挤掉已有条目
+	set(c, "key-x", 26)
+	if cached(c, "key-x") || !cached(c, "key-a") || !cached(c, "key-b") {
+		t.Fatal("oversized value should be skipped without evicting others")
+	}
+
+	// 覆盖写入按新大小计算
+	set(c, "key-b", 15)
+	if c.size != 25 || !cached(c, "key-a") {
+		t.Fatalf("size = %d after overwrite, want 25", c.size)
+	}
+	set(c, "key-c", 20)
+	if c.size > 25 {
+		t.Errorf("size = %d exceeds bound", c.size)
+	}
+	if cached(c, "key-a") || cached(c, "key-b") || !cached(c, "key-c") {
+		t.Error("older entries should be evicted to fit key-c")
+	}
+}
+
+func TestDiskCacheReloadKeepsOrder(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 30)
+	set(c, "key-a", 10)
+	set(c, "key-b", 10)
+	set(c, "key-c", 10)
+	cached(c, "key-a")
+	time.Sleep(2 * time.Millisecond)
+
+	// 重启后按文件 mtime 恢复访问顺序
+	reloaded := newTestDiskCache(t, dir, 30)
+	if reloaded.size != 30 {
+		t.Fatalf("reloaded size = %d, want 30", reloaded.size)
+	}
+	set(reloaded, "key-d", 10)
+	if cached(reloaded, "key-b") || !cached(reloaded, "key-a") {
+		t.Error("reloaded cache should evict key-b first")
+	}
+}
+
+func TestDiskCacheConcurrentSetKeepsIndexConsistent(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 200)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("key-%d", (g+i)%12)
+				c.Set(key, bytes.Repeat([]byte{byte(g)}, 10+i%20))
+				c.Get(fmt.Sprintf("key-%d", i%12))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	entries, temps := diskFiles(t, dir)
+	if temps != 0 {
+		t.Errorf("%d temporary files left behind", temps)
+	}
+	if entries != len(c.entries) {
+		t.Errorf("index has %d entries but directory has %d files", len(c.entries), entries)
+	}
+	var size int64
+	for key, entry := range c.entries {
+		info, err := os.Stat(c.path(key))
+		if err != nil {
+			t.Errorf("indexed entry %s has no file: %v", key, err)
+			continue
+		}
+		if info.Size() != entry.size {
+			t.Errorf("entry %s indexed as %d bytes, file has %d", key, entry.size, info.Size())
+		}
+		size += entry.size
+	}
+	if size != c.size || c.size > 200 {
+		t.Errorf("tracked size = %d, sum of entries = %d, bound 200", c.size, size)
+	}
+}
@@ -0,0 +1,155 @@
+package gemini
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// replayService 以 testdata/cassettes 中录制的 Gemini 响应回放，不访问网络
+func replayService(t *testing.T, cassette string) (*Service, context.Context) {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := httpclient.NewCassetteTransport(httpclient.CassetteReplay, "testdata/cassettes", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := httpclient.New(httpclient.Options{Transport: transport})
+	s := New("test-key", "gemini-2.0-flash", DefaultBaseURL, client, log)
+	return s, httpclient.WithCassetteID(context.Background(), cassette)
+}
+
+func TestReplayAnalyzeImage(t *testing.T) {
+	s, ctx := replayService(t, "analyze-ok")
+	ctx, recorder := provider.WithUsageRecorder(ctx)
+
+	spec, err := s.AnalyzeImage(ctx, []byte("\x89PNG\r\n\x1a\n"), "zh-CN", "consulting_minimal")
+	if err != nil {
+		t.Fatalf("AnalyzeImage: %v", err)
+	}
+	if spec.Title != "季度销售回顾" || len(spec.Bullets) != 3 || !strings.HasPrefix(spec.ImagePrompt, "A clean bar chart") {
+		t.Errorf("spec = %+v", spec)
+	}
+	calls := recorder.Calls()
+	if len(calls) != 1 || calls[0].PromptTokens != 1290 || calls[0].CandidateTokens != 180 || calls[0].Model != "gemini-2.0-flash" {
+		t.Errorf("recorded usage = %+v", calls)
+	}
+}
+
+func TestReplayAnalyzeImageRepair(t *testing.T) {
+	s, ctx := replayService(t, "analyze-repair")
+	ctx, recorder := provider.WithUsageRecorder(ctx)
+
+	spec, err := s.AnalyzeImage(ctx, []byte("\x89PNG\r\n\x1a\n"), "zh-CN", "consulting_minimal")
+	if err != nil {
+		t.Fatalf("AnalyzeImage: %v", err)
+	}
+	if len(spec.Bullets) != 3 || spec.ImagePrompt == "" {
+		t.Errorf("spec = %+v, want the repaired output", spec)
+	}
+	if n := len(recorder.Calls()); n != 2 {
+		t.Errorf("calls = %d, want the original request and one repair", n)
+	}
+}
+
+func TestReplayAnalyzeImageErrors(t *testing.T) {
+	tests := []struct {
+		cassette string
+		code     string
+		message  string
+	}{
+		{"analyze-safety", errors.ErrCodeContentBlocked, "response blocked: SAFETY (HARM_CATEGORY_DANGEROUS_CONTENT)"},
+		{"analyze-prompt-blocked", errors.ErrCodeContentBlocked, "prompt blocked: PROHIBITED_CONTENT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			s, ctx := replayService(t, tt.cassette)
+			ctx, recorder := provider.WithUsageRecorder(ctx)
+
+			_, err := s.AnalyzeImage(ctx, []byte("\x89PNG\r\n\x1a\n"), "zh-CN", "consulting_minimal")
+			if !errors.Is(err, tt.code) || !strings.Contains(err.Error(), tt.message) {
+				t.Fatalf("error = %v, want %s containing %q", err, tt.code, tt.message)
+			}
+			// 被拦截的调用同样计费
+			if calls := recorder.Calls(); len(calls) != 1 || calls[0].PromptTokens != 1290 {
+				t.Errorf("recorded usage = %+v", calls)
+			}
+		})
+	}
+}
+
+func TestReplayAnalyzeImageMaxTokens(t *testing.T) {
+	tests := []struct {
+		cassette string
+		calls    int
+		code     string
+	}{
+		// 截断在 notes 中，补全 JSON 后即合格
+		{"analyze-max-tokens-closed", 1, ""},
+		// 补全后缺少必填字段，重新生成
+		{"analyze-max-tokens-repair", 2, ""},
+		// 重新生成仍被截断
+		{"analyze-max-tokens", 1 + provider.MaxRepairAttempts, errors.ErrCodeMaxTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			s, ctx := replayService(t, tt.cassette)
+			ctx, recorder := provider.WithUsageRecorder(ctx)
+
+			spec, err := s.AnalyzeImage(ctx, []byte("\x89PNG\r\n\x1a\n"), "zh-CN", "consulting_minimal")
+			if tt.code != "" {
+				if !errors.Is(err, tt.code) || !strings.Contains(err.Error(), "truncated") {
+					t.Fatalf("error = %v, want %s", err, tt.code)
+				}
+			} else if err != nil {
+				t.Fatalf("AnalyzeImage: %v", err)
+			} else if spec.Title != "季度销售回顾" || len(spec.Bullets) != 3 || spec.ImagePrompt == "" {
+				t.Errorf("spec = %+v", spec)
+			}
+			if n := len(recorder.Calls()); n != tt.calls {
+				t.Errorf("calls = %d, want %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestReplayAnalyzeImageOutlineSlideCount(t *testing.T) {
+	tests := []struct {
+		cassette string
+		calls    int
+	}{
+		// 页数不足时要求模型重新生成
+		{"outline-short", 2},
+		// 页数过多时裁掉多余的内容页，保留总结页
+		{"outline-long", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			s, ctx := replayService(t, tt.cassette)
+			ctx, recorder := provider.WithUsageRecorder(ctx)
+
+			slides, err := s.AnalyzeImageOutline(ctx, []byte("\x89PNG\r\n\x1a\n"), "zh-CN", "consulting_minimal", 2)
+			if err != nil {
+				t.Fatalf("AnalyzeImageOutline: %v", err)
+			}
+			var titles []string
+			for _, slide := range slides {
+				titles = append(titles, slide.Title)
+			}
+			if got := strings.Join(titles, ","); got != "年度战略规划,第一部分,第二部分,总结" {
+				t.Errorf("titles = %s", got)
+			}
+			if n := len(recorder.Calls()); n != tt.calls {
+				t.Errorf("calls = %d, want %d", n, tt.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// Response generateContent 接口的响应，只保留用到的字段；imagegen 也使用同一结构
+type Response struct {
+	Candidates     []Candidate     `json:"candidates"`
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+}
+
+type Candidate struct {
+	Content struct {
+		Parts []Part `json:"parts"`
+	} `json:"content"`
+	FinishReason  string         `json:"finishReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+type Part struct {
+	Text       string `json:"text,omitempty"`
+	InlineData *struct {
+		MimeType string `json:"mimeType"`
+		Data     string `json:"data"`
+	} `json:"inlineData,omitempty"`
+}
+
+// PromptFeedback 提示词本身被拦截时 BlockReason 非空，此时没有候选
+type PromptFeedback struct {
+	BlockReason   string         `json:"blockReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// UsageMetadata 本次调用计费的 token 数
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// blockedFinishReasons 因安全策略、版权或违禁内容终止生成的 finishReason
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":                   true,
+	"RECITATION":               true,
+	"BLOCKLIST":                true,
+	"PROHIBITED_CONTENT":       true,
+	"SPII":                     true,
+	"IMAGE_SAFETY":             true,
+	"IMAGE_PROHIBITED_CONTENT": true,
+	"IMAGE_RECITATION":         true,
+}
+
+// ParseResponse 解析响应并检查拦截与截断：提示词被拦截或候选因安全原因终止返回 CONTENT_BLOCKED，
+// 输出被截断返回 MAX_TOKENS 并同时返回已生成的部分，没有候选时返回 emptyCode
+func ParseResponse(body []byte, emptyCode string) (*Response, error) {
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse gemini response")
+	}
+
+	if fb := response.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		return nil, errors.New(errors.ErrCodeContentBlocked, blockedMessage("prompt blocked", fb.BlockReason, fb.SafetyRatings))
+	}
+	if len(response.Candidates) == 0 {
+		return nil, errors.New(emptyCode, "empty response from gemini")
+	}
+
+	candidate := response.Candidates[0]
+	switch {
+	case blockedFinishReasons[candidate.FinishReason]:
+		return nil, errors.New(errors.ErrCodeContentBlocked, blockedMessage("response blocked", candidate.FinishReason, candidate.SafetyRatings))
+	case candidate.FinishReason == "MAX_TOKENS":
+		return &response, errors.New(errors.ErrCodeMaxTokens, "response truncated at maxOutputTokens")
+	}
+
+	return &response, nil
+}
+
+// ParseUsage 读取 usageMetadata，被拦截的响应同样计费；思考 token 按输出计费，计入 CandidateTokens
+func ParseUsage(body []byte) provider.Usage {
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil || response.UsageMetadata == nil {
+		return provider.Usage{}
+	}
+	meta := response.UsageMetadata
+	return provider.Usage{
+		PromptTokens:    meta.PromptTokenCount,
+		CandidateTokens: meta.CandidatesTokenCount + meta.ThoughtsTokenCount,
+		TotalTokens:     meta.TotalTokenCount,
+	}
+}
+
+// FinishReason 第一个候选的 finishReason，用于补充错误信息
+func (r *Response) FinishReason() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	return r.Candidates[0].FinishReason
+}
+
+// blockedMessage 拼接拦截原因与触发拦截的安全类别
+func blockedMessage(prefix, reason string, ratings []SafetyRating) string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" {
+			categories = append(categories, rating.Category)
+		}
+	}
+	if len(categories) == 0 {
+		return fmt.Sprintf("%s: %s", prefix, reason)
+	}
+	return fmt.Sprintf("%s: %s (%s)", prefix, reason, strings.Join(categories, ", "))
+}
@@ -0,0 +1,68 @@
+package gemini
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+// requiredFields SlideSpec 中模型必须输出的字段
+var requiredFields = map[string]bool{
+	"title":        true,
+	"bullets":      true,
+	"image_prompt": true,
+}
+
+// slideSpecSchema 由 provider.SlideSpec 的 json 标签生成 responseSchema（OpenAPI 子集）
+func slideSpecSchema() map[string]interface{} {
+	t := reflect.TypeOf(provider.SlideSpec{})
+
+	properties := make(map[string]interface{})
+	var ordering, required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			properties[name] = map[string]interface{}{"type": "STRING"}
+		case reflect.Slice:
+			properties[name] = map[string]interface{}{
+				"type":     "ARRAY",
+				"items":    map[string]interface{}{"type": "STRING"},
+				"maxItems": provider.MaxBullets,
+			}
+		default:
+			continue
+		}
+		ordering = append(ordering, name)
+		if requiredFields[name] {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":             "OBJECT",
+		"properties":       properties,
+		"required":         required,
+		"propertyOrdering": ordering,
+	}
+}
+
+// outlineSchema {"slides": [SlideSpec...]}
+func outlineSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "OBJECT",
+		"properties": map[string]interface{}{
+			"slides": map[string]interface{}{
+				"type":  "ARRAY",
+				"items": slideSpecSchema(),
+			},
+		},
+		"required": []string{"slides"},
+	}
+}
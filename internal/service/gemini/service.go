@@ -11,60 +11,164 @@ import (
 
 	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
 	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/ChaseRain/img2ppt/pkg/util"
 )
 
-type SlideSpec struct {
-	Title       string   `json:"title"`
-	Subtitle    string   `json:"subtitle"`
-	Bullets     []string `json:"bullets"`
-	Notes       string   `json:"notes"`
-	ImagePrompt string   `json:"image_prompt"`
-	Style       string   `json:"style"`
-}
+// 未配置 base_url、model 时使用的默认值
+const (
+	DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultModel   = "gemini-3-pro-image-preview"
+)
 
+// Service Gemini 图片分析，实现 provider.Analyzer
 type Service struct {
 	apiKey     string
 	model      string
+	baseURL    string
 	httpClient *httpclient.Client
 	logger     *logger.Logger
 }
 
-func New(apiKey, model string, client *httpclient.Client, log *logger.Logger) *Service {
+// New 创建分析服务，baseURL 为 Gemini API 地址，如 https://generativelanguage.googleapis.com/v1beta
+func New(apiKey, model, baseURL string, client *httpclient.Client, log *logger.Logger) *Service {
 	return &Service{
 		apiKey:     apiKey,
 		model:      model,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
 		httpClient: client,
 		logger:     log,
 	}
 }
 
-func (s *Service) AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*SlideSpec, error) {
-	prompt := s.buildPrompt(language, style)
+// NewAnalyzer 按配置创建 Gemini 实现，供 provider.Registry 登记AnalyzerFactory
+func NewAnalyzer(settings provider.Settings) (provider.Analyzer, error) {
+	model := settings.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return New(settings.APIKey, model, baseURL, settings.HTTPClient, settings.Logger), nil
+}
 
-	imageBase64 := base64.StdEncoding.EncodeToString(imageBytes)
-	mimeType := detectMimeType(imageBytes)
+// Model 返回所用模型名，参与结果缓存的键
+func (s *Service) Model() string {
+	return s.model
+}
 
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{
-						"inline_data": map[string]string{
-							"mime_type": mimeType,
-							"data":      imageBase64,
-						},
-					},
-					{
-						"text": prompt,
-					},
-				},
+func (s *Service) AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*provider.SlideSpec, error) {
+	prompt := provider.AnalysisPrompt(language, style)
+
+	var spec *provider.SlideSpec
+	send := s.sender(userContents(imageBytes, prompt), 2048, slideSpecSchema())
+	if err := provider.Validated(ctx, s.logger, send, provider.AcceptSlideSpec(provider.ContentRules, &spec)); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// AnalyzeImageOutline 根据图片规划多页大纲：封面页 + contentSlides 页内容页 + 总结页
+func (s *Service) AnalyzeImageOutline(ctx context.Context, imageBytes []byte, language, style string, contentSlides int) ([]*provider.SlideSpec, error) {
+	prompt := provider.OutlinePrompt(language, style, contentSlides)
+
+	var slides []*provider.SlideSpec
+	send := s.sender(userContents(imageBytes, prompt), 8192, outlineSchema())
+	if err := provider.Validated(ctx, s.logger, send, provider.AcceptOutline(contentSlides+2, s.logger, &slides)); err != nil {
+		return nil, err
+	}
+
+	return slides, nil
+}
+
+// GenerateCover 根据各页标题为多页演示文稿生成封面页（纯文本请求）
+func (s *Service) GenerateCover(ctx context.Context, titles []string, language, style string) (*provider.SlideSpec, error) {
+	prompt := provider.CoverPrompt(titles, language, style)
+
+	var cover *provider.SlideSpec
+	send := s.sender(userContents(nil, prompt), 1024, slideSpecSchema())
+	if err := provider.Validated(ctx, s.logger, send, provider.AcceptSlideSpec(provider.CoverRules, &cover)); err != nil {
+		return nil, err
+	}
+
+	return cover, nil
+}
+
+// ReviseSlide 以多轮对话修改单页：原分析提示词、该页已有内容（model 轮）、修改意见
+func (s *Service) ReviseSlide(ctx context.Context, spec *provider.SlideSpec, instruction, target, language, style string) (*provider.SlideSpec, error) {
+	prompt, reply := provider.RevisionHistory(spec, language, style)
+	contents := []map[string]interface{}{
+		turn("user", prompt),
+		turn("model", reply),
+		turn("user", provider.RevisionPrompt(instruction, target)),
+	}
+
+	var revised *provider.SlideSpec
+	send := s.sender(contents, 2048, slideSpecSchema())
+	if err := provider.Validated(ctx, s.logger, send, provider.AcceptSlideSpec(provider.RevisionRules(spec), &revised)); err != nil {
+		return nil, err
+	}
+
+	return revised, nil
+}
+
+// sender 按 schema 请求结构化输出；修复请求为单轮纯文本，输出被截断时返回已生成的部分与 MAX_TOKENS 错误
+func (s *Service) sender(contents []map[string]interface{}, maxOutputTokens int, schema map[string]interface{}) provider.SendFunc {
+	return func(ctx context.Context, repair string) (string, error) {
+		if repair != "" {
+			contents = userContents(nil, repair)
+		}
+		respBody, err := s.generateContent(ctx, contents, maxOutputTokens, schema)
+		if err != nil {
+			return "", err
+		}
+		return extractText(respBody)
+	}
+}
+
+// userContents 单轮请求，imageBytes 为空时只发送文本
+func userContents(imageBytes []byte, prompt string) []map[string]interface{} {
+	var parts []map[string]interface{}
+	if len(imageBytes) > 0 {
+		parts = append(parts, map[string]interface{}{
+			"inline_data": map[string]string{
+				"mime_type": util.DetectImageMimeType(imageBytes),
+				"data":      base64.StdEncoding.EncodeToString(imageBytes),
 			},
+		})
+	}
+	parts = append(parts, map[string]interface{}{
+		"text": prompt,
+	})
+
+	return []map[string]interface{}{
+		{
+			"parts": parts,
 		},
+	}
+}
+
+// turn 多轮对话中的一轮纯文本，role 为 user 或 model
+func turn(role, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"role":  role,
+		"parts": []map[string]interface{}{{"text": text}},
+	}
+}
+
+// generateContent 调用 generateContent 接口，schema 约束返回的 JSON 结构
+func (s *Service) generateContent(ctx context.Context, contents []map[string]interface{}, maxOutputTokens int, schema map[string]interface{}) ([]byte, error) {
+	requestBody := map[string]interface{}{
+		"contents": contents,
 		"generationConfig": map[string]interface{}{
-			"temperature":     0.7,
-			"maxOutputTokens": 2048,
+			"temperature":      0.7,
+			"maxOutputTokens":  maxOutputTokens,
 			"responseMimeType": "application/json",
+			"responseSchema":   schema,
 		},
 	}
 
@@ -73,8 +177,7 @@ func (s *Service) AnalyzeImage(ctx context.Context, imageBytes []byte, language,
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal request")
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		s.model, s.apiKey)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", s.baseURL, s.model, s.apiKey)
 
 	resp, err := s.httpClient.PostJSON(ctx, url, bodyBytes)
 	if err != nil {
@@ -92,75 +195,28 @@ func (s *Service) AnalyzeImage(ctx context.Context, imageBytes []byte, language,
 		return nil, errors.New(errors.ErrCodeGeminiAPI, fmt.Sprintf("gemini API returned %d", resp.StatusCode))
 	}
 
-	return s.parseResponse(respBody)
-}
-
-func (s *Service) buildPrompt(language, style string) string {
-	return fmt.Sprintf(`你是 PPT 设计助手。输入是一张图片。
-分析图片内容，提取关键信息，输出 JSON：
-{
-  "title": "简洁有力的标题",
-  "subtitle": "副标题（可选）",
-  "bullets": ["要点1", "要点2", "要点3"],
-  "notes": "演讲者备注",
-  "image_prompt": "用于生成插图的描述。禁止出现文字。风格为 %s，16:9，适合作为PPT插图。描述应该与图片内容相关但更加抽象艺术化。",
-  "style": "%s"
-}
-语言：%s。
-请确保输出是有效的 JSON 格式。`, style, style, language)
-}
-
-func (s *Service) parseResponse(body []byte) (*SlideSpec, error) {
-	var response struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse gemini response")
-	}
-
-	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
-		return nil, errors.New(errors.ErrCodeGeminiAPI, "empty response from gemini")
-	}
-
-	text := response.Candidates[0].Content.Parts[0].Text
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
-
-	var spec SlideSpec
-	if err := json.Unmarshal([]byte(text), &spec); err != nil {
-		s.logger.Error("failed to parse slide spec", "text", text, "error", err)
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse slide spec JSON")
-	}
+	usage := ParseUsage(respBody)
+	usage.Model, usage.KeyID = s.model, provider.KeyID(s.apiKey)
+	provider.RecordUsage(ctx, usage)
 
-	return &spec, nil
+	return respBody, nil
 }
 
-func detectMimeType(data []byte) string {
-	if len(data) < 4 {
-		return "application/octet-stream"
+// extractText 取出第一个候选的文本，并去掉 markdown 代码块标记；被拦截时返回对应错误码，
+// 被截断时返回已生成的部分文本与 MAX_TOKENS 错误
+func extractText(body []byte) (string, error) {
+	response, err := ParseResponse(body, errors.ErrCodeGeminiAPI)
+	if response == nil {
+		return "", err
 	}
 
-	if data[0] == 0xFF && data[1] == 0xD8 {
-		return "image/jpeg"
-	}
-	if data[0] == 0x89 && data[1] == 0x50 && data[2] == 0x4E && data[3] == 0x47 {
-		return "image/png"
-	}
-	if data[0] == 0x47 && data[1] == 0x49 && data[2] == 0x46 {
-		return "image/gif"
-	}
-	if data[0] == 0x52 && data[1] == 0x49 && data[2] == 0x46 && data[3] == 0x46 {
-		return "image/webp"
+	parts := response.Candidates[0].Content.Parts
+	if len(parts) == 0 {
+		if err != nil {
+			return "", err
+		}
+		return "", errors.New(errors.ErrCodeGeminiAPI, fmt.Sprintf("empty response from gemini (finish reason %s)", response.FinishReason()))
 	}
 
-	return "image/jpeg"
+	return provider.TrimJSON(parts[0].Text), err
 }
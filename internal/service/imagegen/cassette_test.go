@@ -0,0 +1,64 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/gemini"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// replayService 以 testdata/cassettes 中录制的 Gemini 响应回放，不访问网络
+func replayService(t *testing.T, cassette string) (*Service, context.Context) {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := httpclient.NewCassetteTransport(httpclient.CassetteReplay, "testdata/cassettes", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := httpclient.New(httpclient.Options{Transport: transport})
+	s := New("test-key", "gemini-2.0-flash-preview-image-generation", gemini.DefaultBaseURL, client, log)
+	return s, httpclient.WithCassetteID(context.Background(), cassette)
+}
+
+func TestReplayGenerateSlideImage(t *testing.T) {
+	s, ctx := replayService(t, "image-ok")
+	ctx, recorder := provider.WithUsageRecorder(ctx)
+
+	img, err := s.GenerateSlideImage(ctx, "a bar chart", nil, "consulting_minimal", provider.FidelityNone)
+	if err != nil {
+		t.Fatalf("GenerateSlideImage: %v", err)
+	}
+	if !bytes.HasPrefix(img.Bytes, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("image is not the recorded png: %q", img.Bytes)
+	}
+	if calls := recorder.Calls(); len(calls) != 1 || calls[0].Images != 1 || calls[0].TotalTokens != 1330 {
+		t.Errorf("recorded usage = %+v", calls)
+	}
+}
+
+func TestReplayGenerateSlideImageErrors(t *testing.T) {
+	tests := []struct {
+		cassette string
+		code     string
+	}{
+		{"image-safety", errors.ErrCodeContentBlocked},
+		{"image-text-only", errors.ErrCodeImageGenAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			s, ctx := replayService(t, tt.cassette)
+			_, err := s.GenerateSlideImage(ctx, "a bar chart", nil, "consulting_minimal", provider.FidelityNone)
+			if !errors.Is(err, tt.code) {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
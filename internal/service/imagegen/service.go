@@ -7,43 +7,81 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/gemini"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
 	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/ChaseRain/img2ppt/pkg/util"
 )
 
-type GeneratedImage struct {
-	Bytes []byte
-}
+// DefaultModel 未配置 model 时使用的配图模型
+const DefaultModel = "gemini-3-pro-image-preview"
 
+// Service Gemini 配图生成，实现 provider.ImageGenerator
 type Service struct {
 	apiKey     string
 	model      string
+	baseURL    string
 	httpClient *httpclient.Client
 	logger     *logger.Logger
 }
 
-func New(apiKey, model string, client *httpclient.Client, log *logger.Logger) *Service {
+// New 创建配图服务，baseURL 为 Gemini API 地址，如 https://generativelanguage.googleapis.com/v1beta
+func New(apiKey, model, baseURL string, client *httpclient.Client, log *logger.Logger) *Service {
 	return &Service{
 		apiKey:     apiKey,
 		model:      model,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
 		httpClient: client,
 		logger:     log,
 	}
 }
 
-func (s *Service) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style string) (*GeneratedImage, error) {
-	enhancedPrompt := s.buildImagePrompt(prompt, style)
+// NewGenerator 按配置创建 Gemini 实现，供 provider.Registry 登记ImageGeneratorFactory
+func NewGenerator(settings provider.Settings) (provider.ImageGenerator, error) {
+	model := settings.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = gemini.DefaultBaseURL
+	}
+	return New(settings.APIKey, model, baseURL, settings.HTTPClient, settings.Logger), nil
+}
+
+// Model 返回所用模型名，参与结果缓存的键
+func (s *Service) Model() string {
+	return s.model
+}
+
+// GenerateSlideImage 生成配图。fidelity 不为 none 且 refImage 非空时，参考图作为 inline_data 与提示词一起发送，
+// 并按 fidelity 要求模型遵循原图的构图与配色。
+func (s *Service) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*provider.GeneratedImage, error) {
+	if len(refImage) == 0 || fidelity == "" {
+		fidelity = provider.FidelityNone
+	}
+
+	var parts []map[string]interface{}
+	if fidelity != provider.FidelityNone {
+		parts = append(parts, map[string]interface{}{
+			"inline_data": map[string]string{
+				"mime_type": util.DetectImageMimeType(refImage),
+				"data":      base64.StdEncoding.EncodeToString(refImage),
+			},
+		})
+	}
+	parts = append(parts, map[string]interface{}{
+		"text": provider.ImagePrompt(prompt, style, fidelity),
+	})
 
 	requestBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
-				"parts": []map[string]interface{}{
-					{
-						"text": enhancedPrompt,
-					},
-				},
+				"parts": parts,
 			},
 		},
 		"generationConfig": map[string]interface{}{
@@ -56,8 +94,7 @@ func (s *Service) GenerateSlideImage(ctx context.Context, prompt string, refImag
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal request")
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		s.model, s.apiKey)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", s.baseURL, s.model, s.apiKey)
 
 	resp, err := s.httpClient.PostJSON(ctx, url, bodyBytes)
 	if err != nil {
@@ -75,43 +112,23 @@ func (s *Service) GenerateSlideImage(ctx context.Context, prompt string, refImag
 		return nil, errors.New(errors.ErrCodeImageGenAPI, fmt.Sprintf("image generation API returned %d", resp.StatusCode))
 	}
 
-	return s.parseResponse(respBody)
-}
-
-func (s *Service) buildImagePrompt(prompt, style string) string {
-	return fmt.Sprintf(`Generate a high-quality illustration for a PowerPoint slide.
+	img, err := s.parseResponse(respBody)
 
-Requirements:
-- Aspect ratio: 16:9
-- Style: %s, professional, clean
-- NO text, letters, words, or numbers in the image
-- Abstract, artistic interpretation suitable for business presentation
-- High contrast, visually striking
-
-Description: %s`, style, prompt)
-}
-
-func (s *Service) parseResponse(body []byte) (*GeneratedImage, error) {
-	var response struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text       string `json:"text,omitempty"`
-					InlineData *struct {
-						MimeType string `json:"mimeType"`
-						Data     string `json:"data"`
-					} `json:"inlineData,omitempty"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+	usage := gemini.ParseUsage(respBody)
+	usage.Model, usage.KeyID = s.model, provider.KeyID(s.apiKey)
+	if img != nil {
+		usage.Images = 1
 	}
+	provider.RecordUsage(ctx, usage)
 
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse image gen response")
-	}
+	return img, err
+}
 
-	if len(response.Candidates) == 0 {
-		return nil, errors.New(errors.ErrCodeImageGenAPI, "empty response from image generation")
+// parseResponse 取出第一张图片；安全拦截返回 CONTENT_BLOCKED，由调用方决定是否换用更保守的提示词重试
+func (s *Service) parseResponse(body []byte) (*provider.GeneratedImage, error) {
+	response, err := gemini.ParseResponse(body, errors.ErrCodeImageGenAPI)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, part := range response.Candidates[0].Content.Parts {
@@ -120,9 +137,9 @@ func (s *Service) parseResponse(body []byte) (*GeneratedImage, error) {
 			if err != nil {
 				return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to decode image data")
 			}
-			return &GeneratedImage{Bytes: imageBytes}, nil
+			return &provider.GeneratedImage{Bytes: imageBytes}, nil
 		}
 	}
 
-	return nil, errors.New(errors.ErrCodeImageGenAPI, "no image in response")
+	return nil, errors.New(errors.ErrCodeImageGenAPI, fmt.Sprintf("no image in response (finish reason %s)", response.FinishReason()))
 }
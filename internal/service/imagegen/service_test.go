@@ -0,0 +1,181 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// geminiRequest 假 Gemini 服务端解析的请求体
+type geminiRequest struct {
+	Contents []struct {
+		Parts []struct {
+			Text       string `json:"text"`
+			InlineData *struct {
+				MimeType string `json:"mime_type"`
+				Data     string `json:"data"`
+			} `json:"inline_data"`
+		} `json:"parts"`
+	} `json:"contents"`
+	GenerationConfig struct {
+		ResponseModalities []string `json:"responseModalities"`
+	} `json:"generationConfig"`
+}
+
+var (
+	refPNG    = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 16)...)
+	outputPNG = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{2}, 16)...)
+)
+
+// fakeGemini 记录最近一次请求，按 respond 返回响应
+func fakeGemini(t *testing.T, respond func(w http.ResponseWriter)) (*httptest.Server, *geminiRequest) {
+	t.Helper()
+	var got geminiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/image-model:generateContent" || r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body, _ := io.ReadAll(r.Body)
+		got = geminiRequest{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		respond(w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func imageResponse(w http.ResponseWriter) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"candidates": []map[string]interface{}{{
+			"content": map[string]interface{}{
+				"parts": []map[string]interface{}{
+					{"text": "here is the image"},
+					{"inlineData": map[string]string{"mimeType": "image/png", "data": base64.StdEncoding.EncodeToString(outputPNG)}},
+				},
+			},
+			"finishReason": "STOP",
+		}},
+		"usageMetadata": map[string]int{"promptTokenCount": 10, "candidatesTokenCount": 1290, "totalTokenCount": 1300},
+	})
+}
+
+func newTestService(t *testing.T, baseURL string) *Service {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New("test-key", "image-model", baseURL+"/", httpclient.New(httpclient.Options{}), log)
+}
+
+func TestGenerateSlideImageFidelity(t *testing.T) {
+	tests := []struct {
+		fidelity string
+		ref      []byte
+		inline   bool
+		want     string
+		notWant  string
+	}{
+		{provider.FidelityNone, refPNG, false, "", "Reference image:"},
+		{"", refPNG, false, "", "Reference image:"},
+		{provider.FidelityLoose, nil, false, "", "Reference image:"},
+		{provider.FidelityLoose, refPNG, true, "Borrow its color palette, mood and main subject", "Closely follow its composition"},
+		{provider.FidelityStrict, refPNG, true, "Closely follow its composition, subject placement and perspective", "Borrow its color palette"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fidelity+"_ref"+map[bool]string{true: "yes", false: "no"}[tt.ref != nil], func(t *testing.T) {
+			srv, got := fakeGemini(t, imageResponse)
+			s := newTestService(t, srv.URL)
+
+			ctx, recorder := provider.WithUsageRecorder(context.Background())
+			img, err := s.GenerateSlideImage(ctx, "a lighthouse at dusk", tt.ref, "consulting_minimal", tt.fidelity)
+			if err != nil {
+				t.Fatalf("GenerateSlideImage: %v", err)
+			}
+			if !bytes.Equal(img.Bytes, outputPNG) {
+				t.Error("returned image does not match the inline data")
+			}
+
+			if len(got.Contents) != 1 {
+				t.Fatalf("contents = %d, want 1", len(got.Contents))
+			}
+			parts := got.Contents[0].Parts
+			wantParts := 1
+			if tt.inline {
+				wantParts = 2
+			}
+			if len(parts) != wantParts {
+				t.Fatalf("parts = %d, want %d", len(parts), wantParts)
+			}
+			if tt.inline {
+				inline := parts[0].InlineData
+				if inline == nil || inline.MimeType != "image/png" || inline.Data != base64.StdEncoding.EncodeToString(tt.ref) {
+					t.Errorf("inline_data = %+v, want the reference png", inline)
+				}
+			}
+			text := parts[len(parts)-1].Text
+			if parts[len(parts)-1].InlineData != nil || !strings.Contains(text, "Description: a lighthouse at dusk") {
+				t.Errorf("last part should be the text prompt, got %q", text)
+			}
+			if tt.want != "" && !strings.Contains(text, tt.want) {
+				t.Errorf("prompt missing %q", tt.want)
+			}
+			if strings.Contains(text, tt.notWant) {
+				t.Errorf("prompt unexpectedly contains %q", tt.notWant)
+			}
+			if modalities := got.GenerationConfig.ResponseModalities; len(modalities) != 2 || modalities[1] != "IMAGE" {
+				t.Errorf("responseModalities = %v", modalities)
+			}
+
+			calls := recorder.Calls()
+			if len(calls) != 1 || calls[0].Images != 1 || calls[0].Model != "image-model" || calls[0].TotalTokens != 1300 {
+				t.Errorf("recorded usage = %+v", calls)
+			}
+		})
+	}
+}
+
+func TestGenerateSlideImageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		code    string
+	}{
+		{"blocked", func(w http.ResponseWriter) {
+			w.Write([]byte(`{"candidates":[{"content":{"parts":[]},"finishReason":"IMAGE_SAFETY"}]}`))
+		}, errors.ErrCodeContentBlocked},
+		{"prompt blocked", func(w http.ResponseWriter) {
+			w.Write([]byte(`{"promptFeedback":{"blockReason":"SAFETY"}}`))
+		}, errors.ErrCodeContentBlocked},
+		{"text only", func(w http.ResponseWriter) {
+			w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"sorry"}]},"finishReason":"STOP"}]}`))
+		}, errors.ErrCodeImageGenAPI},
+		{"bad request", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"invalid"}}`))
+		}, errors.ErrCodeImageGenAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := fakeGemini(t, tt.respond)
+			s := newTestService(t, srv.URL)
+			_, err := s.GenerateSlideImage(context.Background(), "prompt", nil, "style", provider.FidelityNone)
+			if !errors.Is(err, tt.code) {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
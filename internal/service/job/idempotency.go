@@ -0,0 +1,54 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+)
+
+// keyEntry 幂等键索引项，过期后同一个键可以重新提交
+type keyEntry struct {
+	jobID       string
+	fingerprint string
+	expiresAt   time.Time
+}
+
+// Fingerprint 计算请求内容摘要，用于判断同一幂等键下的两次请求是否相同。
+// 除标识类字段（RequestID、ClientID、JobID）外的全部字段按固定顺序写入，新增字段时同样追加在末尾
+func Fingerprint(req *orchestrator.GeneratePPTRequest) string {
+	h := sha256.New()
+	writeField(h, req.ImageBytes)
+	for _, img := range req.Images {
+		writeField(h, img)
+	}
+	writeField(h, []byte(req.Language))
+	writeField(h, []byte(req.Style))
+	writeField(h, []byte(req.Mode))
+	writeField(h, binary.BigEndian.AppendUint64(nil, uint64(req.SlideCount)))
+	writeField(h, []byte(req.CallbackURL))
+	writeField(h, []byte(req.ImageFidelity))
+	writeField(h, binary.BigEndian.AppendUint64(nil, uint64(req.Variants)))
+	writeField(h, []byte(strconv.FormatBool(req.NoCache)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField 以长度前缀写入字段，避免相邻字段拼接产生歧义
+func writeField(h hash.Hash, data []byte) {
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(data))))
+	h.Write(data)
+}
+
+// scopedKey 幂等键按客户端隔离
+func scopedKey(clientID, key string) string {
+	return clientID + "\x00" + key
+}
+
+// reusable 该任务的结果可以直接返回或继续等待
+func reusable(job *Job) bool {
+	return job.Status == StatusPending || job.Status == StatusRunning || job.Status == StatusSucceeded
+}
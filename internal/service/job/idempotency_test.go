@@ -0,0 +1,54 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+)
+
+func TestFingerprintCoversEveryContentField(t *testing.T) {
+	base := func() *orchestrator.GeneratePPTRequest {
+		return &orchestrator.GeneratePPTRequest{
+			RequestID:     "req-1",
+			ImageBytes:    []byte("image"),
+			Language:      "zh-CN",
+			Style:         "consulting_minimal",
+			Mode:          "single",
+			SlideCount:    1,
+			ClientID:      "client-a",
+			ImageFidelity: "none",
+			Variants:      1,
+			JobID:         "job-1",
+		}
+	}
+	want := Fingerprint(base())
+
+	changes := map[string]func(r *orchestrator.GeneratePPTRequest){
+		"image":          func(r *orchestrator.GeneratePPTRequest) { r.ImageBytes = []byte("other") },
+		"images":         func(r *orchestrator.GeneratePPTRequest) { r.Images = [][]byte{[]byte("a")} },
+		"language":       func(r *orchestrator.GeneratePPTRequest) { r.Language = "en" },
+		"style":          func(r *orchestrator.GeneratePPTRequest) { r.Style = "dark" },
+		"mode":           func(r *orchestrator.GeneratePPTRequest) { r.Mode = "deck" },
+		"slide count":    func(r *orchestrator.GeneratePPTRequest) { r.SlideCount = 5 },
+		"callback":       func(r *orchestrator.GeneratePPTRequest) { r.CallbackURL = "https://example.com/hook" },
+		"fidelity":       func(r *orchestrator.GeneratePPTRequest) { r.ImageFidelity = "strict" },
+		"variants":       func(r *orchestrator.GeneratePPTRequest) { r.Variants = 0 },
+		"more variants":  func(r *orchestrator.GeneratePPTRequest) { r.Variants = 3 },
+		"no cache":       func(r *orchestrator.GeneratePPTRequest) { r.NoCache = true },
+		"empty fidelity": func(r *orchestrator.GeneratePPTRequest) { r.ImageFidelity = "" },
+	}
+	for name, change := range changes {
+		req := base()
+		change(req)
+		if Fingerprint(req) == want {
+			t.Errorf("%s: fingerprint unchanged", name)
+		}
+	}
+
+	// 标识类字段不影响指纹
+	req := base()
+	req.RequestID, req.ClientID, req.JobID = "req-2", "client-b", "job-2"
+	if Fingerprint(req) != want {
+		t.Error("identity fields changed the fingerprint")
+	}
+}
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+)
+
+// 任务状态
+const (
+	StatusPending   = "PENDING"
+	StatusRunning   = "RUNNING"
+	StatusSucceeded = "SUCCEEDED"
+	StatusFailed    = "FAILED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Job 异步生成任务
+type Job struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+	ClientID  string `json:"client_id,omitempty"`
+	// CallbackURL 任务结束后由 Notifier 投递结果
+	CallbackURL string `json:"callback_url,omitempty"`
+	// IdempotencyKey 按客户端隔离后的 client_request_id，Fingerprint 为请求内容摘要
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Fingerprint    string `json:"fingerprint,omitempty"`
+	// Stage/Message/Progress 为最近一次进度事件
+	Stage    string `json:"stage,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Progress int    `json:"progress"`
+
+	Result       *orchestrator.GeneratePPTResponse `json:"result,omitempty"`
+	ErrorCode    string                            `json:"error_code,omitempty"`
+	ErrorMessage string                            `json:"error_message,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Notifier 在任务进入终态后被调用
+type Notifier interface {
+	JobFinished(job *Job)
+}
+
+// Terminal 任务是否已结束
+func (j *Job) Terminal() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+func (j *Job) clone() *Job {
+	c := *j
+	return &c
+}
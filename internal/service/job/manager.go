@@ -0,0 +1,436 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/google/uuid"
+)
+
+type task struct {
+	jobID string
+	req   *orchestrator.GeneratePPTRequest
+}
+
+type Options struct {
+	Workers   int
+	QueueSize int
+	// IdempotencyTTL 幂等键有效期，从任务创建时开始计算
+	IdempotencyTTL time.Duration
+}
+
+// Manager 异步任务调度：提交后立即返回任务，由后台 worker 池执行编排流程。
+// 同步与流式请求也登记为任务，以便通过 Cancel 中止，并按幂等键复用结果。
+type Manager struct {
+	store        Store
+	orchestrator *orchestrator.Orchestrator
+	notifier     Notifier
+	opts         Options
+	logger       *logger.Logger
+
+	queue  chan task
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+	watches map[string]*watch
+	keys    map[string]keyEntry
+	// editing 正在改选配图或修改内容的任务，同一任务同时只允许一次修改
+	editing map[string]bool
+}
+
+// NewManager 创建任务管理器，notifier 可为空
+func NewManager(store Store, orch *orchestrator.Orchestrator, notifier Notifier, opts Options, log *logger.Logger) *Manager {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		store:        store,
+		orchestrator: orch,
+		notifier:     notifier,
+		opts:         opts,
+		logger:       log,
+		queue:        make(chan task, opts.QueueSize),
+		ctx:          ctx,
+		cancel:       cancel,
+		running:      make(map[string]context.CancelFunc),
+		watches:      make(map[string]*watch),
+		keys:         make(map[string]keyEntry),
+		editing:      make(map[string]bool),
+	}
+
+	m.loadJobs()
+
+	for i := 0; i < opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+
+	return m
+}
+
+// Submit 创建 PENDING 任务并放入队列，队列已满时返回 RATE_LIMITED。
+// key 非空时作为幂等键：命中进行中或已成功的同内容任务时直接返回该任务（reused 为 true），
+// 内容不同时返回 CONFLICT。
+func (m *Manager) Submit(req *orchestrator.GeneratePPTRequest, key string) (job *Job, reused bool, err error) {
+	job, reused, err = m.create(req, key)
+	if err != nil || reused {
+		return job, reused, err
+	}
+
+	select {
+	case m.queue <- task{jobID: job.ID, req: req}:
+	default:
+		// 任务从未被客户端拿到，直接丢弃而不是标记失败，避免触发回调
+		m.discard(job)
+		return nil, false, errors.New(errors.ErrCodeRateLimited, "job queue is full")
+	}
+
+	m.logger.Info("job submitted", "job_id", job.ID, "request_id", req.RequestID)
+	return job, false, nil
+}
+
+// Start 为同步执行（含流式请求）登记任务，随后调用 Execute 执行；
+// reused 为 true 时应改用 Watch 等待已有任务。幂等语义同 Submit。
+func (m *Manager) Start(req *orchestrator.GeneratePPTRequest, key string) (job *Job, reused bool, err error) {
+	return m.create(req, key)
+}
+
+// Execute 执行任务并记录进度与结果，ctx 或 Cancel 任一取消都会中止执行
+func (m *Manager) Execute(ctx context.Context, jobID string, req *orchestrator.GeneratePPTRequest, onProgress orchestrator.ProgressCallback) (*orchestrator.GeneratePPTResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 先登记 cancel 再切换为 RUNNING，保证 Cancel 看到 RUNNING 时一定能取消执行
+	m.mu.Lock()
+	m.running[jobID] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, jobID)
+		m.mu.Unlock()
+	}()
+
+	started := false
+	if _, err := m.store.Update(jobID, func(job *Job) {
+		if job.Status != StatusPending {
+			return
+		}
+		started = true
+		job.Status = StatusRunning
+		job.UpdatedAt = time.Now()
+	}); err != nil {
+		m.logger.Error("failed to mark job running", "job_id", jobID, "error", err)
+		return nil, err
+	}
+	if !started {
+		// 排队期间已被取消
+		m.closeWatch(jobID)
+		return nil, errors.New(errors.ErrCodeCancelled, "job cancelled")
+	}
+
+	record := func(event orchestrator.ProgressEvent) {
+		if _, err := m.store.Update(jobID, func(job *Job) {
+			if job.Terminal() {
+				return
+			}
+			job.Stage = event.Stage
+			job.Message = event.Message
+			job.Progress = event.Progress
+			job.UpdatedAt = time.Now()
+		}); err != nil {
+			m.logger.Warn("failed to record job progress", "job_id", jobID, "error", err)
+		}
+		m.broadcast(jobID, event)
+		if onProgress != nil {
+			onProgress(event)
+		}
+	}
+
+	result, err := m.orchestrator.GeneratePPT(ctx, req, record)
+	m.finish(jobID, result, err)
+	return result, err
+}
+
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Cancel 将任务标记为 CANCELLED 并取消其 context；已结束的任务返回 CONFLICT。
+// 尚未开始执行的任务由这里结束订阅，不必等 worker 出队。
+func (m *Manager) Cancel(id string) (*Job, error) {
+	finished, pending := false, false
+	job, err := m.store.Update(id, func(job *Job) {
+		if job.Terminal() {
+			finished = true
+			return
+		}
+		pending = job.Status == StatusPending
+		job.Status = StatusCancelled
+		job.ErrorCode = errors.ErrCodeCancelled
+		job.ErrorMessage = "job cancelled by client"
+		job.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if finished {
+		return job, errors.New(errors.ErrCodeConflict, "job already finished")
+	}
+
+	m.mu.Lock()
+	cancel := m.running[id]
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	if pending {
+		m.closeWatch(id)
+	}
+
+	m.logger.Info("job cancelled", "job_id", id, "request_id", job.RequestID)
+	m.notify(job)
+	return job, nil
+}
+
+// SelectImage 将已成功任务第 slide 页的配图换成第 candidate 个候选并重新渲染，结果写回任务。
+// 任务未成功或已有修改正在进行时返回 CONFLICT。
+func (m *Manager) SelectImage(ctx context.Context, id string, slide, candidate int) (*Job, error) {
+	job, err := m.edit(id, func(job *Job) (*orchestrator.GeneratePPTResponse, error) {
+		return m.orchestrator.SelectImage(ctx, job.Result, slide, candidate)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("job image selected", "job_id", id, "slide", slide, "candidate", candidate)
+	return job, nil
+}
+
+// Revise 按修改意见调整已成功任务的一页，生成新版本的 .pptx 并写回任务，旧版本保留。
+// 任务未成功或已有修改正在进行时返回 CONFLICT。
+func (m *Manager) Revise(ctx context.Context, id string, req *orchestrator.ReviseRequest) (*Job, error) {
+	job, err := m.edit(id, func(job *Job) (*orchestrator.GeneratePPTResponse, error) {
+		req.ClientID = job.ClientID
+		return m.orchestrator.Revise(ctx, job.Result, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("job revised", "job_id", id, "slide", req.Slide, "version", job.Result.Version)
+	return job, nil
+}
+
+// edit 对已成功任务的结果执行修改并写回，同一任务同时只允许一次修改
+func (m *Manager) edit(id string, apply func(job *Job) (*orchestrator.GeneratePPTResponse, error)) (*Job, error) {
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusSucceeded || job.Result == nil {
+		return nil, errors.New(errors.ErrCodeConflict, "job has not succeeded")
+	}
+
+	m.mu.Lock()
+	if m.editing[id] {
+		m.mu.Unlock()
+		return nil, errors.New(errors.ErrCodeConflict, "another edit of this job is in progress")
+	}
+	m.editing[id] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.editing, id)
+		m.mu.Unlock()
+	}()
+
+	result, err := apply(job)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err = m.store.Update(id, func(job *Job) {
+		job.Result = result
+		job.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		m.logger.Error("failed to record job edit", "job_id", id, "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Close 停止接收任务并等待 worker 退出，正在执行的任务会被取消
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) create(req *orchestrator.GeneratePPTRequest, key string) (*Job, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweepKeys(now)
+
+	var fingerprint string
+	if key != "" {
+		key = scopedKey(req.ClientID, key)
+		fingerprint = Fingerprint(req)
+		if entry, ok := m.keys[key]; ok {
+			if entry.fingerprint != fingerprint {
+				return nil, false, errors.New(errors.ErrCodeConflict, "idempotency key reused with a different payload")
+			}
+			existing, err := m.store.Get(entry.jobID)
+			if err == nil && reusable(existing) {
+				m.logger.Info("idempotent request reused job", "job_id", existing.ID, "request_id", req.RequestID)
+				return existing, true, nil
+			}
+		}
+	}
+
+	job := &Job{
+		ID:             uuid.New().String(),
+		RequestID:      req.RequestID,
+		Status:         StatusPending,
+		ClientID:       req.ClientID,
+		CallbackURL:    req.CallbackURL,
+		IdempotencyKey: key,
+		Fingerprint:    fingerprint,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := m.store.Create(job); err != nil {
+		return nil, false, err
+	}
+	// 存储文件以任务 ID 命名，请求 ID 由客户端提供，可能与其他客户端或过期的旧任务重复
+	req.JobID = job.ID
+
+	m.watches[job.ID] = &watch{done: make(chan struct{})}
+	if key != "" {
+		m.keys[key] = keyEntry{
+			jobID:       job.ID,
+			fingerprint: fingerprint,
+			expiresAt:   now.Add(m.opts.IdempotencyTTL),
+		}
+	}
+	return job, false, nil
+}
+
+// discard 撤销 create 登记的任务，用于任务未能入队的情况
+func (m *Manager) discard(job *Job) {
+	m.mu.Lock()
+	if w := m.watches[job.ID]; w != nil {
+		close(w.done)
+		delete(m.watches, job.ID)
+	}
+	if entry, ok := m.keys[job.IdempotencyKey]; ok && entry.jobID == job.ID {
+		delete(m.keys, job.IdempotencyKey)
+	}
+	m.mu.Unlock()
+
+	if err := m.store.Delete(job.ID); err != nil {
+		m.logger.Warn("failed to delete discarded job", "job_id", job.ID, "error", err)
+	}
+}
+
+// sweepKeys 清理过期的幂等键，调用方需持有 m.mu
+func (m *Manager) sweepKeys(now time.Time) {
+	for key, entry := range m.keys {
+		if !now.Before(entry.expiresAt) {
+			delete(m.keys, key)
+		}
+	}
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case t := <-m.queue:
+			m.Execute(m.ctx, t.jobID, t.req, nil)
+		}
+	}
+}
+
+func (m *Manager) finish(jobID string, result *orchestrator.GeneratePPTResponse, err error) {
+	alreadyCancelled := false
+	job, updateErr := m.store.Update(jobID, func(job *Job) {
+		// Cancel 已写入终态并发出通知
+		if job.Status == StatusCancelled {
+			alreadyCancelled = true
+			return
+		}
+		job.UpdatedAt = time.Now()
+		if err != nil {
+			job.Status = StatusFailed
+			job.ErrorCode = errors.ErrCodeInternal
+			if appErr, ok := err.(*errors.AppError); ok {
+				job.ErrorCode = appErr.Code
+				if appErr.Code == errors.ErrCodeCancelled {
+					job.Status = StatusCancelled
+				}
+			}
+			job.ErrorMessage = err.Error()
+			return
+		}
+		job.Status = StatusSucceeded
+		job.Progress = 100
+		job.Result = result
+	})
+	m.closeWatch(jobID)
+	if updateErr != nil {
+		m.logger.Error("failed to record job result", "job_id", jobID, "error", updateErr)
+		return
+	}
+
+	m.logger.Info("job finished", "job_id", jobID, "status", job.Status)
+	if !alreadyCancelled {
+		m.notify(job)
+	}
+}
+
+func (m *Manager) notify(job *Job) {
+	if m.notifier != nil {
+		m.notifier.JobFinished(job)
+	}
+}
+
+// loadJobs 从存储重建幂等键索引；上次进程退出时未完成的任务无法恢复（输入图片不落盘），标记为失败
+func (m *Manager) loadJobs() {
+	jobs, err := m.store.List()
+	if err != nil {
+		m.logger.Warn("failed to list jobs for recovery", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, job := range jobs {
+		if job.IdempotencyKey != "" {
+			if expiresAt := job.CreatedAt.Add(m.opts.IdempotencyTTL); now.Before(expiresAt) {
+				m.keys[job.IdempotencyKey] = keyEntry{
+					jobID:       job.ID,
+					fingerprint: job.Fingerprint,
+					expiresAt:   expiresAt,
+				}
+			}
+		}
+		if job.Terminal() {
+			continue
+		}
+		m.finish(job.ID, nil, errors.New(errors.ErrCodeInternal, "job interrupted by server restart"))
+	}
+}
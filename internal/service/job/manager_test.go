@@ -0,0 +1,117 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	jobs []*Job
+}
+
+func (n *recordingNotifier) JobFinished(job *Job) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.jobs = append(n.jobs, job)
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.jobs)
+}
+
+func newTestManager(t *testing.T, opts Options) (*Manager, *MemoryStore, *recordingNotifier) {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdempotencyTTL == 0 {
+		opts.IdempotencyTTL = time.Hour
+	}
+	store := NewMemoryStore()
+	notifier := &recordingNotifier{}
+	m := NewManager(store, nil, notifier, opts, log)
+	t.Cleanup(m.Close)
+	return m, store, notifier
+}
+
+func TestSubmitQueueFullDiscardsJob(t *testing.T) {
+	m, store, notifier := newTestManager(t, Options{Workers: 1, QueueSize: 0})
+	// 停掉 worker，无缓冲队列必然入队失败
+	m.Close()
+
+	req := &orchestrator.GeneratePPTRequest{RequestID: "req-1", ClientID: "client-a", CallbackURL: "https://example.com/hook"}
+	_, _, err := m.Submit(req, "key-1")
+	if !errors.Is(err, errors.ErrCodeRateLimited) {
+		t.Fatalf("Submit error = %v, want RATE_LIMITED", err)
+	}
+
+	jobs, _ := store.List()
+	if len(jobs) != 0 {
+		t.Errorf("store holds %d jobs, want 0", len(jobs))
+	}
+	if n := notifier.count(); n != 0 {
+		t.Errorf("notifier called %d times, want 0", n)
+	}
+	if len(m.keys) != 0 || len(m.watches) != 0 {
+		t.Errorf("discarded job left keys=%d watches=%d", len(m.keys), len(m.watches))
+	}
+}
+
+func TestCancelPendingJobReleasesWatchers(t *testing.T) {
+	m, _, notifier := newTestManager(t, Options{Workers: 1})
+
+	job, _, err := m.Start(&orchestrator.GeneratePPTRequest{RequestID: "req-1"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *Job, 1)
+	go func() {
+		j, _ := m.Watch(context.Background(), job.ID, nil)
+		done <- j
+	}()
+	// 等待订阅登记
+	for {
+		m.mu.Lock()
+		subscribed := len(m.watches[job.ID].subs) > 0
+		m.mu.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := m.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case j := <-done:
+		if j == nil || j.Status != StatusCancelled {
+			t.Errorf("watcher got %+v, want CANCELLED job", j)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher still blocked after cancelling a pending job")
+	}
+	if n := notifier.count(); n != 1 {
+		t.Errorf("notifier called %d times, want 1", n)
+	}
+
+	// 之后再执行也不会重复结束订阅或通知
+	if _, err := m.Execute(context.Background(), job.ID, nil, nil); !errors.Is(err, errors.ErrCodeCancelled) {
+		t.Errorf("Execute error = %v, want CANCELLED", err)
+	}
+	if n := notifier.count(); n != 1 {
+		t.Errorf("notifier called %d times after Execute, want 1", n)
+	}
+}
@@ -0,0 +1,238 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// Store 任务持久化接口
+type Store interface {
+	Create(job *Job) error
+	Get(id string) (*Job, error)
+	// Update 在存储内部加锁执行 fn，返回更新后的任务副本
+	Update(id string, fn func(job *Job)) (*Job, error)
+	List() ([]*Job, error)
+	// Delete 删除任务，任务不存在时不报错
+	Delete(id string) error
+}
+
+// NewStore 按类型创建任务存储：memory 或 file
+func NewStore(storeType, path string) (Store, error) {
+	switch storeType {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown job store type %q", storeType)
+	}
+}
+
+// MemoryStore 进程内任务存储，重启后丢失
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+func (s *MemoryStore) Create(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; ok {
+		return errors.New(errors.ErrCodeInternal, "job already exists")
+	}
+	s.jobs[job.ID] = job.clone()
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "job not found")
+	}
+	return job.clone(), nil
+}
+
+func (s *MemoryStore) Update(id string, fn func(job *Job)) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "job not found")
+	}
+	fn(job)
+	return job.clone(), nil
+}
+
+func (s *MemoryStore) List() ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.clone())
+	}
+	sortByCreated(jobs)
+	return jobs, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return nil
+}
+
+// FileStore 每个任务一个 JSON 文件，进程重启后任务仍可查询
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("job store path is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create job store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Create(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path(job.ID)); err == nil {
+		return errors.New(errors.ErrCodeInternal, "job already exists")
+	}
+	return s.write(job)
+}
+
+func (s *FileStore) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(id)
+}
+
+func (s *FileStore) Update(id string, fn func(job *Job)) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.read(id)
+	if err != nil {
+		return nil, err
+	}
+	fn(job)
+	if err := s.write(job); err != nil {
+		return nil, err
+	}
+	return job.clone(), nil
+}
+
+func (s *FileStore) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to list jobs")
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		job, err := s.read(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sortByCreated(jobs)
+	return jobs, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to delete job")
+	}
+	return nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".json")
+}
+
+func (s *FileStore) read(id string) (*Job, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrCodeNotFound, "job not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read job")
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to decode job")
+	}
+	return &job, nil
+}
+
+// write 先写临时文件再重命名，避免进程崩溃时留下半个文件
+func (s *FileStore) write(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to encode job")
+	}
+
+	tmp, err := os.CreateTemp(s.dir, job.ID+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write job")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write job")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write job")
+	}
+	if err := os.Rename(tmp.Name(), s.path(job.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write job")
+	}
+	return nil
+}
+
+func sortByCreated(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"context"
+
+	"github.com/ChaseRain/img2ppt/internal/service/orchestrator"
+)
+
+// watch 进行中任务的进度订阅者
+type watch struct {
+	subs []chan orchestrator.ProgressEvent
+	done chan struct{}
+}
+
+// Watch 订阅进行中任务的进度，直到任务结束或 ctx 取消；任务已结束时直接返回
+func (m *Manager) Watch(ctx context.Context, jobID string, onProgress orchestrator.ProgressCallback) (*Job, error) {
+	m.mu.Lock()
+	w := m.watches[jobID]
+	var sub chan orchestrator.ProgressEvent
+	if w != nil {
+		sub = make(chan orchestrator.ProgressEvent, 16)
+		w.subs = append(w.subs, sub)
+	}
+	m.mu.Unlock()
+
+	if w == nil {
+		return m.store.Get(jobID)
+	}
+	defer m.unsubscribe(jobID, sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event := <-sub:
+			if onProgress != nil {
+				onProgress(event)
+			}
+		case <-w.done:
+			return m.store.Get(jobID)
+		}
+	}
+}
+
+// broadcast 向订阅者转发进度，订阅者处理不过来时丢弃事件
+func (m *Manager) broadcast(jobID string, event orchestrator.ProgressEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.watches[jobID]
+	if w == nil {
+		return
+	}
+	for _, sub := range w.subs {
+		select {
+		case sub <- event:
+		default:
+		}
+	}
+}
+
+func (m *Manager) closeWatch(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w := m.watches[jobID]; w != nil {
+		close(w.done)
+		delete(m.watches, jobID)
+	}
+}
+
+func (m *Manager) unsubscribe(jobID string, sub chan orchestrator.ProgressEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.watches[jobID]
+	if w == nil {
+		return
+	}
+	for i, s := range w.subs {
+		if s == sub {
+			w.subs = append(w.subs[:i], w.subs[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ollama
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// 未配置 base_url、model 时使用的默认值
+const (
+	DefaultBaseURL = "http://localhost:11434"
+	DefaultModel   = "llava"
+)
+
+// Analyzer 基于本地 Ollama /api/chat 接口的图片分析，用于无法访问外网的部署，实现 provider.Analyzer
+type Analyzer struct {
+	model      string
+	baseURL    string
+	httpClient *httpclient.Client
+	logger     *logger.Logger
+}
+
+// NewAnalyzer 按配置创建分析实现，BaseURL 如 http://localhost:11434，Model 需为视觉模型（如 llava）
+func NewAnalyzer(settings provider.Settings) (provider.Analyzer, error) {
+	model := settings.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Analyzer{
+		model:      model,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: settings.HTTPClient,
+		logger:     settings.Logger,
+	}, nil
+}
+
+func (a *Analyzer) Model() string {
+	return a.model
+}
+
+func (a *Analyzer) AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*provider.SlideSpec, error) {
+	return a.slideSpec(ctx, userMessages(imageBytes, provider.AnalysisPrompt(language, style)), 2048, provider.ContentRules)
+}
+
+func (a *Analyzer) AnalyzeImageOutline(ctx context.Context, imageBytes []byte, language, style string, contentSlides int) ([]*provider.SlideSpec, error) {
+	var slides []*provider.SlideSpec
+	send := a.sender(userMessages(imageBytes, provider.OutlinePrompt(language, style, contentSlides)), 8192)
+	if err := provider.Validated(ctx, a.logger, send, provider.AcceptOutline(contentSlides+2, a.logger, &slides)); err != nil {
+		return nil, err
+	}
+	return slides, nil
+}
+
+func (a *Analyzer) GenerateCover(ctx context.Context, titles []string, language, style string) (*provider.SlideSpec, error) {
+	return a.slideSpec(ctx, userMessages(nil, provider.CoverPrompt(titles, language, style)), 1024, provider.CoverRules)
+}
+
+// ReviseSlide 以多轮对话修改单页：原分析提示词、该页已有内容（assistant 轮）、修改意见
+func (a *Analyzer) ReviseSlide(ctx context.Context, spec *provider.SlideSpec, instruction, target, language, style string) (*provider.SlideSpec, error) {
+	prompt, reply := provider.RevisionHistory(spec, language, style)
+	return a.slideSpec(ctx, []map[string]interface{}{
+		{"role": "user", "content": prompt},
+		{"role": "assistant", "content": reply},
+		{"role": "user", "content": provider.RevisionPrompt(instruction, target)},
+	}, 2048, provider.RevisionRules(spec))
+}
+
+// slideSpec 请求单页输出并按 rules 校验
+func (a *Analyzer) slideSpec(ctx context.Context, messages []map[string]interface{}, maxTokens int, rules provider.SpecRules) (*provider.SlideSpec, error) {
+	var spec *provider.SlideSpec
+	if err := provider.Validated(ctx, a.logger, a.sender(messages, maxTokens), provider.AcceptSlideSpec(rules, &spec)); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// sender 以 messages 调用 send，修复请求为单轮纯文本。本地模型常在 JSON 前后附带说明文字或多余逗号，
+// 输出先在本地修复 JSON 再交给校验
+func (a *Analyzer) sender(messages []map[string]interface{}, maxTokens int) provider.SendFunc {
+	return func(ctx context.Context, repair string) (string, error) {
+		if repair != "" {
+			messages = userMessages(nil, repair)
+		}
+		text, err := a.send(ctx, messages, maxTokens)
+		if err != nil {
+			return "", err
+		}
+		return provider.RepairJSON(text), nil
+	}
+}
+
+// userMessages 单轮请求，图片以 base64 放在消息的 images 字段中
+func userMessages(imageBytes []byte, prompt string) []map[string]interface{} {
+	message := map[string]interface{}{
+		"role":    "user",
+		"content": prompt,
+	}
+	if len(imageBytes) > 0 {
+		message["images"] = []string{base64.StdEncoding.EncodeToString(imageBytes)}
+	}
+	return []map[string]interface{}{message}
+}
+
+// send 以给定的消息列表调用 /api/chat（非流式）
+func (a *Analyzer) send(ctx context.Context, messages []map[string]interface{}, maxTokens int) (string, error) {
+	requestBody := map[string]interface{}{
+		"model":    a.model,
+		"messages": messages,
+		"stream":   false,
+		"format":   "json",
+		"options": map[string]interface{}{
+			"temperature": 0.7,
+			"num_predict": maxTokens,
+		},
+	}
+
+	bodyBytes, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal request")
+	}
+
+	resp, err := a.httpClient.PostJSON(ctx, a.baseURL+"/api/chat", bodyBytes)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeOllamaAPI, "ollama API request failed")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		a.logger.Error("ollama API error", "status", resp.StatusCode, "body", string(respBody))
+		return "", errors.New(errors.ErrCodeOllamaAPI, fmt.Sprintf("ollama API returned %d", resp.StatusCode))
+	}
+
+	var response struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to parse ollama response")
+	}
+	if response.Error != "" {
+		return "", errors.New(errors.ErrCodeOllamaAPI, response.Error)
+	}
+	if strings.TrimSpace(response.Message.Content) == "" {
+		return "", errors.New(errors.ErrCodeOllamaAPI, "empty response from ollama")
+	}
+
+	return response.Message.Content, nil
+}
@@ -0,0 +1,135 @@
+package ollama
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// chatServer 以固定的 status 与 body 应答 /api/chat，并把解析后的请求体交给 inspect
+func chatServer(t *testing.T, status int, body string, inspect func(req map[string]interface{})) *Analyzer {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if inspect != nil {
+			inspect(req)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAnalyzer(provider.Settings{
+		Model:      "llava:13b",
+		BaseURL:    srv.URL + "/",
+		HTTPClient: httpclient.New(httpclient.Options{}),
+		Logger:     log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.(*Analyzer)
+}
+
+func TestSendRequest(t *testing.T) {
+	image := []byte("\x89PNG\r\n\x1a\n0000")
+	var got map[string]interface{}
+	a := chatServer(t, http.StatusOK, `{"model":"llava:13b","message":{"role":"assistant","content":"{}"},"done":true}`, func(req map[string]interface{}) {
+		got = req
+	})
+
+	text, err := a.send(context.Background(), userMessages(image, "分析这张图"), 2048)
+	if err != nil || text != "{}" {
+		t.Fatalf("send = %q, %v", text, err)
+	}
+	if got["model"] != "llava:13b" || got["stream"] != false || got["format"] != "json" {
+		t.Errorf("request = %v", got)
+	}
+	if options, _ := got["options"].(map[string]interface{}); options["num_predict"] != float64(2048) {
+		t.Errorf("options = %v", got["options"])
+	}
+	messages, _ := got["messages"].([]interface{})
+	if len(messages) != 1 {
+		t.Fatalf("messages = %v", got["messages"])
+	}
+	message := messages[0].(map[string]interface{})
+	images, _ := message["images"].([]interface{})
+	if message["role"] != "user" || message["content"] != "分析这张图" || len(images) != 1 || images[0] != base64.StdEncoding.EncodeToString(image) {
+		t.Errorf("message = %v", message)
+	}
+}
+
+func TestSenderRepairsJSONLocally(t *testing.T) {
+	// 本地模型常见的说明文字、代码块与多余逗号在交给校验前修复；修复请求为不带图片的单轮消息
+	reply := "好的，以下是结果：\n```json\n{\"title\":\"季度回顾\",\"bullets\":[\"收入增长\",],}\n```\n希望有帮助"
+	body, _ := json.Marshal(map[string]interface{}{"message": map[string]string{"role": "assistant", "content": reply}, "done": true})
+	var messages []interface{}
+	a := chatServer(t, http.StatusOK, string(body), func(req map[string]interface{}) {
+		messages, _ = req["messages"].([]interface{})
+	})
+
+	text, err := a.sender(userMessages([]byte("img"), "分析"), 2048)(context.Background(), "请修正")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if text != `{"title":"季度回顾","bullets":["收入增长"]}` {
+		t.Errorf("text = %s", text)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("messages = %v", messages)
+	}
+	if message := messages[0].(map[string]interface{}); message["content"] != "请修正" || message["images"] != nil {
+		t.Errorf("repair message = %v", message)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+	}{
+		{"model not found", http.StatusNotFound, `{"error":"model \"llava:13b\" not found, try pulling it first"}`, errors.ErrCodeOllamaAPI},
+		{"error field", http.StatusOK, `{"error":"out of memory"}`, errors.ErrCodeOllamaAPI},
+		{"empty content", http.StatusOK, `{"message":{"role":"assistant","content":"  "},"done":true}`, errors.ErrCodeOllamaAPI},
+		{"malformed", http.StatusOK, `{"message":`, errors.ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := chatServer(t, tt.status, tt.body, nil)
+			_, err := a.send(context.Background(), userMessages(nil, "p"), 1024)
+			if !errors.Is(err, tt.code) {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	a, err := NewAnalyzer(provider.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Model() != DefaultModel || a.(*Analyzer).baseURL != DefaultBaseURL {
+		t.Errorf("defaults = %s %s", a.Model(), a.(*Analyzer).baseURL)
+	}
+}
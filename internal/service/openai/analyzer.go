@@ -0,0 +1,219 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/ChaseRain/img2ppt/pkg/util"
+)
+
+// 未配置 base_url、model 时使用的默认值
+const (
+	DefaultBaseURL       = "https://api.openai.com/v1"
+	DefaultAnalyzerModel = "gpt-4o"
+	DefaultImageModel    = "dall-e-3"
+)
+
+// Analyzer 基于 OpenAI 兼容 /chat/completions 接口的图片分析（vLLM、LocalAI 等），实现 provider.Analyzer
+type Analyzer struct {
+	client *client
+	model  string
+	logger *logger.Logger
+}
+
+// NewAnalyzer 按配置创建分析实现，BaseURL 需包含版本前缀，如 https://api.openai.com/v1
+func NewAnalyzer(settings provider.Settings) (provider.Analyzer, error) {
+	model := settings.Model
+	if model == "" {
+		model = DefaultAnalyzerModel
+	}
+	return &Analyzer{
+		client: newClient(settings.APIKey, settings.BaseURL, settings.HTTPClient, settings.Logger),
+		model:  model,
+		logger: settings.Logger,
+	}, nil
+}
+
+func (a *Analyzer) Model() string {
+	return a.model
+}
+
+func (a *Analyzer) AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*provider.SlideSpec, error) {
+	return a.slideSpec(ctx, userMessages(imageBytes, provider.AnalysisPrompt(language, style)), 2048, provider.ContentRules)
+}
+
+func (a *Analyzer) AnalyzeImageOutline(ctx context.Context, imageBytes []byte, language, style string, contentSlides int) ([]*provider.SlideSpec, error) {
+	var slides []*provider.SlideSpec
+	send := a.sender(userMessages(imageBytes, provider.OutlinePrompt(language, style, contentSlides)), 8192)
+	if err := provider.Validated(ctx, a.logger, send, provider.AcceptOutline(contentSlides+2, a.logger, &slides)); err != nil {
+		return nil, err
+	}
+	return slides, nil
+}
+
+func (a *Analyzer) GenerateCover(ctx context.Context, titles []string, language, style string) (*provider.SlideSpec, error) {
+	return a.slideSpec(ctx, userMessages(nil, provider.CoverPrompt(titles, language, style)), 1024, provider.CoverRules)
+}
+
+// ReviseSlide 以多轮对话修改单页：原分析提示词、该页已有内容（assistant 轮）、修改意见
+func (a *Analyzer) ReviseSlide(ctx context.Context, spec *provider.SlideSpec, instruction, target, language, style string) (*provider.SlideSpec, error) {
+	prompt, reply := provider.RevisionHistory(spec, language, style)
+	return a.slideSpec(ctx, []map[string]interface{}{
+		{"role": "user", "content": prompt},
+		{"role": "assistant", "content": reply},
+		{"role": "user", "content": provider.RevisionPrompt(instruction, target)},
+	}, 2048, provider.RevisionRules(spec))
+}
+
+// slideSpec 请求单页输出，并按 rules 校验
+func (a *Analyzer) slideSpec(ctx context.Context, messages []map[string]interface{}, maxTokens int, rules provider.SpecRules) (*provider.SlideSpec, error) {
+	var spec *provider.SlideSpec
+	if err := provider.Validated(ctx, a.logger, a.sender(messages, maxTokens), provider.AcceptSlideSpec(rules, &spec)); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// sender 以 messages 调用 chat，修复请求为单轮纯文本
+func (a *Analyzer) sender(messages []map[string]interface{}, maxTokens int) provider.SendFunc {
+	return func(ctx context.Context, repair string) (string, error) {
+		if repair != "" {
+			messages = userMessages(nil, repair)
+		}
+		return a.chat(ctx, messages, maxTokens)
+	}
+}
+
+// userMessages 单轮请求，图片以 data URI 形式放在 image_url 中，imageBytes 为空时只发送文本
+func userMessages(imageBytes []byte, prompt string) []map[string]interface{} {
+	content := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": prompt,
+		},
+	}
+	if len(imageBytes) > 0 {
+		content = append(content, map[string]interface{}{
+			"type": "image_url",
+			"image_url": map[string]string{
+				"url": fmt.Sprintf("data:%s;base64,%s", util.DetectImageMimeType(imageBytes), base64.StdEncoding.EncodeToString(imageBytes)),
+			},
+		})
+	}
+
+	return []map[string]interface{}{
+		{
+			"role":    "user",
+			"content": content,
+		},
+	}
+}
+
+// chat 以给定的消息列表调用 /chat/completions，要求返回 JSON 对象
+func (a *Analyzer) chat(ctx context.Context, messages []map[string]interface{}, maxTokens int) (string, error) {
+	requestBody := map[string]interface{}{
+		"model":           a.model,
+		"messages":        messages,
+		"temperature":     0.7,
+		"max_tokens":      maxTokens,
+		"response_format": map[string]string{"type": "json_object"},
+	}
+
+	respBody, err := a.client.post(ctx, "/chat/completions", requestBody, errors.ErrCodeOpenAIAPI)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to parse chat completions response")
+	}
+	provider.RecordUsage(ctx, provider.Usage{
+		Model:           a.model,
+		KeyID:           provider.KeyID(a.client.apiKey),
+		PromptTokens:    response.Usage.PromptTokens,
+		CandidateTokens: response.Usage.CompletionTokens,
+		TotalTokens:     response.Usage.TotalTokens,
+	})
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return "", errors.New(errors.ErrCodeOpenAIAPI, "empty response from chat completions")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// client OpenAI 兼容接口的公共请求逻辑
+type client struct {
+	apiKey     string
+	baseURL    string
+	httpClient *httpclient.Client
+	logger     *logger.Logger
+}
+
+func newClient(apiKey, baseURL string, httpClient *httpclient.Client, log *logger.Logger) *client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &client{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		logger:     log,
+	}
+}
+
+// post 发送 JSON 请求，非 200 响应按 errCode 返回错误
+func (c *client) post(ctx context.Context, path string, body interface{}, errCode string) ([]byte, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, errCode, "openai API request failed")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("openai API error", "path", path, "status", resp.StatusCode, "body", string(respBody))
+		return nil, errors.New(errCode, fmt.Sprintf("openai API returned %d", resp.StatusCode))
+	}
+
+	return respBody, nil
+}
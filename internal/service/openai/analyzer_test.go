@@ -0,0 +1,230 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n0000")
+
+const validSpec = `{"title":"季度回顾","bullets":["收入增长","成本下降"],"notes":"讲稿","image_prompt":"a bar chart"}`
+
+// chatRequest 假服务端解析的 /chat/completions 请求体
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	MaxTokens      int               `json:"max_tokens"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+// fakeOpenAI 依次返回 replies 中的内容作为 assistant 消息，并记录收到的请求
+type fakeOpenAI struct {
+	t        *testing.T
+	mu       sync.Mutex
+	replies  []string
+	requests []chatRequest
+	headers  []http.Header
+}
+
+func newFakeOpenAI(t *testing.T, replies ...string) (*fakeOpenAI, *httptest.Server) {
+	f := &fakeOpenAI{t: t, replies: replies}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeOpenAI) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("invalid request body: %v", err)
+	}
+
+	f.mu.Lock()
+	n := len(f.requests)
+	f.requests = append(f.requests, req)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	if n >= len(f.replies) {
+		f.t.Errorf("unexpected request #%d", n+1)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"role": "assistant", "content": f.replies[n]}},
+		},
+		"usage": map[string]int{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120},
+	})
+}
+
+func newTestAnalyzer(t *testing.T, baseURL string) provider.Analyzer {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAnalyzer(provider.Settings{
+		APIKey:     "sk-test",
+		Model:      "vision-model",
+		BaseURL:    baseURL + "/v1/",
+		HTTPClient: httpclient.New(httpclient.Options{}),
+		Logger:     log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAnalyzeImageRequest(t *testing.T) {
+	fake, srv := newFakeOpenAI(t, "```json\n"+validSpec+"\n```")
+	a := newTestAnalyzer(t, srv.URL)
+
+	ctx, recorder := provider.WithUsageRecorder(context.Background())
+	spec, err := a.AnalyzeImage(ctx, testPNG, "zh", "consulting_minimal")
+	if err != nil {
+		t.Fatalf("AnalyzeImage: %v", err)
+	}
+	if spec.Title != "季度回顾" || len(spec.Bullets) != 2 || spec.ImagePrompt != "a bar chart" {
+		t.Errorf("spec = %+v", spec)
+	}
+
+	if len(fake.requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(fake.requests))
+	}
+	if got := fake.headers[0].Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", got)
+	}
+	req := fake.requests[0]
+	if req.Model != "vision-model" || req.MaxTokens != 2048 || req.ResponseFormat["type"] != "json_object" {
+		t.Errorf("request = %+v", req)
+	}
+	var content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+	}
+	if len(req.Messages) != 1 || json.Unmarshal(req.Messages[0].Content, &content) != nil || len(content) != 2 {
+		t.Fatalf("messages = %+v", req.Messages)
+	}
+	if content[0].Type != "text" || content[0].Text != provider.AnalysisPrompt("zh", "consulting_minimal") {
+		t.Errorf("text part = %+v", content[0])
+	}
+	wantURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG)
+	if content[1].Type != "image_url" || content[1].ImageURL.URL != wantURL {
+		t.Errorf("image part = %+v", content[1])
+	}
+
+	calls := recorder.Calls()
+	if len(calls) != 1 || calls[0].Model != "vision-model" || calls[0].PromptTokens != 100 || calls[0].CandidateTokens != 20 || calls[0].KeyID != provider.KeyID("sk-test") {
+		t.Errorf("recorded usage = %+v", calls)
+	}
+}
+
+func TestRepairRequest(t *testing.T) {
+	// 修复请求为单轮纯文本，不再附带图片
+	fake, srv := newFakeOpenAI(t, `{"title":"季度回顾","bullets":[]}`, validSpec)
+	a := newTestAnalyzer(t, srv.URL)
+
+	if _, err := a.AnalyzeImage(context.Background(), testPNG, "zh", ""); err != nil {
+		t.Fatalf("AnalyzeImage: %v", err)
+	}
+	if len(fake.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(fake.requests))
+	}
+	var content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	repair := fake.requests[1]
+	if len(repair.Messages) != 1 || json.Unmarshal(repair.Messages[0].Content, &content) != nil || len(content) != 1 || content[0].Type != "text" {
+		t.Fatalf("repair messages = %+v", repair.Messages)
+	}
+	if repair.MaxTokens != 2048 || !strings.Contains(content[0].Text, "fewer than 1 bullets") {
+		t.Errorf("repair request = %+v", repair)
+	}
+}
+
+func TestReviseSlideConversation(t *testing.T) {
+	fake, srv := newFakeOpenAI(t, validSpec)
+	a := newTestAnalyzer(t, srv.URL)
+
+	original := &provider.SlideSpec{Title: "旧标题", Bullets: []string{"旧要点"}, ImagePrompt: "old"}
+	if _, err := a.ReviseSlide(context.Background(), original, "更正式一些", provider.ReviseText, "zh", ""); err != nil {
+		t.Fatalf("ReviseSlide: %v", err)
+	}
+	messages := fake.requests[0].Messages
+	if len(messages) != 3 || messages[0].Role != "user" || messages[1].Role != "assistant" || messages[2].Role != "user" {
+		t.Fatalf("messages = %+v", messages)
+	}
+	if !strings.Contains(string(messages[1].Content), "旧标题") || !strings.Contains(string(messages[2].Content), "更正式一些") {
+		t.Errorf("conversation does not carry the slide and instruction: %+v", messages)
+	}
+}
+
+func TestChatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+	}{
+		{"bad request", http.StatusBadRequest, `{"error":{"message":"bad"}}`, errors.ErrCodeOpenAIAPI},
+		{"no choices", http.StatusOK, `{"choices":[]}`, errors.ErrCodeOpenAIAPI},
+		{"malformed", http.StatusOK, `{"choices":`, errors.ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			a := newTestAnalyzer(t, srv.URL)
+
+			_, err := a.GenerateCover(context.Background(), []string{"a"}, "zh", "")
+			if !errors.Is(err, tt.code) {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	a, err := NewAnalyzer(provider.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Model() != DefaultAnalyzerModel || a.(*Analyzer).client.baseURL != DefaultBaseURL {
+		t.Errorf("analyzer defaults = %s %s", a.Model(), a.(*Analyzer).client.baseURL)
+	}
+	g, err := NewImageGenerator(provider.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Model() != DefaultImageModel || g.(*ImageGenerator).client.baseURL != DefaultBaseURL {
+		t.Errorf("image generator defaults = %s %s", g.Model(), g.(*ImageGenerator).client.baseURL)
+	}
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// ImageGenerator 基于 OpenAI 兼容 /images/generations 接口的配图生成，实现 provider.ImageGenerator。
+// 该接口不接受参考图，image_fidelity 不生效。
+type ImageGenerator struct {
+	client     *client
+	model      string
+	size       string
+	httpClient *httpclient.Client
+	logger     *logger.Logger
+}
+
+// NewImageGenerator 按配置创建配图实现，ImageSize 为空时由服务端决定尺寸
+func NewImageGenerator(settings provider.Settings) (provider.ImageGenerator, error) {
+	model := settings.Model
+	if model == "" {
+		model = DefaultImageModel
+	}
+	return &ImageGenerator{
+		client:     newClient(settings.APIKey, settings.BaseURL, settings.HTTPClient, settings.Logger),
+		model:      model,
+		size:       settings.ImageSize,
+		httpClient: settings.HTTPClient,
+		logger:     settings.Logger,
+	}, nil
+}
+
+func (g *ImageGenerator) Model() string {
+	return g.model
+}
+
+func (g *ImageGenerator) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*provider.GeneratedImage, error) {
+	if len(refImage) > 0 && fidelity != "" && fidelity != provider.FidelityNone {
+		g.logger.Debug("reference image is not supported by images/generations, ignoring", "fidelity", fidelity)
+	}
+
+	requestBody := map[string]interface{}{
+		"model":           g.model,
+		"prompt":          provider.ImagePrompt(prompt, style, provider.FidelityNone),
+		"n":               1,
+		"response_format": "b64_json",
+	}
+	if g.size != "" {
+		requestBody["size"] = g.size
+	}
+
+	respBody, err := g.client.post(ctx, "/images/generations", requestBody, errors.ErrCodeImageGenAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+			URL     string `json:"url"`
+		} `json:"data"`
+		// Usage 仅 gpt-image 系列返回
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse image generation response")
+	}
+	provider.RecordUsage(ctx, provider.Usage{
+		Model:           g.model,
+		KeyID:           provider.KeyID(g.client.apiKey),
+		PromptTokens:    response.Usage.InputTokens,
+		CandidateTokens: response.Usage.OutputTokens,
+		TotalTokens:     response.Usage.TotalTokens,
+		Images:          len(response.Data),
+	})
+	if len(response.Data) == 0 {
+		return nil, errors.New(errors.ErrCodeImageGenAPI, "no image in response")
+	}
+
+	data := response.Data[0]
+	if data.B64JSON != "" {
+		imageBytes, err := base64.StdEncoding.DecodeString(data.B64JSON)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to decode image data")
+		}
+		return &provider.GeneratedImage{Bytes: imageBytes}, nil
+	}
+	if data.URL != "" {
+		return g.download(ctx, data.URL)
+	}
+
+	return nil, errors.New(errors.ErrCodeImageGenAPI, "no image in response")
+}
+
+// download 部分兼容服务忽略 response_format，只返回图片地址
+func (g *ImageGenerator) download(ctx context.Context, url string) (*provider.GeneratedImage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to create request")
+	}
+
+	resp, err := g.httpClient.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeImageGenAPI, "failed to download generated image")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.ErrCodeImageGenAPI, fmt.Sprintf("image download returned %d", resp.StatusCode))
+	}
+
+	imageBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to read generated image")
+	}
+	return &provider.GeneratedImage{Bytes: imageBytes}, nil
+}
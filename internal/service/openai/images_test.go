@@ -0,0 +1,129 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+func newTestImageGenerator(t *testing.T, baseURL, size string) provider.ImageGenerator {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewImageGenerator(provider.Settings{
+		APIKey:     "sk-test",
+		Model:      "image-model",
+		BaseURL:    baseURL + "/v1",
+		ImageSize:  size,
+		HTTPClient: httpclient.New(httpclient.Options{}),
+		Logger:     log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGenerateSlideImageB64(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/images/generations" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":  []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(testPNG)}},
+			"usage": map[string]int{"input_tokens": 30, "output_tokens": 4000, "total_tokens": 4030},
+		})
+	}))
+	defer srv.Close()
+	g := newTestImageGenerator(t, srv.URL, "1792x1024")
+
+	ctx, recorder := provider.WithUsageRecorder(context.Background())
+	img, err := g.GenerateSlideImage(ctx, "a lighthouse", testPNG, "consulting_minimal", provider.FidelityStrict)
+	if err != nil {
+		t.Fatalf("GenerateSlideImage: %v", err)
+	}
+	if !bytes.Equal(img.Bytes, testPNG) {
+		t.Errorf("image = %q", img.Bytes)
+	}
+
+	// 参考图不被该接口支持，提示词按 none 生成
+	wantPrompt := provider.ImagePrompt("a lighthouse", "consulting_minimal", provider.FidelityNone)
+	if got["model"] != "image-model" || got["prompt"] != wantPrompt || got["response_format"] != "b64_json" || got["size"] != "1792x1024" || got["n"] != float64(1) {
+		t.Errorf("request = %v", got)
+	}
+
+	calls := recorder.Calls()
+	if len(calls) != 1 || calls[0].Images != 1 || calls[0].TotalTokens != 4030 || calls[0].Model != "image-model" {
+		t.Errorf("recorded usage = %+v", calls)
+	}
+}
+
+func TestGenerateSlideImageDownloadsURL(t *testing.T) {
+	var got map[string]interface{}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/v1/images/generations", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]string{{"url": srv.URL + "/generated/1.png"}},
+		})
+	})
+	mux.HandleFunc("/generated/1.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testPNG)
+	})
+	g := newTestImageGenerator(t, srv.URL, "")
+
+	img, err := g.GenerateSlideImage(context.Background(), "a lighthouse", nil, "", provider.FidelityNone)
+	if err != nil {
+		t.Fatalf("GenerateSlideImage: %v", err)
+	}
+	if !bytes.Equal(img.Bytes, testPNG) {
+		t.Errorf("image = %q", img.Bytes)
+	}
+	if _, ok := got["size"]; ok {
+		t.Errorf("size should be omitted when not configured: %v", got)
+	}
+}
+
+func TestGenerateSlideImageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+	}{
+		{"bad request", http.StatusBadRequest, `{"error":{"message":"content_policy_violation"}}`, errors.ErrCodeImageGenAPI},
+		{"empty data", http.StatusOK, `{"data":[]}`, errors.ErrCodeImageGenAPI},
+		{"no image", http.StatusOK, `{"data":[{}]}`, errors.ErrCodeImageGenAPI},
+		{"bad base64", http.StatusOK, `{"data":[{"b64_json":"%%%"}]}`, errors.ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			g := newTestImageGenerator(t, srv.URL, "")
+
+			_, err := g.GenerateSlideImage(context.Background(), "prompt", nil, "", provider.FidelityNone)
+			if !errors.Is(err, tt.code) {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
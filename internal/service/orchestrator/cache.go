@@ -0,0 +1,212 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/internal/service/cache"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// 缓存条目类别
+const (
+	cacheKindAnalysis = "analysis"
+	cacheKindImage    = "image"
+)
+
+// CacheStats 单次生成中分析结果与配图的缓存命中情况
+type CacheStats struct {
+	AnalysisHits   int `json:"analysis_hits"`
+	AnalysisMisses int `json:"analysis_misses"`
+	ImageHits      int `json:"image_hits"`
+	ImageMisses    int `json:"image_misses"`
+	// Bypassed 请求设置了 no_cache：不读取缓存，但仍以新结果刷新缓存
+	Bypassed bool `json:"bypassed,omitempty"`
+}
+
+type cacheStatsKey struct{}
+
+// cacheRecorder 并发生成各页时汇总缓存命中次数
+type cacheRecorder struct {
+	mu    sync.Mutex
+	stats CacheStats
+}
+
+func (r *cacheRecorder) record(kind string, hit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case kind == cacheKindAnalysis && hit:
+		r.stats.AnalysisHits++
+	case kind == cacheKindAnalysis:
+		r.stats.AnalysisMisses++
+	case hit:
+		r.stats.ImageHits++
+	default:
+		r.stats.ImageMisses++
+	}
+}
+
+func (r *cacheRecorder) snapshot() *CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	return &stats
+}
+
+// withCacheStats 为本次生成挂载命中统计；未配置缓存时返回 nil
+func (o *Orchestrator) withCacheStats(ctx context.Context, req *GeneratePPTRequest) (context.Context, *cacheRecorder) {
+	if o.cache == nil {
+		return ctx, nil
+	}
+	recorder := &cacheRecorder{stats: CacheStats{Bypassed: req.NoCache}}
+	return context.WithValue(ctx, cacheStatsKey{}, recorder), recorder
+}
+
+// cached 先按 key 查缓存，未命中时调用 load 并写回；load 失败的结果不缓存
+func (o *Orchestrator) cached(ctx context.Context, req *GeneratePPTRequest, kind, key string, load func() ([]byte, error)) ([]byte, error) {
+	if o.cache == nil {
+		return load()
+	}
+
+	recorder, _ := ctx.Value(cacheStatsKey{}).(*cacheRecorder)
+	if !req.NoCache {
+		if data, ok := o.cache.Get(key); ok {
+			if recorder != nil {
+				recorder.record(kind, true)
+			}
+			o.logger.Debug("cache hit", "request_id", req.RequestID, "kind", kind, "key", key)
+			return data, nil
+		}
+	}
+	if recorder != nil {
+		recorder.record(kind, false)
+	}
+
+	data, err := load()
+	if err != nil {
+		return nil, err
+	}
+	o.cache.Set(key, data)
+	return data, nil
+}
+
+// analyzeImage 分析单张图片，结果按图片内容、语言、风格与模型缓存；缓存未命中时才占用 limiter 名额
+func (o *Orchestrator) analyzeImage(ctx context.Context, req *GeneratePPTRequest, imageBytes []byte) (*provider.SlideSpec, error) {
+	key := cache.Key([]byte(cacheKindAnalysis), imageBytes, []byte(req.Language), []byte(req.Style), []byte(o.analyzer.Model()))
+	data, err := o.cached(ctx, req, cacheKindAnalysis, key, func() ([]byte, error) {
+		release, err := o.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		spec, err := o.analyzer.AnalyzeImage(ctx, imageBytes, req.Language, req.Style)
+		if err != nil {
+			return nil, err
+		}
+		return marshalCached(spec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeSpec(data)
+}
+
+// analyzeOutline 规划 deck 大纲，缓存键额外包含内容页数量
+func (o *Orchestrator) analyzeOutline(ctx context.Context, req *GeneratePPTRequest, contentSlides int) ([]*provider.SlideSpec, error) {
+	key := cache.Key([]byte("outline"), req.ImageBytes, []byte(req.Language), []byte(req.Style), []byte(o.analyzer.Model()), []byte(strconv.Itoa(contentSlides)))
+	data, err := o.cached(ctx, req, cacheKindAnalysis, key, func() ([]byte, error) {
+		release, err := o.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		specs, err := o.analyzer.AnalyzeImageOutline(ctx, req.ImageBytes, req.Language, req.Style, contentSlides)
+		if err != nil {
+			return nil, err
+		}
+		return marshalCached(specs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []*provider.SlideSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to decode cached outline")
+	}
+	return specs, nil
+}
+
+// generateImage 生成配图，结果按提示词、风格与模型缓存；使用参考图时键还包含还原程度与参考图内容。
+// 首次生成被拦截时以保守提示词且不带参考图重试一次，重试结果按实际使用的提示词缓存
+func (o *Orchestrator) generateImage(ctx context.Context, req *GeneratePPTRequest, prompt string, refImage []byte) (*provider.GeneratedImage, error) {
+	fidelity := req.ImageFidelity
+	if fidelity == "" || len(refImage) == 0 {
+		fidelity = provider.FidelityNone
+	}
+
+	img, err := o.generateCachedImage(ctx, req, prompt, refImage, fidelity)
+	if errors.Is(err, errors.ErrCodeContentBlocked) {
+		o.logger.Warn("image blocked, retrying with sanitized prompt", "request_id", req.RequestID, "error", err)
+		img, err = o.generateCachedImage(ctx, req, provider.SanitizeImagePrompt(prompt), nil, provider.FidelityNone)
+	}
+	return img, err
+}
+
+// generateCachedImage 按本次实际发送的提示词、参考图与还原程度查缓存，未命中时占用 limiter 名额生成
+func (o *Orchestrator) generateCachedImage(ctx context.Context, req *GeneratePPTRequest, prompt string, refImage []byte, fidelity string) (*provider.GeneratedImage, error) {
+	parts := [][]byte{[]byte(cacheKindImage), []byte(prompt), []byte(req.Style), []byte(o.imageGen.Model())}
+	if fidelity != provider.FidelityNone {
+		parts = append(parts, []byte(fidelity), refImage)
+	}
+	key := cache.Key(parts...)
+	data, err := o.cached(ctx, req, cacheKindImage, key, func() ([]byte, error) {
+		release, err := o.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		img, err := o.imageGen.GenerateSlideImage(ctx, prompt, refImage, req.Style, fidelity)
+		if err != nil {
+			return nil, err
+		}
+		return img.Bytes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &provider.GeneratedImage{Bytes: data}, nil
+}
+
+func decodeSpec(data []byte) (*provider.SlideSpec, error) {
+	var spec provider.SlideSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to decode cached slide spec")
+	}
+	return &spec, nil
+}
+
+func marshalCached(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to encode cache entry")
+	}
+	return data, nil
+}
+
+// acquire 占用一个 limiter 名额
+func (o *Orchestrator) acquire(ctx context.Context) (func(), error) {
+	release, err := o.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeRateLimited, "rate limit exceeded")
+	}
+	return release, nil
+}
@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/cache"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// blockingGenerator 拦截除保守提示词以外的所有提示词，并记录收到的提示词
+type blockingGenerator struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (g *blockingGenerator) Model() string { return "fake-image-model" }
+
+func (g *blockingGenerator) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*provider.GeneratedImage, error) {
+	g.mu.Lock()
+	g.prompts = append(g.prompts, prompt)
+	g.mu.Unlock()
+	if !strings.HasPrefix(prompt, "An abstract") {
+		return nil, errors.New(errors.ErrCodeContentBlocked, "response blocked: IMAGE_SAFETY")
+	}
+	return &provider.GeneratedImage{Bytes: []byte("sanitized:" + prompt)}, nil
+}
+
+func (g *blockingGenerator) calls() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.prompts...)
+}
+
+func TestSanitizedRetryCachedUnderSanitizedPrompt(t *testing.T) {
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &blockingGenerator{}
+	mem := cache.NewMemoryCache(0, 0)
+	o := New(nil, gen, nil, nil, mem, nil, limiter.New(1, 100), log)
+	req := &GeneratePPTRequest{RequestID: "req-1", Style: "consulting_minimal"}
+
+	const prompt = `A poster quoting "forbidden words"`
+	img, err := o.generateImage(context.Background(), req, prompt, nil)
+	if err != nil {
+		t.Fatalf("generateImage: %v", err)
+	}
+	sanitized := provider.SanitizeImagePrompt(prompt)
+	if string(img.Bytes) != "sanitized:"+sanitized {
+		t.Fatalf("image = %q", img.Bytes)
+	}
+
+	model := []byte(gen.Model())
+	originalKey := cache.Key([]byte(cacheKindImage), []byte(prompt), []byte(req.Style), model)
+	sanitizedKey := cache.Key([]byte(cacheKindImage), []byte(sanitized), []byte(req.Style), model)
+	if _, ok := mem.Get(originalKey); ok {
+		t.Error("sanitized image must not be cached under the original prompt")
+	}
+	if data, ok := mem.Get(sanitizedKey); !ok || string(data) != string(img.Bytes) {
+		t.Error("sanitized image should be cached under the sanitized prompt")
+	}
+
+	// 再次请求时原提示词仍会尝试一次，随后命中保守提示词的缓存
+	if _, err := o.generateImage(context.Background(), req, prompt, nil); err != nil {
+		t.Fatal(err)
+	}
+	calls := gen.calls()
+	want := []string{prompt, sanitized, prompt}
+	if len(calls) != len(want) {
+		t.Fatalf("generator calls = %q, want %q", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %q, want %q", i, calls[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+// SlideProgressData 单页配图进度，Index 从 1 开始
+type SlideProgressData struct {
+	Index       int    `json:"index"`
+	Total       int    `json:"total"`
+	Title       string `json:"title"`
+	ImagePrompt string `json:"image_prompt,omitempty"`
+	HasImage    bool   `json:"has_image"`
+	Error       string `json:"error,omitempty"`
+	// ErrorCode 分析或配图失败时的错误码，如 CONTENT_BLOCKED
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// GenerateDeckPPTWithProgress 由单张图片生成多页演示文稿：封面页 + 内容页 + 总结页
+func (o *Orchestrator) GenerateDeckPPTWithProgress(ctx context.Context, req *GeneratePPTRequest, onProgress ProgressCallback) (*GeneratePPTResponse, error) {
+	contentSlides := req.SlideCount
+	if contentSlides <= 0 {
+		contentSlides = DefaultDeckSlides
+	}
+	if contentSlides > MaxDeckSlides {
+		contentSlides = MaxDeckSlides
+	}
+
+	emit := newEmitter(onProgress)
+
+	o.logger.Info("starting deck generation",
+		"request_id", req.RequestID,
+		"language", req.Language,
+		"style", req.Style,
+		"content_slides", contentSlides,
+	)
+
+	// Step 1: Plan outline
+	emit("analyzing", "正在分析图片并规划大纲...", 10, nil)
+
+	specs, err := o.analyzeOutline(ctx, req, contentSlides)
+	if err != nil {
+		o.logger.Error("failed to plan outline", "request_id", req.RequestID, "error", err)
+		return nil, err
+	}
+
+	slidesData := make([]SlideSpecData, len(specs))
+	for i, spec := range specs {
+		slidesData[i] = SlideSpecData{
+			Title:       spec.Title,
+			Subtitle:    spec.Subtitle,
+			Bullets:     spec.Bullets,
+			Notes:       spec.Notes,
+			ImagePrompt: spec.ImagePrompt,
+		}
+	}
+	emit("outlined", fmt.Sprintf("大纲规划完成，共 %d 页", len(specs)), 30, slidesData)
+
+	o.logger.Info("outline planned", "request_id", req.RequestID, "slides", len(specs))
+
+	// Step 2: Generate one illustration per slide
+	refs := make([][]byte, len(specs))
+	for i := range refs {
+		refs[i] = req.ImageBytes
+	}
+	images := o.generateImages(ctx, req, specs, refs, nil, emit, 30, 80)
+	for i, img := range images {
+		slidesData[i].Candidates = img.candidates
+		slidesData[i].SelectedCandidate = img.selected
+		slidesData[i].ImageFile = img.file
+	}
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Render PPT
+	emit("rendering", "正在渲染 PPT...", 85, nil)
+
+	slides := make([]ppt.Slide, len(specs))
+	for i, spec := range specs {
+		slides[i] = ppt.Slide{Spec: spec, Image: images[i].image}
+	}
+
+	pptBytes, err := o.pptSvc.RenderDeck(slides)
+	if err != nil {
+		o.logger.Error("failed to render PPT", "request_id", req.RequestID, "error", err)
+		return nil, err
+	}
+	o.logger.Info("PPT rendered", "request_id", req.RequestID, "slides", len(slides), "size_bytes", len(pptBytes))
+
+	// Step 4: Save to storage
+	emit("rendering", "正在保存文件...", 90, nil)
+
+	url, err := o.storageSvc.SavePPT(ctx, req.JobID, pptBytes)
+	if err != nil {
+		o.logger.Error("failed to save PPT", "request_id", req.RequestID, "error", err)
+		return nil, err
+	}
+
+	cover := specs[0]
+	emit("complete", "生成完成！", 100, map[string]string{
+		"ppt_url": url,
+		"title":   cover.Title,
+	})
+
+	o.logger.Info("PPT saved successfully",
+		"request_id", req.RequestID,
+		"url", url,
+	)
+
+	return &GeneratePPTResponse{
+		RequestID: req.RequestID,
+		PPTURL:    url,
+		Title:     cover.Title,
+		Subtitle:  cover.Subtitle,
+		Bullets:   cover.Bullets,
+		Notes:     cover.Notes,
+		Slides:    slidesData,
+	}, nil
+}
+
+// generateImages 并发为各页生成配图，每次调用各自占用一个 limiter 名额。
+// refs[i] 为第 i 页的参考图；skip 非空时跳过 skip[i] 为 true 的页。
+// 进度在 [from, to] 区间内按完成页数推进。
+func (o *Orchestrator) generateImages(
+	ctx context.Context,
+	req *GeneratePPTRequest,
+	specs []*provider.SlideSpec,
+	refs [][]byte,
+	skip []bool,
+	emit emitFunc,
+	from, to int,
+) []slideImage {
+	images := make([]slideImage, len(specs))
+
+	total := 0
+	for i := range specs {
+		if skip == nil || !skip[i] {
+			total++
+		}
+	}
+	if total == 0 {
+		return images
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	for i, spec := range specs {
+		if skip != nil && skip[i] {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, spec *provider.SlideSpec) {
+			defer wg.Done()
+
+			progress := SlideProgressData{
+				Index:       i + 1,
+				Total:       len(specs),
+				Title:       spec.Title,
+				ImagePrompt: spec.ImagePrompt,
+			}
+			mu.Lock()
+			current := from + (to-from)*done/total
+			mu.Unlock()
+			emit("slide_generating", fmt.Sprintf("正在生成第 %d/%d 页配图...", i+1, len(specs)), current, progress)
+
+			images[i] = o.generateCandidates(ctx, req, i, len(specs), spec, refs[i], emit, current)
+
+			mu.Lock()
+			done++
+			current = from + (to-from)*done/total
+			mu.Unlock()
+
+			progress.HasImage = images[i].image != nil
+			message := fmt.Sprintf("第 %d/%d 页配图生成完成", i+1, len(specs))
+			if err := images[i].err; err != nil {
+				o.logger.Warn("failed to generate slide image, continuing without image",
+					"request_id", req.RequestID,
+					"slide", i+1,
+					"error", err,
+				)
+				message = fmt.Sprintf("第 %d/%d 页配图生成跳过（将使用默认样式）", i+1, len(specs))
+				progress.Error = err.Error()
+				progress.ErrorCode = errorCode(err)
+			}
+			emit("slide_generated", message, current, progress)
+		}(i, spec)
+	}
+	wg.Wait()
+
+	return images
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fileNamePattern 拆分存储文件名：任务 ID、页序号（配图与候选）与版本号，参见 versionID、slideImageID、candidateID
+var fileNamePattern = regexp.MustCompile(`^(.+?)(?:_slide(\d+)(?:_candidate\d+)?)?(?:_v(\d+))?$`)
+
+// FileRef 存储文件名解析结果，Slide 与 Version 为 0 表示不是单页配图或不是修改后的版本
+type FileRef struct {
+	JobID   string
+	Slide   int
+	Version int
+}
+
+// ParseFileName 按生成时的命名规则解析存储文件名
+func ParseFileName(name string) FileRef {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	m := fileNamePattern.FindStringSubmatch(base)
+	if m == nil {
+		return FileRef{JobID: base}
+	}
+	ref := FileRef{JobID: m[1]}
+	ref.Slide, _ = strconv.Atoi(m[2])
+	ref.Version, _ = strconv.Atoi(m[3])
+	return ref
+}
+
+// FileTitle 下载文件名使用的标题（不含扩展名）：演示文稿取第 1 页标题，配图取所在页标题，修改后的版本附加版本号
+func (r FileRef) FileTitle(result *GeneratePPTResponse) string {
+	title := result.Title
+	if r.Slide > 0 && r.Slide <= len(result.Slides) {
+		title = fmt.Sprintf("%s-%d", result.Slides[r.Slide-1].Title, r.Slide)
+	}
+	if r.Version > 1 {
+		title = fmt.Sprintf("%s-v%d", title, r.Version)
+	}
+	return title
+}
@@ -0,0 +1,236 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// GenerateMultiImagePPTWithProgress 每张输入图片生成一页，按输入顺序合并为一个演示文稿，并在最前面插入封面页。
+// 单张图片分析失败时该页渲染为错误占位页，不影响其余页面。
+func (o *Orchestrator) GenerateMultiImagePPTWithProgress(ctx context.Context, req *GeneratePPTRequest, onProgress ProgressCallback) (*GeneratePPTResponse, error) {
+	if len(req.Images) == 0 {
+		return nil, errors.New(errors.ErrCodeInvalidReq, "no images to process")
+	}
+
+	emit := newEmitter(onProgress)
+
+	o.logger.Info("starting multi-image generation",
+		"request_id", req.RequestID,
+		"language", req.Language,
+		"style", req.Style,
+		"images", len(req.Images),
+	)
+
+	// Step 1: Analyze every image concurrently; slide 0 is reserved for the cover
+	emit("analyzing", fmt.Sprintf("正在分析 %d 张图片...", len(req.Images)), 5, nil)
+
+	total := len(req.Images) + 1
+	specs := make([]*provider.SlideSpec, total)
+	failed := make([]bool, total)
+	errs := make([]error, total)
+	slidesData := make([]SlideSpecData, total)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	for i, imageBytes := range req.Images {
+		wg.Add(1)
+		go func(i int, imageBytes []byte) {
+			defer wg.Done()
+
+			index := i + 1
+			spec, err := o.analyzeOne(ctx, req, i, imageBytes)
+			progress := SlideProgressData{Index: index + 1, Total: total}
+			if err != nil {
+				spec = errorPlaceholder(i, err)
+				failed[index] = true
+				errs[index] = err
+				progress.Error = err.Error()
+				progress.ErrorCode = errorCode(err)
+				slidesData[index].Error = err.Error()
+			}
+			specs[index] = spec
+			progress.Title = spec.Title
+
+			mu.Lock()
+			done++
+			current := 5 + 35*done/len(req.Images)
+			mu.Unlock()
+
+			message := fmt.Sprintf("第 %d/%d 张图片分析完成", i+1, len(req.Images))
+			if err != nil {
+				message = fmt.Sprintf("第 %d/%d 张图片分析失败，将使用占位页", i+1, len(req.Images))
+			}
+			emit("slide_analyzed", message, current, progress)
+		}(i, imageBytes)
+	}
+	wg.Wait()
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	failedCount := 0
+	var titles []string
+	for i := 1; i < total; i++ {
+		if failed[i] {
+			failedCount++
+			continue
+		}
+		titles = append(titles, specs[i].Title)
+	}
+	if failedCount == len(req.Images) {
+		// 沿用第一张图片的错误码，而不是假定某个模型提供方
+		return nil, errors.Wrap(errs[1], errorCode(errs[1]), "all images failed to analyze")
+	}
+
+	// Step 2: Generate cover slide from the analyzed titles
+	specs[0] = o.generateCover(ctx, req, titles)
+	failed[0] = true // 封面页不生成配图
+
+	for i, spec := range specs {
+		slidesData[i].Title = spec.Title
+		slidesData[i].Subtitle = spec.Subtitle
+		slidesData[i].Bullets = spec.Bullets
+		slidesData[i].Notes = spec.Notes
+		slidesData[i].ImagePrompt = spec.ImagePrompt
+	}
+	emit("outlined", fmt.Sprintf("内容分析完成，共 %d 页", total), 45, slidesData)
+
+	o.logger.Info("multi-image analysis completed",
+		"request_id", req.RequestID,
+		"slides", total,
+		"failed", failedCount,
+	)
+
+	// Step 3: Generate illustrations, each slide uses its own source image as reference
+	refs := make([][]byte, total)
+	for i, imageBytes := range req.Images {
+		refs[i+1] = imageBytes
+	}
+	images := o.generateImages(ctx, req, specs, refs, failed, emit, 45, 85)
+	for i, img := range images {
+		slidesData[i].Candidates = img.candidates
+		slidesData[i].SelectedCandidate = img.selected
+		slidesData[i].ImageFile = img.file
+	}
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Render PPT
+	emit("rendering", "正在渲染 PPT...", 88, nil)
+
+	slides := make([]ppt.Slide, total)
+	for i, spec := range specs {
+		slides[i] = ppt.Slide{Spec: spec, Image: images[i].image}
+	}
+
+	pptBytes, err := o.pptSvc.RenderDeck(slides)
+	if err != nil {
+		o.logger.Error("failed to render PPT", "request_id", req.RequestID, "error", err)
+		return nil, err
+	}
+	o.logger.Info("PPT rendered", "request_id", req.RequestID, "slides", len(slides), "size_bytes", len(pptBytes))
+
+	// Step 5: Save to storage
+	emit("rendering", "正在保存文件...", 92, nil)
+
+	url, err := o.storageSvc.SavePPT(ctx, req.JobID, pptBytes)
+	if err != nil {
+		o.logger.Error("failed to save PPT", "request_id", req.RequestID, "error", err)
+		return nil, err
+	}
+
+	cover := specs[0]
+	emit("complete", "生成完成！", 100, map[string]string{
+		"ppt_url": url,
+		"title":   cover.Title,
+	})
+
+	o.logger.Info("PPT saved successfully",
+		"request_id", req.RequestID,
+		"url", url,
+	)
+
+	return &GeneratePPTResponse{
+		RequestID: req.RequestID,
+		PPTURL:    url,
+		Title:     cover.Title,
+		Subtitle:  cover.Subtitle,
+		Notes:     cover.Notes,
+		Slides:    slidesData,
+	}, nil
+}
+
+// analyzeOne 分析单张图片，缓存未命中时在 limiter 名额内调用模型
+func (o *Orchestrator) analyzeOne(ctx context.Context, req *GeneratePPTRequest, index int, imageBytes []byte) (*provider.SlideSpec, error) {
+	spec, err := o.analyzeImage(ctx, req, imageBytes)
+	if err != nil {
+		o.logger.Warn("failed to analyze image, using placeholder slide",
+			"request_id", req.RequestID,
+			"image", index+1,
+			"error", err,
+		)
+		return nil, err
+	}
+	return spec, nil
+}
+
+// generateCover 生成封面页，失败时退回到本地拼装的封面
+func (o *Orchestrator) generateCover(ctx context.Context, req *GeneratePPTRequest, titles []string) *provider.SlideSpec {
+	fallback := &provider.SlideSpec{
+		Title:    titles[0],
+		Subtitle: fmt.Sprintf("共 %d 张图片", len(req.Images)),
+		Style:    req.Style,
+	}
+
+	release, err := o.limiter.Acquire(ctx)
+	if err != nil {
+		o.logger.Warn("failed to acquire limiter for cover", "request_id", req.RequestID, "error", err)
+		return fallback
+	}
+	defer release()
+
+	cover, err := o.analyzer.GenerateCover(ctx, titles, req.Language, req.Style)
+	if err != nil {
+		o.logger.Warn("failed to generate cover, using fallback", "request_id", req.RequestID, "error", err)
+		return fallback
+	}
+	cover.Bullets = nil
+	cover.ImagePrompt = ""
+	return cover
+}
+
+// errorPlaceholder 分析失败时的占位页。演示文稿会交付给最终用户，
+// 这里只放面向用户的提示，原始错误保留在日志和 SlideSpecData.Error 中
+func errorPlaceholder(index int, err error) *provider.SlideSpec {
+	return &provider.SlideSpec{
+		Title:    fmt.Sprintf("第 %d 张图片处理失败", index+1),
+		Subtitle: errorCode(err),
+		Bullets:  []string{"该图片未能生成内容，请检查图片后重试"},
+		Notes:    placeholderNotes(err),
+	}
+}
+
+// placeholderNotes 按错误码给出面向用户的备注说明
+func placeholderNotes(err error) string {
+	switch errorCode(err) {
+	case errors.ErrCodeContentBlocked:
+		return "图片内容未通过安全审核，请更换图片后重试。"
+	case errors.ErrCodeRateLimited:
+		return "服务繁忙，请稍后重试。"
+	case errors.ErrCodeInvalidReq:
+		return "图片格式或内容无法识别，请检查图片后重试。"
+	default:
+		return "图片分析服务暂时不可用，请稍后重试。"
+	}
+}
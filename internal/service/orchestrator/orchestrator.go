@@ -2,27 +2,81 @@ package orchestrator
 
 import (
 	"context"
+	"sync"
 
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
 	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
-	"github.com/ChaseRain/img2ppt/internal/service/gemini"
-	"github.com/ChaseRain/img2ppt/internal/service/imagegen"
+	"github.com/ChaseRain/img2ppt/internal/service/cache"
 	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
 	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
 	"github.com/ChaseRain/img2ppt/pkg/errors"
+	"github.com/google/uuid"
 )
 
+// 生成模式
+const (
+	ModeSingle = "single"
+	ModeDeck   = "deck"
+	ModeImages = "images"
+)
+
+// deck 模式内容页数量（不含封面页与总结页）
+const (
+	DefaultDeckSlides = 3
+	MaxDeckSlides     = 10
+)
+
+// MaxImages 多图模式下单次请求最多上传的图片数量
+const MaxImages = 20
+
 type GeneratePPTRequest struct {
 	RequestID  string
 	ImageBytes []byte
+	// Images 多图模式下的输入，每张图片生成一页
+	Images     [][]byte
 	Language   string
 	Style      string
+	Mode       string
+	SlideCount int
+	// ClientID 调用方标识，用于选择回调签名密钥
+	ClientID string
+	// CallbackURL 生成结束后接收结果的回调地址
+	CallbackURL string
+	// ImageFidelity 配图对原图的还原程度：none、loose 或 strict
+	ImageFidelity string
+	// NoCache 跳过结果缓存，强制重新分析与生成配图
+	NoCache bool
+	// Variants 每页配图的候选数量，大于 1 时可通过 SelectImage 改选
+	Variants int
+	// JobID 所属任务 ID，存储文件以此命名，避免不同客户端或幂等键过期后重复的请求 ID 互相覆盖；
+	// 为空时 GeneratePPT 生成随机 ID
+	JobID string
 }
 
 type GeneratePPTResponse struct {
-	RequestID string
-	PPTURL    string
-	Title     string
+	RequestID string   `json:"request_id"`
+	PPTURL    string   `json:"ppt_url"`
+	Title     string   `json:"title"`
+	Subtitle  string   `json:"subtitle,omitempty"`
+	Bullets   []string `json:"bullets,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+	// Slides 每页的内容与配图，修改与改选配图时据此重新渲染
+	Slides []SlideSpecData `json:"slides,omitempty"`
+	// Language、Style 生成时使用的语言与风格，修改幻灯片时沿用
+	Language string `json:"language,omitempty"`
+	Style    string `json:"style,omitempty"`
+	// Version 当前版本号，Versions 为全部版本（修改或改选配图后才有，第 1 版为原始生成结果）
+	Version  int          `json:"version,omitempty"`
+	Versions []PPTVersion `json:"versions,omitempty"`
+	// Cache 缓存命中情况，未启用缓存时为空
+	Cache *CacheStats `json:"cache,omitempty"`
+	// Usage 本次生成调用模型的 token 数、配图张数与估算费用，缓存命中的步骤不计
+	Usage *usage.Summary `json:"usage,omitempty"`
+	// JobID 存储文件名前缀，修改与改选配图生成的新文件沿用
+	JobID string `json:"job_id,omitempty"`
 }
 
 // ProgressEvent 进度事件
@@ -38,62 +92,119 @@ type SlideSpecData struct {
 	Title       string   `json:"title"`
 	Subtitle    string   `json:"subtitle"`
 	Bullets     []string `json:"bullets"`
+	Notes       string   `json:"notes"`
 	ImagePrompt string   `json:"image_prompt"`
+	// Error 该页生成失败时的错误信息（多图模式）
+	Error string `json:"error,omitempty"`
+	// Candidates 配图候选（variants 大于 1 时），SelectedCandidate 为当前使用的候选序号
+	Candidates        []ImageCandidate `json:"candidates,omitempty"`
+	SelectedCandidate int              `json:"selected_candidate,omitempty"`
+	// ImageFile 当前配图在存储中的文件名，为空表示该页没有配图
+	ImageFile string `json:"image_file,omitempty"`
+}
+
+// spec 还原为渲染与修改使用的 SlideSpec
+func (d SlideSpecData) spec(style string) *provider.SlideSpec {
+	return &provider.SlideSpec{
+		Title:       d.Title,
+		Subtitle:    d.Subtitle,
+		Bullets:     d.Bullets,
+		Notes:       d.Notes,
+		ImagePrompt: d.ImagePrompt,
+		Style:       style,
+	}
 }
 
 // ProgressCallback 进度回调函数
 type ProgressCallback func(event ProgressEvent)
 
 type Orchestrator struct {
-	geminiSvc   *gemini.Service
-	imageGenSvc *imagegen.Service
-	pptSvc      *ppt.Service
-	storageSvc  *storage.Service
-	limiter     *limiter.Limiter
-	logger      *logger.Logger
+	analyzer   provider.Analyzer
+	imageGen   provider.ImageGenerator
+	pptSvc     *ppt.Service
+	storageSvc *storage.Service
+	cache      cache.Cache
+	usage      *usage.Tracker
+	limiter    *limiter.Limiter
+	logger     *logger.Logger
 }
 
 func New(
-	geminiSvc *gemini.Service,
-	imageGenSvc *imagegen.Service,
+	analyzer provider.Analyzer,
+	imageGen provider.ImageGenerator,
 	pptSvc *ppt.Service,
 	storageSvc *storage.Service,
+	resultCache cache.Cache,
+	tracker *usage.Tracker,
 	lim *limiter.Limiter,
 	log *logger.Logger,
 ) *Orchestrator {
 	return &Orchestrator{
-		geminiSvc:   geminiSvc,
-		imageGenSvc: imageGenSvc,
-		pptSvc:      pptSvc,
-		storageSvc:  storageSvc,
-		limiter:     lim,
-		logger:      log,
+		analyzer:   analyzer,
+		imageGen:   imageGen,
+		pptSvc:     pptSvc,
+		storageSvc: storageSvc,
+		cache:      resultCache,
+		usage:      tracker,
+		limiter:    lim,
+		logger:     log,
 	}
 }
 
+// GeneratePPT 根据 Mode 选择单页或多页生成
+func (o *Orchestrator) GeneratePPT(ctx context.Context, req *GeneratePPTRequest, onProgress ProgressCallback) (*GeneratePPTResponse, error) {
+	var (
+		resp *GeneratePPTResponse
+		err  error
+	)
+	if req.JobID == "" {
+		req.JobID = uuid.New().String()
+	}
+	ctx = httpclient.WithCassetteID(ctx, req.JobID)
+	ctx, recorder := o.withCacheStats(ctx, req)
+	ctx, calls := provider.WithUsageRecorder(ctx)
+	switch req.Mode {
+	case ModeDeck:
+		resp, err = o.GenerateDeckPPTWithProgress(ctx, req, onProgress)
+	case ModeImages:
+		resp, err = o.GenerateMultiImagePPTWithProgress(ctx, req, onProgress)
+	default:
+		resp, err = o.GenerateSingleSlidePPTWithProgress(ctx, req, onProgress)
+	}
+
+	status := usage.StatusSucceeded
+	if err != nil {
+		status = usage.StatusFailed
+		// 取消后下游返回的各类错误统一归为 CANCELLED
+		if cancelErr := checkCancelled(ctx); cancelErr != nil {
+			resp, err = nil, cancelErr
+			status = usage.StatusCancelled
+		}
+	}
+	// 失败的请求已产生的调用同样计费，一并结算
+	var summary *usage.Summary
+	if o.usage != nil {
+		summary = o.usage.Settle(req.RequestID, req.ClientID, status, calls.Calls())
+	}
+	if resp != nil {
+		resp.JobID = req.JobID
+		resp.Language, resp.Style = req.Language, req.Style
+		if recorder != nil {
+			resp.Cache = recorder.snapshot()
+		}
+		resp.Usage = summary
+	}
+	return resp, err
+}
+
 // GenerateSingleSlidePPT 同步生成（保持兼容）
 func (o *Orchestrator) GenerateSingleSlidePPT(ctx context.Context, req *GeneratePPTRequest) (*GeneratePPTResponse, error) {
 	return o.GenerateSingleSlidePPTWithProgress(ctx, req, nil)
 }
 
-// GenerateSingleSlidePPTWithProgress 带进度回调的生成
+// GenerateSingleSlidePPTWithProgress 带进度回调的生成，分析与每次配图调用各自占用 limiter 名额
 func (o *Orchestrator) GenerateSingleSlidePPTWithProgress(ctx context.Context, req *GeneratePPTRequest, onProgress ProgressCallback) (*GeneratePPTResponse, error) {
-	release, err := o.limiter.Acquire(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeRateLimited, "rate limit exceeded")
-	}
-	defer release()
-
-	emit := func(stage, message string, progress int, data interface{}) {
-		if onProgress != nil {
-			onProgress(ProgressEvent{
-				Stage:    stage,
-				Message:  message,
-				Progress: progress,
-				Data:     data,
-			})
-		}
-	}
+	emit := newEmitter(onProgress)
 
 	o.logger.Info("starting PPT generation",
 		"request_id", req.RequestID,
@@ -101,21 +212,23 @@ func (o *Orchestrator) GenerateSingleSlidePPTWithProgress(ctx context.Context, r
 		"style", req.Style,
 	)
 
-	// Step 1: Analyze image with Gemini
+	// Step 1: Analyze image
 	emit("analyzing", "正在分析图片内容...", 10, nil)
 
-	slideSpec, err := o.geminiSvc.AnalyzeImage(ctx, req.ImageBytes, req.Language, req.Style)
+	slideSpec, err := o.analyzeImage(ctx, req, req.ImageBytes)
 	if err != nil {
 		o.logger.Error("failed to analyze image", "request_id", req.RequestID, "error", err)
 		return nil, err
 	}
 
-	emit("analyzed", "内容分析完成", 40, SlideSpecData{
+	specData := SlideSpecData{
 		Title:       slideSpec.Title,
 		Subtitle:    slideSpec.Subtitle,
 		Bullets:     slideSpec.Bullets,
+		Notes:       slideSpec.Notes,
 		ImagePrompt: slideSpec.ImagePrompt,
-	})
+	}
+	emit("analyzed", "内容分析完成", 40, specData)
 
 	o.logger.Info("image analysis completed",
 		"request_id", req.RequestID,
@@ -128,18 +241,28 @@ func (o *Orchestrator) GenerateSingleSlidePPTWithProgress(ctx context.Context, r
 		"image_prompt": slideSpec.ImagePrompt,
 	})
 
-	genImg, err := o.imageGenSvc.GenerateSlideImage(ctx, slideSpec.ImagePrompt, req.ImageBytes, req.Style)
-	if err != nil {
+	generated := o.generateCandidates(ctx, req, 0, 1, slideSpec, req.ImageBytes, emit, 50)
+	genImg := generated.image
+	if generated.err != nil {
 		o.logger.Warn("failed to generate image, continuing without image",
 			"request_id", req.RequestID,
-			"error", err,
+			"error", generated.err,
 		)
-		genImg = nil
-		emit("generated", "配图生成跳过（将使用默认样式）", 70, nil)
+		emit("generated", "配图生成跳过（将使用默认样式）", 70, map[string]string{
+			"error_code": errorCode(generated.err),
+			"error":      generated.err.Error(),
+		})
 	} else {
 		emit("generated", "配图生成完成", 70, nil)
 		o.logger.Info("slide image generated", "request_id", req.RequestID)
 	}
+	specData.Candidates = generated.candidates
+	specData.SelectedCandidate = generated.selected
+	specData.ImageFile = generated.file
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
 
 	// Step 3: Render PPT
 	emit("rendering", "正在渲染 PPT...", 80, nil)
@@ -154,7 +277,7 @@ func (o *Orchestrator) GenerateSingleSlidePPTWithProgress(ctx context.Context, r
 	// Step 4: Save to storage
 	emit("rendering", "正在保存文件...", 90, nil)
 
-	url, err := o.storageSvc.SavePPT(ctx, req.RequestID, pptBytes)
+	url, err := o.storageSvc.SavePPT(ctx, req.JobID, pptBytes)
 	if err != nil {
 		o.logger.Error("failed to save PPT", "request_id", req.RequestID, "error", err)
 		return nil, err
@@ -170,9 +293,50 @@ func (o *Orchestrator) GenerateSingleSlidePPTWithProgress(ctx context.Context, r
 		"url", url,
 	)
 
-	return &GeneratePPTResponse{
+	resp := &GeneratePPTResponse{
 		RequestID: req.RequestID,
 		PPTURL:    url,
 		Title:     slideSpec.Title,
-	}, nil
+		Subtitle:  slideSpec.Subtitle,
+		Bullets:   slideSpec.Bullets,
+		Notes:     slideSpec.Notes,
+		Slides:    []SlideSpecData{specData},
+	}
+	return resp, nil
+}
+
+// checkCancelled 请求被取消时返回 CANCELLED 错误，避免继续渲染和保存
+func checkCancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, errors.ErrCodeCancelled, "generation cancelled")
+	}
+	return nil
+}
+
+// errorCode 取 AppError 的错误码，其他错误归为 INTERNAL_ERROR
+func errorCode(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return errors.ErrCodeInternal
+}
+
+type emitFunc func(stage, message string, progress int, data interface{})
+
+// newEmitter 包装进度回调，允许多个 goroutine 并发上报
+func newEmitter(onProgress ProgressCallback) emitFunc {
+	var mu sync.Mutex
+	return func(stage, message string, progress int, data interface{}) {
+		if onProgress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		onProgress(ProgressEvent{
+			Stage:    stage,
+			Message:  message,
+			Progress: progress,
+			Data:     data,
+		})
+	}
 }
@@ -0,0 +1,237 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/internal/service/usage"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// ReviseRequest 按自然语言修改意见调整已生成演示文稿的一页
+type ReviseRequest struct {
+	// Slide 页序号，从 1 开始
+	Slide       int
+	Instruction string
+	// Target 为 text、image 或 both（默认），限定可修改的部分
+	Target string
+	// ClientID 用量记账使用的调用方标识
+	ClientID string
+}
+
+// PPTVersion 每次修改生成一个新版本的 .pptx，旧版本文件保留
+type PPTVersion struct {
+	Version     int    `json:"version"`
+	URL         string `json:"url"`
+	Slide       int    `json:"slide,omitempty"`
+	Instruction string `json:"instruction,omitempty"`
+	Target      string `json:"target,omitempty"`
+	// ImageRegenerated 该版本是否重新生成了配图
+	ImageRegenerated bool           `json:"image_regenerated,omitempty"`
+	Usage            *usage.Summary `json:"usage,omitempty"`
+	CreatedAt        int64          `json:"created_at,omitempty"`
+	// Candidate 改选配图生成的版本所选的候选序号，Slide 为改选的页
+	Candidate *int `json:"candidate,omitempty"`
+}
+
+// versionID 第 version 版 .pptx 在存储中的 ID，第 1 版沿用任务 ID
+func versionID(jobID string, version int) string {
+	if version <= 1 {
+		return jobID
+	}
+	return fmt.Sprintf("%s_v%d", jobID, version)
+}
+
+// nextVersion 返回已有版本列表与下一个版本号，没有修改过的结果以原始文件作为第 1 版
+func (r *GeneratePPTResponse) nextVersion() ([]PPTVersion, int) {
+	versions := r.Versions
+	if len(versions) == 0 {
+		versions = []PPTVersion{{Version: 1, URL: r.PPTURL}}
+	}
+	return versions, versions[len(versions)-1].Version + 1
+}
+
+// fileID 存储文件名前缀，早期保存的结果没有 JobID 时沿用请求 ID
+func (r *GeneratePPTResponse) fileID() string {
+	if r.JobID != "" {
+		return r.JobID
+	}
+	return r.RequestID
+}
+
+// Revise 把该页已有内容与修改意见发给分析模型，只重新生成变化的部分，渲染为新版本的 .pptx。
+// 配图描述变化时才重新生成配图（不使用参考图，原图不落盘），其余页沿用已保存的配图
+func (o *Orchestrator) Revise(ctx context.Context, result *GeneratePPTResponse, req *ReviseRequest) (*GeneratePPTResponse, error) {
+	if req.Slide < 1 || req.Slide > len(result.Slides) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("slide must be between 1 and %d", len(result.Slides)))
+	}
+	if req.Instruction == "" {
+		return nil, errors.New(errors.ErrCodeInvalidReq, "instruction is required")
+	}
+	if !provider.ValidReviseTarget(req.Target) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("unsupported target %q", req.Target))
+	}
+	if req.Target == "" {
+		req.Target = provider.ReviseBoth
+	}
+
+	ctx = httpclient.WithCassetteID(ctx, result.fileID())
+	ctx, calls := provider.WithUsageRecorder(ctx)
+
+	updated, err := o.revise(ctx, result, req)
+
+	status := usage.StatusSucceeded
+	if err != nil {
+		status = usage.StatusFailed
+		if cancelErr := checkCancelled(ctx); cancelErr != nil {
+			updated, err = nil, cancelErr
+			status = usage.StatusCancelled
+		}
+	}
+	var summary *usage.Summary
+	if o.usage != nil {
+		summary = o.usage.Settle(result.RequestID, req.ClientID, status, calls.Calls())
+	}
+	if updated != nil {
+		updated.Versions[len(updated.Versions)-1].Usage = summary
+	}
+	return updated, err
+}
+
+func (o *Orchestrator) revise(ctx context.Context, result *GeneratePPTResponse, req *ReviseRequest) (*GeneratePPTResponse, error) {
+	index := req.Slide - 1
+	current := result.Slides[index]
+	spec := current.spec(result.Style)
+
+	o.logger.Info("starting slide revision",
+		"request_id", result.RequestID,
+		"slide", req.Slide,
+		"target", req.Target,
+	)
+
+	release, err := o.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revised, err := o.analyzer.ReviseSlide(ctx, spec, req.Instruction, req.Target, result.Language, result.Style)
+	release()
+	if err != nil {
+		o.logger.Error("failed to revise slide", "request_id", result.RequestID, "slide", req.Slide, "error", err)
+		return nil, err
+	}
+
+	// 模型可能越界修改，按 target 只取允许修改的部分
+	next := current
+	if req.Target != provider.ReviseImage {
+		next.Title = revised.Title
+		next.Subtitle = revised.Subtitle
+		next.Bullets = revised.Bullets
+		next.Notes = revised.Notes
+	}
+	if req.Target != provider.ReviseText {
+		next.ImagePrompt = revised.ImagePrompt
+	}
+
+	regenerate := next.ImagePrompt != current.ImagePrompt && next.ImagePrompt != ""
+	if req.Target == provider.ReviseImage && !regenerate {
+		return nil, errors.New(errors.ErrCodeInvalidOutput, "model did not change the image prompt")
+	}
+
+	versions, version := result.nextVersion()
+
+	if regenerate {
+		genReq := &GeneratePPTRequest{
+			RequestID: result.RequestID,
+			JobID:     result.fileID(),
+			ClientID:  req.ClientID,
+			Style:     result.Style,
+		}
+		img, err := o.generateImage(ctx, genReq, next.ImagePrompt, nil)
+		if err != nil {
+			o.logger.Error("failed to regenerate slide image", "request_id", result.RequestID, "slide", req.Slide, "error", err)
+			return nil, err
+		}
+		_, file, err := o.storageSvc.SaveImage(ctx, fmt.Sprintf("%s_v%d", slideImageID(result.fileID(), index), version), img.Bytes)
+		if err != nil {
+			o.logger.Error("failed to save slide image", "request_id", result.RequestID, "slide", req.Slide, "error", err)
+			return nil, err
+		}
+		// 新配图取代原有候选
+		next.ImageFile = file
+		next.Candidates = nil
+		next.SelectedCandidate = 0
+	}
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	updated := *result
+	updated.Slides = append([]SlideSpecData(nil), result.Slides...)
+	updated.Slides[index] = next
+	// 顶层内容对应第 1 页（单页内容或封面）
+	if index == 0 {
+		updated.Title = next.Title
+		updated.Subtitle = next.Subtitle
+		updated.Bullets = next.Bullets
+		updated.Notes = next.Notes
+	}
+
+	url, err := o.renderSlides(ctx, &updated, versionID(result.fileID(), version))
+	if err != nil {
+		return nil, err
+	}
+	updated.PPTURL = url
+	updated.Version = version
+	updated.Versions = append(append([]PPTVersion(nil), versions...), PPTVersion{
+		Version:          version,
+		URL:              url,
+		Slide:            req.Slide,
+		Instruction:      req.Instruction,
+		Target:           req.Target,
+		ImageRegenerated: regenerate,
+		CreatedAt:        time.Now().Unix(),
+	})
+
+	o.logger.Info("slide revised",
+		"request_id", result.RequestID,
+		"slide", req.Slide,
+		"version", version,
+		"image_regenerated", regenerate,
+		"url", url,
+	)
+	return &updated, nil
+}
+
+// renderSlides 用各页内容与已保存的配图重新渲染，保存为 id 对应的文件
+func (o *Orchestrator) renderSlides(ctx context.Context, result *GeneratePPTResponse, id string) (string, error) {
+	slides := make([]ppt.Slide, len(result.Slides))
+	for i, data := range result.Slides {
+		slides[i].Spec = data.spec(result.Style)
+		if data.ImageFile == "" {
+			continue
+		}
+		imageBytes, err := o.storageSvc.GetFile(ctx, data.ImageFile)
+		if err != nil {
+			o.logger.Error("failed to load slide image", "request_id", result.RequestID, "file", data.ImageFile, "error", err)
+			return "", err
+		}
+		slides[i].Image = &provider.GeneratedImage{Bytes: imageBytes}
+	}
+
+	pptBytes, err := o.pptSvc.RenderDeck(slides)
+	if err != nil {
+		o.logger.Error("failed to render PPT", "request_id", result.RequestID, "error", err)
+		return "", err
+	}
+	url, err := o.storageSvc.SavePPT(ctx, id, pptBytes)
+	if err != nil {
+		o.logger.Error("failed to save PPT", "request_id", result.RequestID, "error", err)
+		return "", err
+	}
+	return url, nil
+}
@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+	"github.com/ChaseRain/img2ppt/internal/service/stub"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// reviser 以 revise 代替分析模型修改单页，未设置时沿用离线桩实现
+type reviser struct {
+	stub.Analyzer
+	revise func(spec *provider.SlideSpec, instruction, target string) *provider.SlideSpec
+}
+
+func (a *reviser) ReviseSlide(ctx context.Context, spec *provider.SlideSpec, instruction, target, language, style string) (*provider.SlideSpec, error) {
+	if a.revise != nil {
+		return a.revise(spec, instruction, target), nil
+	}
+	return a.Analyzer.ReviseSlide(ctx, spec, instruction, target, language, style)
+}
+
+// countingGenerator 记录配图生成次数的离线桩
+type countingGenerator struct {
+	stub.ImageGenerator
+	calls int
+}
+
+func (g *countingGenerator) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*provider.GeneratedImage, error) {
+	g.calls++
+	return g.ImageGenerator.GenerateSlideImage(ctx, prompt, refImage, style, fidelity)
+}
+
+type reviseFixture struct {
+	o        *Orchestrator
+	analyzer *reviser
+	imageGen *countingGenerator
+	files    *storage.Service
+	result   *GeneratePPTResponse
+}
+
+// newReviseFixture 两页已保存配图并渲染出第 1 版的结果
+func newReviseFixture(t *testing.T) *reviseFixture {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := storage.New(storage.Options{Type: "local", BasePath: t.TempDir(), BaseURL: "/files"}, httpclient.New(httpclient.Options{}), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &reviseFixture{analyzer: &reviser{}, imageGen: &countingGenerator{}, files: files}
+	f.o = New(f.analyzer, f.imageGen, ppt.New(log), files, nil, nil, limiter.New(1, 100), log)
+
+	ctx := context.Background()
+	f.result = &GeneratePPTResponse{
+		RequestID: "req-1",
+		JobID:     "job-1",
+		Language:  "zh",
+		Title:     "季度回顾",
+		Bullets:   []string{"收入增长"},
+		Slides: []SlideSpecData{
+			{Title: "季度回顾", Bullets: []string{"收入增长"}, ImagePrompt: "a bar chart"},
+			{Title: "下季度计划", Bullets: []string{"扩大团队"}, ImagePrompt: "a roadmap"},
+		},
+	}
+	for i, c := range []color.Color{color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}} {
+		if _, f.result.Slides[i].ImageFile, err = files.SaveImage(ctx, slideImageID("job-1", i), solidPNG(t, c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.result.PPTURL, err = f.o.renderSlides(ctx, f.result, versionID("job-1", 1)); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// fileName 下载地址对应的存储文件名
+func fileName(t *testing.T, fileURL string) string {
+	t.Helper()
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path.Base(u.Path)
+}
+
+// download 按地址读取存储中的文件
+func (f *reviseFixture) download(t *testing.T, fileURL string) []byte {
+	t.Helper()
+	data, err := f.files.GetFile(context.Background(), fileName(t, fileURL))
+	if err != nil {
+		t.Fatalf("download %s: %v", fileURL, err)
+	}
+	return data
+}
+
+func TestReviseTextKeepsImage(t *testing.T) {
+	f := newReviseFixture(t)
+
+	updated, err := f.o.Revise(context.Background(), f.result, &ReviseRequest{Slide: 2, Instruction: "更正式一些", Target: provider.ReviseText})
+	if err != nil {
+		t.Fatalf("Revise: %v", err)
+	}
+	if f.imageGen.calls != 0 {
+		t.Errorf("image generator called %d times for a text revision", f.imageGen.calls)
+	}
+	slide := updated.Slides[1]
+	if slide.ImageFile != f.result.Slides[1].ImageFile || slide.ImagePrompt != "a roadmap" || slide.Notes == "" {
+		t.Errorf("revised slide = %+v", slide)
+	}
+	if v := updated.Versions[1]; v.ImageRegenerated || v.Target != provider.ReviseText || v.Slide != 2 {
+		t.Errorf("version = %+v", v)
+	}
+}
+
+func TestReviseImageUnchangedPrompt(t *testing.T) {
+	f := newReviseFixture(t)
+	f.analyzer.revise = func(spec *provider.SlideSpec, instruction, target string) *provider.SlideSpec {
+		return spec
+	}
+
+	_, err := f.o.Revise(context.Background(), f.result, &ReviseRequest{Slide: 1, Instruction: "换一张图", Target: provider.ReviseImage})
+	if !errors.Is(err, errors.ErrCodeInvalidOutput) {
+		t.Fatalf("error = %v, want INVALID_MODEL_OUTPUT", err)
+	}
+	if f.imageGen.calls != 0 {
+		t.Errorf("image generator called %d times", f.imageGen.calls)
+	}
+}
+
+func TestReviseVersions(t *testing.T) {
+	f := newReviseFixture(t)
+	ctx := context.Background()
+	original := f.download(t, f.result.PPTURL)
+
+	v2, err := f.o.Revise(ctx, f.result, &ReviseRequest{Slide: 2, Instruction: "换成时间线", Target: provider.ReviseImage})
+	if err != nil {
+		t.Fatalf("first Revise: %v", err)
+	}
+	v3, err := f.o.Revise(ctx, v2, &ReviseRequest{Slide: 1, Instruction: "更简洁"})
+	if err != nil {
+		t.Fatalf("second Revise: %v", err)
+	}
+
+	if fileName(t, v2.PPTURL) != "job-1_v2.pptx" || fileName(t, v3.PPTURL) != "job-1_v3.pptx" || v3.Version != 3 {
+		t.Errorf("urls = %s, %s", v2.PPTURL, v3.PPTURL)
+	}
+	if len(v3.Versions) != 3 {
+		t.Fatalf("versions = %+v", v3.Versions)
+	}
+	for i, v := range v3.Versions {
+		if v.Version != i+1 || len(f.download(t, v.URL)) == 0 {
+			t.Errorf("version %d = %+v", i+1, v)
+		}
+	}
+	if v3.Versions[0].URL != f.result.PPTURL || !bytes.Equal(f.download(t, f.result.PPTURL), original) {
+		t.Error("version 1 should keep the original file")
+	}
+
+	// 配图按版本另存，之前版本引用的配图仍可读取
+	if f.imageGen.calls != 1 || !v3.Versions[1].ImageRegenerated || v3.Versions[2].ImageRegenerated {
+		t.Errorf("image generator calls = %d, versions = %+v", f.imageGen.calls, v3.Versions)
+	}
+	if v2.Slides[1].ImageFile == f.result.Slides[1].ImageFile || v3.Slides[1].ImageFile != v2.Slides[1].ImageFile {
+		t.Errorf("image files = %s, %s, %s", f.result.Slides[1].ImageFile, v2.Slides[1].ImageFile, v3.Slides[1].ImageFile)
+	}
+	if _, err := f.files.GetFile(ctx, f.result.Slides[1].ImageFile); err != nil {
+		t.Errorf("original image: %v", err)
+	}
+}
+
+func TestReviseSlideOutOfRange(t *testing.T) {
+	f := newReviseFixture(t)
+	for _, slide := range []int{0, 3} {
+		_, err := f.o.Revise(context.Background(), f.result, &ReviseRequest{Slide: slide, Instruction: "更正式一些"})
+		if !errors.Is(err, errors.ErrCodeInvalidReq) {
+			t.Errorf("slide %d error = %v, want INVALID_REQUEST", slide, err)
+		}
+	}
+}
+
+func TestReviseFirstSlideUpdatesTopLevel(t *testing.T) {
+	f := newReviseFixture(t)
+	f.analyzer.revise = func(spec *provider.SlideSpec, instruction, target string) *provider.SlideSpec {
+		revised := *spec
+		revised.Title = spec.Title + "（修订）"
+		revised.Bullets = []string{"收入增长 12%", "成本下降 5%"}
+		return &revised
+	}
+	ctx := context.Background()
+
+	updated, err := f.o.Revise(ctx, f.result, &ReviseRequest{Slide: 1, Instruction: "补充数据", Target: provider.ReviseText})
+	if err != nil {
+		t.Fatalf("Revise: %v", err)
+	}
+	if updated.Title != "季度回顾（修订）" || len(updated.Bullets) != 2 || updated.Slides[0].Title != updated.Title {
+		t.Errorf("top level = %q %v, slide 1 = %+v", updated.Title, updated.Bullets, updated.Slides[0])
+	}
+	if f.result.Title != "季度回顾" || len(f.result.Bullets) != 1 {
+		t.Error("revision should only change the returned result")
+	}
+
+	// 修改其他页不影响顶层内容
+	again, err := f.o.Revise(ctx, updated, &ReviseRequest{Slide: 2, Instruction: "补充数据", Target: provider.ReviseText})
+	if err != nil {
+		t.Fatalf("Revise slide 2: %v", err)
+	}
+	if again.Title != updated.Title || again.Slides[1].Title != "下季度计划（修订）" {
+		t.Errorf("top level = %q, slide 2 = %+v", again.Title, again.Slides[1])
+	}
+}
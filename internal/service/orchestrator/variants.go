@@ -0,0 +1,184 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// MaxVariants 每页最多生成的配图候选数量
+const MaxVariants = 4
+
+// ImageCandidate 配图候选，Error 非空表示该候选生成失败
+type ImageCandidate struct {
+	Index int    `json:"index"`
+	URL   string `json:"url,omitempty"`
+	// File 候选在存储中的文件名，选择候选后据此读回重新渲染
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// CandidateProgressData 单个配图候选的生成结果，Index 为页序号（从 1 开始），Variant 为候选序号（从 0 开始）
+type CandidateProgressData struct {
+	Index     int    `json:"index"`
+	Total     int    `json:"total"`
+	Variant   int    `json:"variant"`
+	Variants  int    `json:"variants"`
+	URL       string `json:"url,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// slideImage 一页配图的生成结果；file 为选用配图在存储中的文件名，variants 为 1 时没有候选
+type slideImage struct {
+	image      *provider.GeneratedImage
+	file       string
+	candidates []ImageCandidate
+	selected   int
+	err        error
+}
+
+// variantCount 请求的候选数量，限制在 [1, MaxVariants]
+func variantCount(req *GeneratePPTRequest) int {
+	switch {
+	case req.Variants < 1:
+		return 1
+	case req.Variants > MaxVariants:
+		return MaxVariants
+	default:
+		return req.Variants
+	}
+}
+
+// variantPrompt 第 2 个及以后的候选在提示词后注明序号，促使模型给出不同构图，同时区分缓存键
+func variantPrompt(prompt string, variant int) string {
+	if variant == 0 {
+		return prompt
+	}
+	return fmt.Sprintf("%s\n(Alternative composition #%d: vary the layout, viewpoint and color accents.)", prompt, variant+1)
+}
+
+// candidateID 候选图片在存储中的 ID
+func candidateID(jobID string, index, variant int) string {
+	return fmt.Sprintf("%s_slide%d_candidate%d", jobID, index+1, variant)
+}
+
+// slideImageID 没有候选时配图在存储中的 ID
+func slideImageID(jobID string, index int) string {
+	return fmt.Sprintf("%s_slide%d", jobID, index+1)
+}
+
+// generateCandidates 为第 index 页并发生成候选配图，每个候选各占一个 limiter 名额。
+// 配图均保存到存储，供修改与改选后重新渲染；候选数量大于 1 时每张候选在生成后立即上报，默认选用第一个成功的候选。
+func (o *Orchestrator) generateCandidates(ctx context.Context, req *GeneratePPTRequest, index, total int, spec *provider.SlideSpec, refImage []byte, emit emitFunc, progress int) slideImage {
+	variants := variantCount(req)
+	if variants == 1 {
+		img, err := o.generateImage(ctx, req, spec.ImagePrompt, refImage)
+		if err != nil {
+			return slideImage{err: err}
+		}
+		// 保存失败不影响本次渲染，只是之后无法在重新渲染时保留该页配图
+		_, file, err := o.storageSvc.SaveImage(ctx, slideImageID(req.JobID, index), img.Bytes)
+		if err != nil {
+			o.logger.Warn("failed to save slide image", "request_id", req.RequestID, "slide", index+1, "error", err)
+		}
+		return slideImage{image: img, file: file}
+	}
+
+	images := make([]*provider.GeneratedImage, variants)
+	candidates := make([]ImageCandidate, variants)
+	errs := make([]error, variants)
+
+	var wg sync.WaitGroup
+	for v := 0; v < variants; v++ {
+		wg.Add(1)
+		go func(v int) {
+			defer wg.Done()
+
+			candidates[v].Index = v
+			data := CandidateProgressData{Index: index + 1, Total: total, Variant: v, Variants: variants}
+
+			img, err := o.generateImage(ctx, req, variantPrompt(spec.ImagePrompt, v), refImage)
+			if err == nil {
+				candidates[v].URL, candidates[v].File, err = o.storageSvc.SaveImage(ctx, candidateID(req.JobID, index, v), img.Bytes)
+			}
+			if err != nil {
+				o.logger.Warn("failed to generate image candidate",
+					"request_id", req.RequestID,
+					"slide", index+1,
+					"variant", v,
+					"error", err,
+				)
+				errs[v] = err
+				candidates[v].Error = err.Error()
+				data.Error = err.Error()
+				data.ErrorCode = errorCode(err)
+				emit("candidate_generated", fmt.Sprintf("第 %d/%d 页第 %d/%d 张候选配图生成失败", index+1, total, v+1, variants), progress, data)
+				return
+			}
+
+			images[v] = img
+			data.URL = candidates[v].URL
+			emit("candidate_generated", fmt.Sprintf("第 %d/%d 页第 %d/%d 张候选配图生成完成", index+1, total, v+1, variants), progress, data)
+		}(v)
+	}
+	wg.Wait()
+
+	for v, img := range images {
+		if img != nil {
+			return slideImage{image: img, file: candidates[v].File, candidates: candidates, selected: v}
+		}
+	}
+	return slideImage{candidates: candidates, err: errs[0]}
+}
+
+// SelectImage 将第 slide 页（从 1 开始）的配图换成第 candidate 个候选并重新渲染，不重新分析图片。
+// 各页其余配图沿用已选中的候选；与修改相同，结果保存为新版本，之前版本的文件与地址保持不变。
+func (o *Orchestrator) SelectImage(ctx context.Context, result *GeneratePPTResponse, slide, candidate int) (*GeneratePPTResponse, error) {
+	if slide < 1 || slide > len(result.Slides) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("slide must be between 1 and %d", len(result.Slides)))
+	}
+	candidates := result.Slides[slide-1].Candidates
+	if len(candidates) == 0 {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("slide %d has no image candidates", slide))
+	}
+	if candidate < 0 || candidate >= len(candidates) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("candidate must be between 0 and %d", len(candidates)-1))
+	}
+	if candidates[candidate].File == "" {
+		return nil, errors.New(errors.ErrCodeInvalidReq, fmt.Sprintf("candidate %d failed to generate", candidate))
+	}
+
+	updated := *result
+	updated.Slides = append([]SlideSpecData(nil), result.Slides...)
+	updated.Slides[slide-1].SelectedCandidate = candidate
+	updated.Slides[slide-1].ImageFile = candidates[candidate].File
+
+	versions, version := result.nextVersion()
+	url, err := o.renderSlides(ctx, &updated, versionID(result.fileID(), version))
+	if err != nil {
+		return nil, err
+	}
+	updated.PPTURL = url
+	updated.Version = version
+	updated.Versions = append(append([]PPTVersion(nil), versions...), PPTVersion{
+		Version:   version,
+		URL:       url,
+		Slide:     slide,
+		Candidate: &candidate,
+		CreatedAt: time.Now().Unix(),
+	})
+
+	o.logger.Info("image candidate selected",
+		"request_id", result.RequestID,
+		"slide", slide,
+		"candidate", candidate,
+		"version", version,
+		"url", url,
+	)
+	return &updated, nil
+}
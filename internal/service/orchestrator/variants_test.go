@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/limiter"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/ppt"
+	"github.com/ChaseRain/img2ppt/internal/service/storage"
+)
+
+func solidPNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 9))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 9; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSelectImageSavesNewVersion(t *testing.T) {
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files, err := storage.New(storage.Options{Type: "local", BasePath: dir, BaseURL: "/files"}, httpclient.New(httpclient.Options{}), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := New(nil, nil, ppt.New(log), files, nil, nil, limiter.New(1, 100), log)
+	ctx := context.Background()
+
+	candidates := make([]ImageCandidate, 2)
+	for v, c := range []color.Color{color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}} {
+		candidates[v].Index = v
+		candidates[v].URL, candidates[v].File, err = files.SaveImage(ctx, candidateID("job-1", 0, v), solidPNG(t, c))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := &GeneratePPTResponse{
+		RequestID: "req-1",
+		JobID:     "job-1",
+		Title:     "季度回顾",
+		Slides: []SlideSpecData{{
+			Title:      "季度回顾",
+			Bullets:    []string{"收入增长"},
+			Candidates: candidates,
+			ImageFile:  candidates[0].File,
+		}},
+	}
+	if result.PPTURL, err = o.renderSlides(ctx, result, versionID("job-1", 1)); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join(dir, "job-1.pptx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := o.SelectImage(ctx, result, 1, 1)
+	if err != nil {
+		t.Fatalf("SelectImage: %v", err)
+	}
+	if updated.Version != 2 || len(updated.Versions) != 2 || updated.PPTURL == result.PPTURL {
+		t.Fatalf("updated = version %d, versions %+v, url %s", updated.Version, updated.Versions, updated.PPTURL)
+	}
+	if v := updated.Versions[0]; v.Version != 1 || v.URL != result.PPTURL {
+		t.Errorf("first version = %+v, want the original url", v)
+	}
+	if v := updated.Versions[1]; v.URL != updated.PPTURL || v.Slide != 1 || v.Candidate == nil || *v.Candidate != 1 {
+		t.Errorf("new version = %+v", v)
+	}
+	if updated.Slides[0].SelectedCandidate != 1 || result.Slides[0].SelectedCandidate != 0 {
+		t.Error("selection should only change the returned result")
+	}
+
+	// 之前版本的文件保持不变
+	if data, err := os.ReadFile(filepath.Join(dir, "job-1.pptx")); err != nil || !bytes.Equal(data, original) {
+		t.Errorf("version 1 file changed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "job-1_v2.pptx")); err != nil {
+		t.Errorf("version 2 file missing: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package ppt
+
+// PresentationML 固定部件（主题、母版、版式等），内容与幻灯片无关
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const (
+	nsA   = `http://schemas.openxmlformats.org/drawingml/2006/main`
+	nsR   = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+	nsP   = `http://schemas.openxmlformats.org/presentationml/2006/main`
+	nsRel = `http://schemas.openxmlformats.org/package/2006/relationships`
+
+	pmlNamespaces = `xmlns:a="` + nsA + `" xmlns:r="` + nsR + `" xmlns:p="` + nsP + `"`
+)
+
+// 关系类型
+const (
+	relTypeOfficeDocument = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	relTypeCoreProps      = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	relTypeExtendedProps  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	relTypeSlideMaster    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster"
+	relTypeSlideLayout    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout"
+	relTypeSlide          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	relTypeTheme          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+	relTypeImage          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	relTypePresProps      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/presProps"
+	relTypeViewProps      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/viewProps"
+	relTypeTableStyles    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/tableStyles"
+	relTypeNotesMaster    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesMaster"
+	relTypeNotesSlide     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+)
+
+// 内容类型
+const (
+	ctRelationships = "application/vnd.openxmlformats-package.relationships+xml"
+	ctPresentation  = "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"
+	ctSlideMaster   = "application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"
+	ctSlideLayout   = "application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"
+	ctSlide         = "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"
+	ctTheme         = "application/vnd.openxmlformats-officedocument.theme+xml"
+	ctPresProps     = "application/vnd.openxmlformats-officedocument.presentationml.presProps+xml"
+	ctViewProps     = "application/vnd.openxmlformats-officedocument.presentationml.viewProps+xml"
+	ctTableStyles   = "application/vnd.openxmlformats-officedocument.presentationml.tableStyles+xml"
+	ctNotesMaster   = "application/vnd.openxmlformats-officedocument.presentationml.notesMaster+xml"
+	ctNotesSlide    = "application/vnd.openxmlformats-officedocument.presentationml.notesSlide+xml"
+	ctCoreProps     = "application/vnd.openxmlformats-package.core-properties+xml"
+	ctExtendedProps = "application/vnd.openxmlformats-officedocument.extended-properties+xml"
+)
+
+// 16:9 幻灯片尺寸（EMU）
+const (
+	slideWidth  = 12192000
+	slideHeight = 6858000
+)
+
+// 空的组合形状属性，每个 spTree 开头都需要
+const spTreeHeader = `<p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+	`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>`
+
+const presPropsXML = xmlHeader + `<p:presentationPr ` + pmlNamespaces + `/>`
+
+const viewPropsXML = xmlHeader + `<p:viewPr ` + pmlNamespaces + `><p:gridSpacing cx="76200" cy="76200"/></p:viewPr>`
+
+const tableStylesXML = xmlHeader + `<a:tblStyleLst xmlns:a="` + nsA + `" def="{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}"/>`
+
+const slideMasterXML = xmlHeader + `<p:sldMaster ` + pmlNamespaces + `>` +
+	`<p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree>` + spTreeHeader +
+	`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Title Placeholder 1"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr>` +
+	`<p:spPr><a:xfrm><a:off x="609600" y="457200"/><a:ext cx="10972800" cy="1143000"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr>` +
+	`<p:txBody><a:bodyPr anchor="b"><a:normAutofit/></a:bodyPr><a:lstStyle/><a:p><a:endParaRPr/></a:p></p:txBody></p:sp>` +
+	`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Text Placeholder 2"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr>` +
+	`<p:spPr><a:xfrm><a:off x="609600" y="1752600"/><a:ext cx="10972800" cy="4648200"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr>` +
+	`<p:txBody><a:bodyPr><a:normAutofit/></a:bodyPr><a:lstStyle/><a:p><a:endParaRPr/></a:p></p:txBody></p:sp>` +
+	`</p:spTree></p:cSld>` +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst>` +
+	`<p:txStyles>` +
+	`<p:titleStyle><a:lvl1pPr algn="l"><a:defRPr sz="3600" b="1"><a:solidFill><a:schemeClr val="tx2"/></a:solidFill><a:latin typeface="+mj-lt"/><a:ea typeface="+mj-ea"/><a:cs typeface="+mj-cs"/></a:defRPr></a:lvl1pPr></p:titleStyle>` +
+	`<p:bodyStyle><a:lvl1pPr marL="342900" indent="-342900"><a:spcBef><a:spcPts val="600"/></a:spcBef><a:buFont typeface="Arial"/><a:buChar char="&#8226;"/><a:defRPr sz="2000"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/><a:ea typeface="+mn-ea"/><a:cs typeface="+mn-cs"/></a:defRPr></a:lvl1pPr></p:bodyStyle>` +
+	`<p:otherStyle><a:defPPr><a:defRPr lang="zh-CN"/></a:defPPr></p:otherStyle>` +
+	`</p:txStyles>` +
+	`</p:sldMaster>`
+
+const slideMasterRelsXML = xmlHeader + `<Relationships xmlns="` + nsRel + `">` +
+	`<Relationship Id="rId1" Type="` + relTypeSlideLayout + `" Target="../slideLayouts/slideLayout1.xml"/>` +
+	`<Relationship Id="rId2" Type="` + relTypeTheme + `" Target="../theme/theme1.xml"/>` +
+	`</Relationships>`
+
+const slideLayoutXML = xmlHeader + `<p:sldLayout ` + pmlNamespaces + ` type="titleOnly" preserve="1">` +
+	`<p:cSld name="Title Only"><p:spTree>` + spTreeHeader +
+	`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Title 1"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr>` +
+	`<p:spPr/><p:txBody><a:bodyPr/><a:lstStyle/><a:p><a:endParaRPr/></a:p></p:txBody></p:sp>` +
+	`</p:spTree></p:cSld>` +
+	`<p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr>` +
+	`</p:sldLayout>`
+
+const slideLayoutRelsXML = xmlHeader + `<Relationships xmlns="` + nsRel + `">` +
+	`<Relationship Id="rId1" Type="` + relTypeSlideMaster + `" Target="../slideMasters/slideMaster1.xml"/>` +
+	`</Relationships>`
+
+const notesMasterXML = xmlHeader + `<p:notesMaster ` + pmlNamespaces + `>` +
+	`<p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree>` + spTreeHeader +
+	`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Slide Image Placeholder 1"/><p:cNvSpPr><a:spLocks noGrp="1" noRot="1" noChangeAspect="1"/></p:cNvSpPr><p:nvPr><p:ph type="sldImg" idx="2"/></p:nvPr></p:nvSpPr>` +
+	`<p:spPr><a:xfrm><a:off x="381000" y="685800"/><a:ext cx="6096000" cy="3429000"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/>` +
+	`<a:ln w="12700"><a:solidFill><a:prstClr val="black"/></a:solidFill></a:ln></p:spPr></p:sp>` +
+	`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Notes Placeholder 2"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="body" sz="quarter" idx="3"/></p:nvPr></p:nvSpPr>` +
+	`<p:spPr><a:xfrm><a:off x="685800" y="4400550"/><a:ext cx="5486400" cy="3600450"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr>` +
+	`<p:txBody><a:bodyPr/><a:lstStyle/><a:p><a:endParaRPr/></a:p></p:txBody></p:sp>` +
+	`</p:spTree></p:cSld>` +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:notesStyle><a:lvl1pPr marL="0" algn="l"><a:defRPr sz="1200"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/><a:ea typeface="+mn-ea"/><a:cs typeface="+mn-cs"/></a:defRPr></a:lvl1pPr></p:notesStyle>` +
+	`</p:notesMaster>`
+
+// 备注母版使用独立的主题部件
+const notesMasterRelsXML = xmlHeader + `<Relationships xmlns="` + nsRel + `">` +
+	`<Relationship Id="rId1" Type="` + relTypeTheme + `" Target="../theme/theme2.xml"/>` +
+	`</Relationships>`
+
+const themeXML = xmlHeader + `<a:theme xmlns:a="` + nsA + `" name="img2ppt">` +
+	`<a:themeElements>` +
+	`<a:clrScheme name="img2ppt">` +
+	`<a:dk1><a:srgbClr val="222222"/></a:dk1>` +
+	`<a:lt1><a:srgbClr val="FFFFFF"/></a:lt1>` +
+	`<a:dk2><a:srgbClr val="1F2A44"/></a:dk2>` +
+	`<a:lt2><a:srgbClr val="EEF1F5"/></a:lt2>` +
+	`<a:accent1><a:srgbClr val="2F5597"/></a:accent1>` +
+	`<a:accent2><a:srgbClr val="ED7D31"/></a:accent2>` +
+	`<a:accent3><a:srgbClr val="A5A5A5"/></a:accent3>` +
+	`<a:accent4><a:srgbClr val="FFC000"/></a:accent4>` +
+	`<a:accent5><a:srgbClr val="5B9BD5"/></a:accent5>` +
+	`<a:accent6><a:srgbClr val="70AD47"/></a:accent6>` +
+	`<a:hlink><a:srgbClr val="0563C1"/></a:hlink>` +
+	`<a:folHlink><a:srgbClr val="954F72"/></a:folHlink>` +
+	`</a:clrScheme>` +
+	`<a:fontScheme name="img2ppt">` +
+	`<a:majorFont><a:latin typeface="Calibri Light"/><a:ea typeface=""/><a:cs typeface=""/><a:font script="Hans" typeface="等线 Light"/></a:majorFont>` +
+	`<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/><a:font script="Hans" typeface="等线"/></a:minorFont>` +
+	`</a:fontScheme>` +
+	`<a:fmtScheme name="img2ppt">` +
+	`<a:fillStyleLst>` +
+	`<a:solidFill><a:schemeClr val="phClr"/></a:solidFill>` +
+	`<a:solidFill><a:schemeClr val="phClr"><a:tint val="50000"/></a:schemeClr></a:solidFill>` +
+	`<a:solidFill><a:schemeClr val="phClr"><a:shade val="80000"/></a:schemeClr></a:solidFill>` +
+	`</a:fillStyleLst>` +
+	`<a:lnStyleLst>` +
+	`<a:ln w="6350"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="12700"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="19050"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`</a:lnStyleLst>` +
+	`<a:effectStyleLst>` +
+	`<a:effectStyle><a:effectLst/></a:effectStyle>` +
+	`<a:effectStyle><a:effectLst/></a:effectStyle>` +
+	`<a:effectStyle><a:effectLst/></a:effectStyle>` +
+	`</a:effectStyleLst>` +
+	`<a:bgFillStyleLst>` +
+	`<a:solidFill><a:schemeClr val="phClr"/></a:solidFill>` +
+	`<a:solidFill><a:schemeClr val="phClr"><a:tint val="95000"/></a:schemeClr></a:solidFill>` +
+	`<a:solidFill><a:schemeClr val="phClr"><a:shade val="90000"/></a:schemeClr></a:solidFill>` +
+	`</a:bgFillStyleLst>` +
+	`</a:fmtScheme>` +
+	`</a:themeElements>` +
+	`<a:objectDefaults/><a:extraClrSchemeLst/>` +
+	`</a:theme>`
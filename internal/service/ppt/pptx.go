@@ -0,0 +1,439 @@
+package ppt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+// 版面布局（EMU）
+const (
+	marginX         = 609600
+	contentWidth    = slideWidth - 2*marginX
+	titleY          = 457200
+	titleHeight     = 1143000
+	subtitleY       = titleY + titleHeight
+	subtitleHeight  = 609600
+	bottomMargin    = 457200
+	columnGap       = 304800
+	halfWidth       = (contentWidth - columnGap) / 2
+	imageColumnX    = marginX + halfWidth + columnGap
+	bodyTopPlain    = subtitleY + 152400
+	bodyTopSubtitle = subtitleY + subtitleHeight + 152400
+)
+
+// 幻灯片关系编号
+const (
+	slideRelLayout = "rId1"
+	slideRelNotes  = "rId2"
+	slideRelImage  = "rId3"
+)
+
+// mediaPart 嵌入的图片资源
+type mediaPart struct {
+	name   string
+	ext    string
+	data   []byte
+	width  int
+	height int
+}
+
+// pptxWriter 将幻灯片组装为 PresentationML 压缩包
+type pptxWriter struct {
+	buf   bytes.Buffer
+	zw    *zip.Writer
+	title string
+	// 已使用的图片扩展名，用于生成 Default 内容类型
+	exts map[string]bool
+}
+
+func buildPresentation(slides []Slide) ([]byte, error) {
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("no slides to render")
+	}
+
+	w := &pptxWriter{exts: make(map[string]bool)}
+	w.zw = zip.NewWriter(&w.buf)
+	if slides[0].Spec != nil {
+		w.title = slides[0].Spec.Title
+	}
+
+	media := make([]*mediaPart, len(slides))
+	for i, sl := range slides {
+		if sl.Image == nil || len(sl.Image.Bytes) == 0 {
+			continue
+		}
+		ext, ok := mediaExtension(sl.Image.Bytes)
+		if !ok {
+			continue
+		}
+		m := &mediaPart{
+			name: fmt.Sprintf("image%d.%s", i+1, ext),
+			ext:  ext,
+			data: sl.Image.Bytes,
+		}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(sl.Image.Bytes)); err == nil {
+			m.width, m.height = cfg.Width, cfg.Height
+		}
+		media[i] = m
+		w.exts[ext] = true
+	}
+
+	if err := w.writeFile("[Content_Types].xml", w.contentTypes(len(slides))); err != nil {
+		return nil, err
+	}
+	if err := w.writeFile("_rels/.rels", rootRelsXML()); err != nil {
+		return nil, err
+	}
+	if err := w.writeFile("docProps/core.xml", w.coreProps()); err != nil {
+		return nil, err
+	}
+	if err := w.writeFile("docProps/app.xml", appProps(len(slides))); err != nil {
+		return nil, err
+	}
+	if err := w.writeFile("ppt/presentation.xml", presentationXML(len(slides))); err != nil {
+		return nil, err
+	}
+	if err := w.writeFile("ppt/_rels/presentation.xml.rels", presentationRelsXML(len(slides))); err != nil {
+		return nil, err
+	}
+
+	static := []struct{ name, body string }{
+		{"ppt/presProps.xml", presPropsXML},
+		{"ppt/viewProps.xml", viewPropsXML},
+		{"ppt/tableStyles.xml", tableStylesXML},
+		{"ppt/theme/theme1.xml", themeXML},
+		{"ppt/theme/theme2.xml", themeXML},
+		{"ppt/slideMasters/slideMaster1.xml", slideMasterXML},
+		{"ppt/slideMasters/_rels/slideMaster1.xml.rels", slideMasterRelsXML},
+		{"ppt/slideLayouts/slideLayout1.xml", slideLayoutXML},
+		{"ppt/slideLayouts/_rels/slideLayout1.xml.rels", slideLayoutRelsXML},
+		{"ppt/notesMasters/notesMaster1.xml", notesMasterXML},
+		{"ppt/notesMasters/_rels/notesMaster1.xml.rels", notesMasterRelsXML},
+	}
+	for _, part := range static {
+		if err := w.writeFile(part.name, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, sl := range slides {
+		n := i + 1
+		if err := w.writeFile(fmt.Sprintf("ppt/slides/slide%d.xml", n), slideXML(sl.Spec, media[i])); err != nil {
+			return nil, err
+		}
+		if err := w.writeFile(fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", n), slideRelsXML(n, media[i])); err != nil {
+			return nil, err
+		}
+		if err := w.writeFile(fmt.Sprintf("ppt/notesSlides/notesSlide%d.xml", n), notesSlideXML(sl.Spec)); err != nil {
+			return nil, err
+		}
+		if err := w.writeFile(fmt.Sprintf("ppt/notesSlides/_rels/notesSlide%d.xml.rels", n), notesSlideRelsXML(n)); err != nil {
+			return nil, err
+		}
+		if media[i] != nil {
+			if err := w.writeBytes("ppt/media/"+media[i].name, media[i].data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := w.zw.Close(); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+func (w *pptxWriter) writeFile(name, content string) error {
+	return w.writeBytes(name, []byte(content))
+}
+
+func (w *pptxWriter) writeBytes(name string, data []byte) error {
+	f, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *pptxWriter) contentTypes(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	fmt.Fprintf(&b, `<Default Extension="rels" ContentType="%s"/>`, ctRelationships)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	for _, ext := range []string{"png", "jpeg", "gif", "webp"} {
+		if w.exts[ext] {
+			fmt.Fprintf(&b, `<Default Extension="%s" ContentType="image/%s"/>`, ext, ext)
+		}
+	}
+
+	override := func(part, contentType string) {
+		fmt.Fprintf(&b, `<Override PartName="%s" ContentType="%s"/>`, part, contentType)
+	}
+	override("/ppt/presentation.xml", ctPresentation)
+	override("/ppt/slideMasters/slideMaster1.xml", ctSlideMaster)
+	override("/ppt/slideLayouts/slideLayout1.xml", ctSlideLayout)
+	override("/ppt/notesMasters/notesMaster1.xml", ctNotesMaster)
+	for i := 1; i <= slideCount; i++ {
+		override(fmt.Sprintf("/ppt/slides/slide%d.xml", i), ctSlide)
+		override(fmt.Sprintf("/ppt/notesSlides/notesSlide%d.xml", i), ctNotesSlide)
+	}
+	override("/ppt/theme/theme1.xml", ctTheme)
+	override("/ppt/theme/theme2.xml", ctTheme)
+	override("/ppt/presProps.xml", ctPresProps)
+	override("/ppt/viewProps.xml", ctViewProps)
+	override("/ppt/tableStyles.xml", ctTableStyles)
+	override("/docProps/core.xml", ctCoreProps)
+	override("/docProps/app.xml", ctExtendedProps)
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (w *pptxWriter) coreProps() string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return xmlHeader + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escapeXML(w.title) + `</dc:title>` +
+		`<dc:creator>img2ppt</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + now + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+func appProps(slideCount int) string {
+	return xmlHeader + `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" ` +
+		`xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">` +
+		`<Application>img2ppt</Application>` +
+		fmt.Sprintf(`<Slides>%d</Slides><Notes>%d</Notes>`, slideCount, slideCount) +
+		`</Properties>`
+}
+
+func rootRelsXML() string {
+	return xmlHeader + `<Relationships xmlns="` + nsRel + `">` +
+		`<Relationship Id="rId1" Type="` + relTypeOfficeDocument + `" Target="ppt/presentation.xml"/>` +
+		`<Relationship Id="rId2" Type="` + relTypeCoreProps + `" Target="docProps/core.xml"/>` +
+		`<Relationship Id="rId3" Type="` + relTypeExtendedProps + `" Target="docProps/app.xml"/>` +
+		`</Relationships>`
+}
+
+// presentation.xml 的关系编号：rId1 为母版，rId2..rId(N+1) 为幻灯片，
+// 其后依次为主题、presProps、viewProps、tableStyles 和备注母版（rId(N+6)）
+func presentationXML(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<p:presentation ` + pmlNamespaces + ` saveSubsetFonts="1">`)
+	b.WriteString(`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>`)
+	fmt.Fprintf(&b, `<p:notesMasterIdLst><p:notesMasterId r:id="rId%d"/></p:notesMasterIdLst>`, slideCount+6)
+	b.WriteString(`<p:sldIdLst>`)
+	for i := 0; i < slideCount; i++ {
+		fmt.Fprintf(&b, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, i+2)
+	}
+	b.WriteString(`</p:sldIdLst>`)
+	fmt.Fprintf(&b, `<p:sldSz cx="%d" cy="%d"/>`, slideWidth, slideHeight)
+	b.WriteString(`<p:notesSz cx="6858000" cy="9144000"/>`)
+	b.WriteString(`</p:presentation>`)
+	return b.String()
+}
+
+func presentationRelsXML(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="` + nsRel + `">`)
+	rel := func(id int, relType, target string) {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="%s" Target="%s"/>`, id, relType, target)
+	}
+	rel(1, relTypeSlideMaster, "slideMasters/slideMaster1.xml")
+	for i := 1; i <= slideCount; i++ {
+		rel(i+1, relTypeSlide, fmt.Sprintf("slides/slide%d.xml", i))
+	}
+	next := slideCount + 2
+	rel(next, relTypeTheme, "theme/theme1.xml")
+	rel(next+1, relTypePresProps, "presProps.xml")
+	rel(next+2, relTypeViewProps, "viewProps.xml")
+	rel(next+3, relTypeTableStyles, "tableStyles.xml")
+	rel(next+4, relTypeNotesMaster, "notesMasters/notesMaster1.xml")
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func slideRelsXML(n int, media *mediaPart) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="` + nsRel + `">`)
+	fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="../slideLayouts/slideLayout1.xml"/>`, slideRelLayout, relTypeSlideLayout)
+	fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="../notesSlides/notesSlide%d.xml"/>`, slideRelNotes, relTypeNotesSlide, n)
+	if media != nil {
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="../media/%s"/>`, slideRelImage, relTypeImage, media.name)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func slideXML(spec *provider.SlideSpec, media *mediaPart) string {
+	if spec == nil {
+		spec = &provider.SlideSpec{}
+	}
+
+	textWidth := contentWidth
+	if media != nil {
+		textWidth = halfWidth
+	}
+	bodyTop := bodyTopPlain
+	if spec.Subtitle != "" {
+		bodyTop = bodyTopSubtitle
+	}
+	bodyHeight := slideHeight - bottomMargin - bodyTop
+
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<p:sld ` + pmlNamespaces + `><p:cSld><p:spTree>`)
+	b.WriteString(spTreeHeader)
+
+	// 标题占位符
+	b.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Title 1"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr>`)
+	b.WriteString(`<p:spPr>` + xfrm(marginX, titleY, contentWidth, titleHeight) + `</p:spPr>`)
+	b.WriteString(`<p:txBody><a:bodyPr><a:normAutofit/></a:bodyPr><a:lstStyle/>`)
+	b.WriteString(paragraph(singleLine(spec.Title), ""))
+	b.WriteString(`</p:txBody></p:sp>`)
+
+	if spec.Subtitle != "" {
+		b.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Subtitle 2"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`)
+		b.WriteString(`<p:spPr>` + xfrm(marginX, subtitleY, contentWidth, subtitleHeight) + `<a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`)
+		b.WriteString(`<p:txBody><a:bodyPr wrap="square" rtlCol="0"><a:normAutofit/></a:bodyPr><a:lstStyle/>`)
+		b.WriteString(paragraph(singleLine(spec.Subtitle), `<a:rPr lang="zh-CN" sz="2000" dirty="0"><a:solidFill><a:schemeClr val="accent1"/></a:solidFill></a:rPr>`))
+		b.WriteString(`</p:txBody></p:sp>`)
+	}
+
+	if len(spec.Bullets) > 0 {
+		b.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="4" name="Bullets 3"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`)
+		b.WriteString(`<p:spPr>` + xfrm(marginX, bodyTop, textWidth, bodyHeight) + `<a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`)
+		b.WriteString(`<p:txBody><a:bodyPr wrap="square" rtlCol="0"><a:normAutofit/></a:bodyPr><a:lstStyle/>`)
+		for _, bullet := range spec.Bullets {
+			b.WriteString(`<a:p><a:pPr marL="342900" indent="-342900"><a:spcBef><a:spcPts val="1200"/></a:spcBef><a:buFont typeface="Arial"/><a:buChar char="&#8226;"/></a:pPr>`)
+			b.WriteString(`<a:r><a:rPr lang="zh-CN" sz="1800" dirty="0"/><a:t>` + escapeXML(singleLine(bullet)) + `</a:t></a:r></a:p>`)
+		}
+		b.WriteString(`</p:txBody></p:sp>`)
+	}
+
+	if media != nil {
+		x, y, cx, cy := fitImage(media, imageColumnX, bodyTop, halfWidth, bodyHeight)
+		b.WriteString(`<p:pic><p:nvPicPr><p:cNvPr id="5" name="Picture 4" descr="` + escapeXML(singleLine(spec.ImagePrompt)) + `"/>`)
+		b.WriteString(`<p:cNvPicPr><a:picLocks noChangeAspect="1"/></p:cNvPicPr><p:nvPr/></p:nvPicPr>`)
+		b.WriteString(`<p:blipFill><a:blip r:embed="` + slideRelImage + `"/><a:stretch><a:fillRect/></a:stretch></p:blipFill>`)
+		b.WriteString(`<p:spPr>` + xfrm(x, y, cx, cy) + `<a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr></p:pic>`)
+	}
+
+	b.WriteString(`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`)
+	return b.String()
+}
+
+// notesSlideXML 生成演讲者备注页，每行备注对应一个段落
+func notesSlideXML(spec *provider.SlideSpec) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<p:notes ` + pmlNamespaces + `><p:cSld><p:spTree>`)
+	b.WriteString(spTreeHeader)
+	b.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Slide Image Placeholder 1"/><p:cNvSpPr><a:spLocks noGrp="1" noRot="1" noChangeAspect="1"/></p:cNvSpPr><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr><p:spPr/></p:sp>`)
+	b.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Notes Placeholder 2"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="body" idx="3"/></p:nvPr></p:nvSpPr><p:spPr/>`)
+	b.WriteString(`<p:txBody><a:bodyPr/><a:lstStyle/>`)
+	var lines []string
+	if spec != nil {
+		for _, line := range strings.Split(strings.ReplaceAll(spec.Notes, "\r\n", "\n"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		b.WriteString(paragraph("", ""))
+	}
+	for _, line := range lines {
+		b.WriteString(paragraph(line, ""))
+	}
+	b.WriteString(`</p:txBody></p:sp>`)
+	b.WriteString(`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:notes>`)
+	return b.String()
+}
+
+// 备注页关系：rId1 为备注母版，rId2 指回所属幻灯片
+func notesSlideRelsXML(n int) string {
+	return xmlHeader + `<Relationships xmlns="` + nsRel + `">` +
+		`<Relationship Id="rId1" Type="` + relTypeNotesMaster + `" Target="../notesMasters/notesMaster1.xml"/>` +
+		fmt.Sprintf(`<Relationship Id="rId2" Type="%s" Target="../slides/slide%d.xml"/>`, relTypeSlide, n) +
+		`</Relationships>`
+}
+
+// fitImage 按原图比例将图片居中放入指定区域
+func fitImage(media *mediaPart, x, y, boxW, boxH int) (int, int, int, int) {
+	if media.width <= 0 || media.height <= 0 {
+		return x, y, boxW, boxH
+	}
+	cx, cy := boxW, boxW*media.height/media.width
+	if cy > boxH {
+		cx, cy = boxH*media.width/media.height, boxH
+	}
+	return x + (boxW-cx)/2, y + (boxH-cy)/2, cx, cy
+}
+
+func xfrm(x, y, cx, cy int) string {
+	return fmt.Sprintf(`<a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm>`, x, y, cx, cy)
+}
+
+// paragraph 生成单个段落，text 为空时输出空段落
+func paragraph(text, rPr string) string {
+	if text == "" {
+		return `<a:p><a:endParaRPr lang="zh-CN" dirty="0"/></a:p>`
+	}
+	if rPr == "" {
+		rPr = `<a:rPr lang="zh-CN" dirty="0"/>`
+	}
+	return `<a:p><a:r>` + rPr + `<a:t>` + escapeXML(text) + `</a:t></a:r></a:p>`
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func mediaExtension(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	if data[0] == 0x89 && data[1] == 0x50 && data[2] == 0x4E && data[3] == 0x47 {
+		return "png", true
+	}
+	if data[0] == 0xFF && data[1] == 0xD8 {
+		return "jpeg", true
+	}
+	if data[0] == 0x47 && data[1] == 0x49 && data[2] == 0x46 {
+		return "gif", true
+	}
+	// WebP 为 RIFF 容器，第 8-12 字节为 WEBP；其他 RIFF 格式（如 WAV、AVI）不是图片
+	if len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		return "webp", true
+	}
+	return "", false
+}
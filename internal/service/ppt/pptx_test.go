@@ -0,0 +1,244 @@
+package ppt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func renderTestDeck(t *testing.T) map[string][]byte {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slides := []Slide{
+		{
+			Spec: &provider.SlideSpec{
+				Title:    "封面 & <标题>",
+				Subtitle: "副标题",
+				Bullets:  []string{"第一点", "第二点"},
+				Notes:    "第一行备注\n\n第二行备注",
+			},
+			Image: &provider.GeneratedImage{Bytes: testPNG(t, 160, 90)},
+		},
+		{
+			Spec: &provider.SlideSpec{Title: "无配图", Bullets: []string{"要点"}},
+		},
+	}
+	data, err := New(log).RenderDeck(slides)
+	if err != nil {
+		t.Fatalf("RenderDeck: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = body
+	}
+	return parts
+}
+
+// relationships 解析 .rels 部件，返回 Id 到 (Type, Target) 的映射
+func relationships(t *testing.T, body []byte) map[string][2]string {
+	t.Helper()
+	var rels struct {
+		Relationship []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		}
+	}
+	if err := xml.Unmarshal(body, &rels); err != nil {
+		t.Fatalf("invalid rels: %v", err)
+	}
+	m := make(map[string][2]string)
+	for _, r := range rels.Relationship {
+		m[r.ID] = [2]string{r.Type, r.Target}
+	}
+	return m
+}
+
+func TestRenderDeckPackage(t *testing.T) {
+	parts := renderTestDeck(t)
+
+	required := []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"docProps/core.xml",
+		"docProps/app.xml",
+		"ppt/presentation.xml",
+		"ppt/_rels/presentation.xml.rels",
+		"ppt/slideMasters/slideMaster1.xml",
+		"ppt/slideLayouts/slideLayout1.xml",
+		"ppt/notesMasters/notesMaster1.xml",
+		"ppt/theme/theme1.xml",
+		"ppt/theme/theme2.xml",
+		"ppt/slides/slide1.xml",
+		"ppt/slides/slide2.xml",
+		"ppt/slides/_rels/slide1.xml.rels",
+		"ppt/slides/_rels/slide2.xml.rels",
+		"ppt/notesSlides/notesSlide1.xml",
+		"ppt/notesSlides/notesSlide2.xml",
+		"ppt/notesSlides/_rels/notesSlide1.xml.rels",
+		"ppt/media/image1.png",
+	}
+	for _, name := range required {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if _, ok := parts["ppt/media/image2.png"]; ok {
+		t.Error("slide without image must not embed media")
+	}
+
+	// 每个 XML 部件都必须是格式正确的 XML
+	for name, body := range parts {
+		if !strings.HasSuffix(name, ".xml") && !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+
+	ct := string(parts["[Content_Types].xml"])
+	for _, want := range []string{
+		`Extension="png" ContentType="image/png"`,
+		`PartName="/ppt/presentation.xml" ContentType="` + ctPresentation + `"`,
+		`PartName="/ppt/slides/slide1.xml" ContentType="` + ctSlide + `"`,
+		`PartName="/ppt/slides/slide2.xml" ContentType="` + ctSlide + `"`,
+		`PartName="/ppt/notesSlides/notesSlide1.xml" ContentType="` + ctNotesSlide + `"`,
+		`PartName="/ppt/notesMasters/notesMaster1.xml" ContentType="` + ctNotesMaster + `"`,
+	} {
+		if !strings.Contains(ct, want) {
+			t.Errorf("[Content_Types].xml missing %s", want)
+		}
+	}
+
+	pres := string(parts["ppt/presentation.xml"])
+	if n := strings.Count(pres, "<p:sldId "); n != 2 {
+		t.Errorf("presentation lists %d slides, want 2", n)
+	}
+	presRels := relationships(t, parts["ppt/_rels/presentation.xml.rels"])
+	slideTargets := 0
+	for _, rel := range presRels {
+		if rel[0] == relTypeSlide {
+			slideTargets++
+			if _, ok := parts["ppt/"+rel[1]]; !ok {
+				t.Errorf("presentation rel points at missing part %s", rel[1])
+			}
+		}
+	}
+	if slideTargets != 2 {
+		t.Errorf("presentation has %d slide relationships, want 2", slideTargets)
+	}
+
+	slide1Rels := relationships(t, parts["ppt/slides/_rels/slide1.xml.rels"])
+	if rel := slide1Rels[slideRelImage]; rel[0] != relTypeImage || rel[1] != "../media/image1.png" {
+		t.Errorf("slide1 image rel = %v", rel)
+	}
+	if rel := slide1Rels[slideRelNotes]; rel[0] != relTypeNotesSlide || rel[1] != "../notesSlides/notesSlide1.xml" {
+		t.Errorf("slide1 notes rel = %v", rel)
+	}
+	if _, ok := relationships(t, parts["ppt/slides/_rels/slide2.xml.rels"])[slideRelImage]; ok {
+		t.Error("slide2 must not have an image relationship")
+	}
+
+	slide1 := string(parts["ppt/slides/slide1.xml"])
+	for _, want := range []string{"封面 &amp; &lt;标题&gt;", "副标题", "第一点", "第二点", `r:embed="` + slideRelImage + `"`} {
+		if !strings.Contains(slide1, want) {
+			t.Errorf("slide1.xml missing %q", want)
+		}
+	}
+
+	notes := string(parts["ppt/notesSlides/notesSlide1.xml"])
+	if strings.Count(notes, "<a:p>") != 2 || !strings.Contains(notes, "第一行备注") || !strings.Contains(notes, "第二行备注") {
+		t.Errorf("notesSlide1.xml should hold two note paragraphs: %s", notes)
+	}
+	notesRels := relationships(t, parts["ppt/notesSlides/_rels/notesSlide1.xml.rels"])
+	if rel := notesRels["rId2"]; rel[0] != relTypeSlide || rel[1] != "../slides/slide1.xml" {
+		t.Errorf("notesSlide1 slide rel = %v", rel)
+	}
+
+	if !bytes.Equal(parts["ppt/media/image1.png"][:8], []byte("\x89PNG\r\n\x1a\n")) {
+		t.Error("media entry is not the embedded png")
+	}
+}
+
+// 备注页正文占位符须与备注母版的 body 占位符 idx 一致，否则 PowerPoint 不继承母版样式
+func TestNotesPlaceholderMatchesMaster(t *testing.T) {
+	parts := renderTestDeck(t)
+	master := string(parts["ppt/notesMasters/notesMaster1.xml"])
+	notes := string(parts["ppt/notesSlides/notesSlide1.xml"])
+	if !strings.Contains(master, `<p:ph type="body" sz="quarter" idx="3"/>`) {
+		t.Fatal("notes master body placeholder changed, update this test")
+	}
+	if !strings.Contains(notes, `<p:ph type="body" idx="3"/>`) {
+		t.Errorf("notes slide body placeholder does not use idx=3: %s", notes)
+	}
+}
+
+func TestMediaExtension(t *testing.T) {
+	riff := func(format string) []byte {
+		return append([]byte("RIFF\x10\x00\x00\x00"+format), make([]byte, 8)...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		ext  string
+		ok   bool
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n"), "png", true},
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0}, "jpeg", true},
+		{"gif", []byte("GIF89a"), "gif", true},
+		{"webp", riff("WEBP"), "webp", true},
+		{"wav", riff("WAVE"), "", false},
+		{"avi", riff("AVI "), "", false},
+		{"short", []byte{0x89}, "", false},
+	}
+	for _, tt := range tests {
+		ext, ok := mediaExtension(tt.data)
+		if ext != tt.ext || ok != tt.ok {
+			t.Errorf("%s: mediaExtension = (%q, %v), want (%q, %v)", tt.name, ext, ok, tt.ext, tt.ok)
+		}
+	}
+}
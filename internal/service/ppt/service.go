@@ -1,11 +1,19 @@
 package ppt
 
 import (
+	"fmt"
+
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
-	"github.com/ChaseRain/img2ppt/internal/service/gemini"
-	"github.com/ChaseRain/img2ppt/internal/service/imagegen"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
 )
 
+// Slide 单页幻灯片的渲染输入，Image 为空时不放置配图
+type Slide struct {
+	Spec  *provider.SlideSpec
+	Image *provider.GeneratedImage
+}
+
 type Service struct {
 	logger *logger.Logger
 }
@@ -16,20 +24,37 @@ func New(log *logger.Logger) *Service {
 	}
 }
 
-// RenderSingleSlide - Mock implementation, just returns the generated image bytes
-func (s *Service) RenderSingleSlide(spec *gemini.SlideSpec, img *imagegen.GeneratedImage) ([]byte, error) {
-	s.logger.Info("mock PPT render",
-		"title", spec.Title,
-		"subtitle", spec.Subtitle,
-		"bullets", len(spec.Bullets),
-		"has_image", img != nil && len(img.Bytes) > 0,
-	)
+// RenderSingleSlide 将 SlideSpec 与配图渲染为单页 .pptx
+func (s *Service) RenderSingleSlide(spec *provider.SlideSpec, img *provider.GeneratedImage) ([]byte, error) {
+	return s.RenderDeck([]Slide{{Spec: spec, Image: img}})
+}
 
-	// Mock: 直接返回生成的图片作为输出，方便测试生图功能
-	if img != nil && len(img.Bytes) > 0 {
-		return img.Bytes, nil
+// RenderDeck 按顺序将多页幻灯片渲染为一个 .pptx
+func (s *Service) RenderDeck(slides []Slide) ([]byte, error) {
+	if len(slides) == 0 {
+		return nil, errors.New(errors.ErrCodePPTRender, "no slides to render")
 	}
+	images := 0
+	for i, slide := range slides {
+		if slide.Spec == nil {
+			return nil, errors.New(errors.ErrCodePPTRender, fmt.Sprintf("slide %d spec is nil", i+1))
+		}
+		if slide.Image != nil && len(slide.Image.Bytes) > 0 {
+			images++
+		}
+	}
+
+	data, err := buildPresentation(slides)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodePPTRender, "failed to build pptx")
+	}
+
+	s.logger.Info("pptx built",
+		"title", slides[0].Spec.Title,
+		"slides", len(slides),
+		"images", images,
+		"size_bytes", len(data),
+	)
 
-	// 如果没有图片，返回一个空的占位
-	return []byte("mock ppt content"), nil
+	return data, nil
 }
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"strings"
+)
+
+// RepairJSON 尽量修复本地模型输出的非严格 JSON：截取首个 { 到最后一个 } 之间的内容，
+// 并去掉字符串外的 // 与 /* */ 注释以及对象和数组末尾多余的逗号。
+func RepairJSON(text string) string {
+	text = TrimJSON(text)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+
+	var out strings.Builder
+	out.Grow(len(text))
+
+	inString := false
+	escaped := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(text) && text[i+1] == '/':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				i = len(text)
+			} else {
+				i += end + 3
+			}
+		case c == ',' && strings.IndexByte("}]", nextSignificant(text, i+1)) >= 0:
+			// 丢弃末尾逗号
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String()
+}
+
+// CloseJSON 补全被截断的 JSON：丢弃末尾不完整的值，并按顺序补齐未闭合的对象和数组；完整的 JSON 原样返回
+func CloseJSON(text string) string {
+	text = TrimJSON(text)
+	if start := strings.Index(text, "{"); start > 0 {
+		text = text[start:]
+	}
+
+	var stack, cutStack []byte
+	cut := 0
+	inString := false
+	escaped := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			continue
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			// 逗号之前的键值或元素是完整的
+			cut, cutStack = i, append(cutStack[:0], stack...)
+			continue
+		default:
+			continue
+		}
+		cut, cutStack = i+1, append(cutStack[:0], stack...)
+	}
+	if len(stack) == 0 && !inString {
+		return text
+	}
+
+	var out strings.Builder
+	out.WriteString(text[:cut])
+	for i := len(cutStack) - 1; i >= 0; i-- {
+		if cutStack[i] == '{' {
+			out.WriteByte('}')
+		} else {
+			out.WriteByte(']')
+		}
+	}
+	return out.String()
+}
+
+// nextSignificant 返回 i 之后第一个非空白字符，没有时返回 0
+func nextSignificant(text string, i int) byte {
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return text[i]
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// maxSanitizedRunes SanitizeImagePrompt 保留的主题描述长度
+const maxSanitizedRunes = 200
+
+var (
+	quotedPattern = regexp.MustCompile(`"[^"]*"|“[^”]*”|「[^」]*」|《[^》]*》`)
+	urlPattern    = regexp.MustCompile(`https?://\S+`)
+	digitPattern  = regexp.MustCompile(`[0-9]+`)
+)
+
+// AnalysisPrompt 单页分析提示词，要求模型输出 SlideSpec JSON
+func AnalysisPrompt(language, style string) string {
+	return fmt.Sprintf(`你是 PPT 设计助手。输入是一张图片。
+分析图片内容，提取关键信息，输出 JSON：
+{
+  "title": "简洁有力的标题",
+  "subtitle": "副标题（可选）",
+  "bullets": ["要点1", "要点2", "要点3"],
+  "notes": "演讲者备注",
+  "image_prompt": "用于生成插图的描述。禁止出现文字。风格为 %s，16:9，适合作为PPT插图。描述应该与图片内容相关但更加抽象艺术化。",
+  "style": "%s"
+}
+语言：%s。
+请确保输出是有效的 JSON 格式。`, style, style, language)
+}
+
+// OutlinePrompt 多页大纲提示词，要求模型输出 {"slides": [SlideSpec...]}
+func OutlinePrompt(language, style string, contentSlides int) string {
+	return fmt.Sprintf(`你是 PPT 设计助手。输入是一张图片。
+分析图片内容，规划一份共 %d 页的演示文稿：第 1 页为封面页，随后 %d 页为内容页，最后 1 页为总结页。输出 JSON：
+{
+  "slides": [
+    {
+      "title": "简洁有力的标题",
+      "subtitle": "副标题（可选）",
+      "bullets": ["要点1", "要点2", "要点3"],
+      "notes": "演讲者备注",
+      "image_prompt": "用于生成插图的描述。禁止出现文字。风格为 %s，16:9，适合作为PPT插图。描述应该与该页内容相关但更加抽象艺术化。",
+      "style": "%s"
+    }
+  ]
+}
+封面页的 bullets 为空数组；内容页每页 3-5 个要点，各页主题不重复；总结页概括核心结论。
+语言：%s。
+请确保输出是有效的 JSON 格式，slides 数组长度为 %d。`, contentSlides+2, contentSlides, style, style, language, contentSlides+2)
+}
+
+// CoverPrompt 由各页标题生成封面页的纯文本提示词
+func CoverPrompt(titles []string, language, style string) string {
+	var list strings.Builder
+	for i, title := range titles {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, title)
+	}
+
+	return fmt.Sprintf(`你是 PPT 设计助手。以下是一份演示文稿各页的标题：
+%s
+为这份演示文稿设计封面页，输出 JSON：
+{
+  "title": "概括全部内容的标题",
+  "subtitle": "副标题（可选）",
+  "bullets": [],
+  "notes": "开场白形式的演讲者备注",
+  "image_prompt": "",
+  "style": "%s"
+}
+语言：%s。
+请确保输出是有效的 JSON 格式。`, list.String(), style, language)
+}
+
+// RevisionHistory 修改幻灯片前的对话历史：原分析提示词，以及作为模型上一轮输出的该页 JSON
+func RevisionHistory(spec *SlideSpec, language, style string) (prompt, reply string) {
+	data, _ := json.Marshal(spec)
+	return AnalysisPrompt(language, style), string(data)
+}
+
+// RevisionPrompt 追加的一轮修改请求，target 限定可修改的字段
+func RevisionPrompt(instruction, target string) string {
+	return fmt.Sprintf(`请按以下修改意见调整你上一轮输出的幻灯片内容：
+%s
+
+%s
+未涉及的字段保持原样，输出修改后的完整 JSON，格式与上一轮相同。`, instruction, revisionScope(target))
+}
+
+// revisionScope 各修改目标允许修改的字段
+func revisionScope(target string) string {
+	switch target {
+	case ReviseText:
+		return "只修改 title、subtitle、bullets、notes，image_prompt 保持原样。"
+	case ReviseImage:
+		return "只修改 image_prompt（配图描述，禁止出现文字），其余字段保持原样。"
+	default:
+		return "可以修改任意字段；只有修改意见涉及配图时才修改 image_prompt。"
+	}
+}
+
+// ImagePrompt 配图提示词，fidelity 不为 none 时附加参考图的使用要求
+func ImagePrompt(prompt, style, fidelity string) string {
+	return fmt.Sprintf(`Generate a high-quality illustration for a PowerPoint slide.
+
+Requirements:
+- Aspect ratio: 16:9
+- Style: %s, professional, clean
+- NO text, letters, words, or numbers in the image
+- Abstract, artistic interpretation suitable for business presentation
+- High contrast, visually striking
+%s
+Description: %s`, style, fidelityInstructions(fidelity), prompt)
+}
+
+// SanitizeImagePrompt 配图被安全策略拦截后重试用的保守提示词：去掉引号内容、链接与数字，
+// 只保留主题，并要求画面为不含人物、标志和文字的抽象构图
+func SanitizeImagePrompt(prompt string) string {
+	prompt = quotedPattern.ReplaceAllString(prompt, " ")
+	prompt = urlPattern.ReplaceAllString(prompt, " ")
+	prompt = digitPattern.ReplaceAllString(prompt, " ")
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(prompt); len(runes) > maxSanitizedRunes {
+		prompt = string(runes[:maxSanitizedRunes])
+	}
+
+	return fmt.Sprintf("An abstract, non-figurative composition of soft shapes, gradients and light loosely inspired by the theme: %s. "+
+		"No people, faces, body parts, logos, brands, weapons, symbols or text.", prompt)
+}
+
+// fidelityInstructions 参考图的使用要求，fidelity 为 none 时为空
+func fidelityInstructions(fidelity string) string {
+	switch fidelity {
+	case FidelityLoose:
+		return `
+Reference image:
+- The attached image is the source picture of this slide
+- Borrow its color palette, mood and main subject
+- Composition may be reinterpreted freely
+`
+	case FidelityStrict:
+		return `
+Reference image:
+- The attached image is the source picture of this slide
+- Closely follow its composition, subject placement and perspective
+- Keep its color palette; do not introduce new dominant colors
+- Redraw it as a clean illustration rather than copying it
+`
+	default:
+		return ""
+	}
+}
+
+// ParseSlideSpec 解析单页 JSON，text 可带 markdown 代码块标记
+func ParseSlideSpec(text string) (*SlideSpec, error) {
+	var spec SlideSpec
+	if err := json.Unmarshal([]byte(TrimJSON(text)), &spec); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse slide spec JSON")
+	}
+	return &spec, nil
+}
+
+// ParseOutline 解析 {"slides": [...]} 形式的大纲，空大纲由调用方按各自的错误码处理
+func ParseOutline(text string) ([]*SlideSpec, error) {
+	var outline struct {
+		Slides []*SlideSpec `json:"slides"`
+	}
+	if err := json.Unmarshal([]byte(TrimJSON(text)), &outline); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to parse outline JSON")
+	}
+	return outline.Slides, nil
+}
+
+// TrimJSON 去掉 markdown 代码块标记与首尾空白
+func TrimJSON(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
+
+// RepairPrompt 要求模型修复不合格的 JSON 输出，problem 为校验发现的问题
+func RepairPrompt(output string, problem error) string {
+	return fmt.Sprintf(`你之前的输出不符合要求：%s
+
+原输出：
+%s
+
+请修正上述问题，只输出修正后的完整 JSON，不要包含任何解释或 markdown 标记。
+要求：title 不能为空且不超过 %d 个字符；bullets 为 %d-%d 个要点；image_prompt 不能为空（封面页除外）。`, problem, output, MaxTitleRunes, MinBullets, MaxBullets)
+}
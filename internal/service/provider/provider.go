@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+)
+
+// SlideSpec 单页内容，由分析模型按 JSON 约定输出
+type SlideSpec struct {
+	Title       string   `json:"title"`
+	Subtitle    string   `json:"subtitle"`
+	Bullets     []string `json:"bullets"`
+	Notes       string   `json:"notes"`
+	ImagePrompt string   `json:"image_prompt"`
+	Style       string   `json:"style"`
+}
+
+type GeneratedImage struct {
+	Bytes []byte
+}
+
+// 配图对参考图的还原程度
+const (
+	// FidelityNone 只根据提示词生成，不发送参考图
+	FidelityNone = "none"
+	// FidelityLoose 参考原图的色调与主体，允许自由构图
+	FidelityLoose = "loose"
+	// FidelityStrict 尽量保持原图的构图、主体位置与配色
+	FidelityStrict = "strict"
+)
+
+// ValidFidelity 判断 image_fidelity 取值是否合法，空值视为 none
+func ValidFidelity(fidelity string) bool {
+	switch fidelity {
+	case "", FidelityNone, FidelityLoose, FidelityStrict:
+		return true
+	}
+	return false
+}
+
+// 按修改意见调整幻灯片时可修改的部分
+const (
+	// ReviseText 只修改文字，保留配图
+	ReviseText = "text"
+	// ReviseImage 只修改配图描述并重新生成配图
+	ReviseImage = "image"
+	// ReviseBoth 文字与配图均可修改，配图描述变化时才重新生成配图
+	ReviseBoth = "both"
+)
+
+// ValidReviseTarget 判断 target 取值是否合法，空值视为 both
+func ValidReviseTarget(target string) bool {
+	switch target {
+	case "", ReviseText, ReviseImage, ReviseBoth:
+		return true
+	}
+	return false
+}
+
+// Analyzer 图片分析：由图片生成单页内容、多页大纲，或由各页标题生成封面
+type Analyzer interface {
+	// Model 返回所用模型名，参与结果缓存的键
+	Model() string
+	AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*SlideSpec, error)
+	// AnalyzeImageOutline 规划封面页 + contentSlides 页内容页 + 总结页
+	AnalyzeImageOutline(ctx context.Context, imageBytes []byte, language, style string, contentSlides int) ([]*SlideSpec, error)
+	GenerateCover(ctx context.Context, titles []string, language, style string) (*SlideSpec, error)
+	// ReviseSlide 把 spec 作为模型上一轮的输出，追加一轮对话按 instruction 修改该页，target 限定可修改的部分
+	ReviseSlide(ctx context.Context, spec *SlideSpec, instruction, target, language, style string) (*SlideSpec, error)
+}
+
+// ImageGenerator 配图生成，fidelity 控制对 refImage 的还原程度
+type ImageGenerator interface {
+	Model() string
+	GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*GeneratedImage, error)
+}
+
+// Settings 创建供应商实现所需的配置
+type Settings struct {
+	APIKey     string
+	Model      string
+	BaseURL    string
+	HTTPClient *httpclient.Client
+	Logger     *logger.Logger
+
+	// ImageSize 配图尺寸，如 1792x1024，仅部分配图供应商使用
+	ImageSize string
+}
+
+type (
+	AnalyzerFactory       func(settings Settings) (Analyzer, error)
+	ImageGeneratorFactory func(settings Settings) (ImageGenerator, error)
+)
+
+// Registry 按名称登记各供应商实现，启动时根据配置选择
+type Registry struct {
+	mu         sync.RWMutex
+	analyzers  map[string]AnalyzerFactory
+	generators map[string]ImageGeneratorFactory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		analyzers:  make(map[string]AnalyzerFactory),
+		generators: make(map[string]ImageGeneratorFactory),
+	}
+}
+
+func (r *Registry) RegisterAnalyzer(name string, factory AnalyzerFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.analyzers[name] = factory
+}
+
+func (r *Registry) RegisterImageGenerator(name string, factory ImageGeneratorFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generators[name] = factory
+}
+
+// Analyzer 创建指定供应商的分析实现，未登记的名称返回错误
+func (r *Registry) Analyzer(name string, settings Settings) (Analyzer, error) {
+	r.mu.RLock()
+	factory, ok := r.analyzers[name]
+	available := names(r.analyzers)
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown analyzer provider %q (available: %s)", name, available)
+	}
+	return factory(settings)
+}
+
+// ImageGenerator 创建指定供应商的配图实现，未登记的名称返回错误
+func (r *Registry) ImageGenerator(name string, settings Settings) (ImageGenerator, error) {
+	r.mu.RLock()
+	factory, ok := r.generators[name]
+	available := names(r.generators)
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown image generator provider %q (available: %s)", name, available)
+	}
+	return factory(settings)
+}
+
+// names 列出已登记的名称，调用方需持有 r.mu
+func names[F any](factories map[string]F) string {
+	list := make([]string, 0, len(factories))
+	for name := range factories {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// MaxRepairAttempts 输出不合格时请求模型修复的最多次数
+const MaxRepairAttempts = 2
+
+// SendFunc 请求一次模型输出：repair 为空时发送原请求，否则以 repair 为提示词发送单轮纯文本请求。
+// 输出因 token 上限被截断时返回已生成的部分与 MAX_TOKENS 错误
+type SendFunc func(ctx context.Context, repair string) (string, error)
+
+// Validated 调用 send 并由 accept 解析校验；不合格时把原输出与问题发回模型修复，
+// 最多 MaxRepairAttempts 次，仍不合格返回 INVALID_MODEL_OUTPUT，最后一次仍被截断时返回 MAX_TOKENS 错误
+func Validated(ctx context.Context, log *logger.Logger, send SendFunc, accept func(text string) error) error {
+	text, truncated, err := receive(ctx, send, "")
+	if err != nil {
+		return err
+	}
+
+	problem := acceptOutput(text, truncated, accept)
+	for attempt := 1; problem != nil && attempt <= MaxRepairAttempts; attempt++ {
+		log.Warn("invalid model output, requesting repair", "attempt", attempt, "truncated", truncated != nil, "error", problem)
+
+		if text, truncated, err = receive(ctx, send, RepairPrompt(text, problem)); err != nil {
+			return err
+		}
+		problem = acceptOutput(text, truncated, accept)
+	}
+
+	if problem != nil {
+		log.Error("model output invalid after repair", "text", text, "truncated", truncated != nil, "error", problem)
+		if truncated != nil {
+			return truncated
+		}
+		return errors.Wrap(problem, errors.ErrCodeInvalidOutput, "model output failed validation after repair")
+	}
+	return nil
+}
+
+// receive 调用 send，把 MAX_TOKENS 错误作为截断标记与其他错误分开返回
+func receive(ctx context.Context, send SendFunc, repair string) (string, error, error) {
+	text, err := send(ctx, repair)
+	if err != nil && errors.Is(err, errors.ErrCodeMaxTokens) {
+		return text, err, nil
+	}
+	return text, nil, err
+}
+
+// acceptOutput 校验模型输出；被截断的输出先补全 JSON 再校验，仍不合格时在问题中注明截断，让重新生成的输出更精简
+func acceptOutput(text string, truncated error, accept func(text string) error) error {
+	if truncated == nil {
+		return accept(text)
+	}
+	if err := accept(CloseJSON(text)); err != nil {
+		return fmt.Errorf("output was truncated at the token limit, keep it shorter: %w", err)
+	}
+	return nil
+}
+
+// AcceptSlideSpec 返回供 Validated 使用的单页校验，合格时写入 spec
+func AcceptSlideSpec(rules SpecRules, spec **SlideSpec) func(text string) error {
+	return func(text string) error {
+		parsed, err := ParseSlideSpec(text)
+		if err != nil {
+			return err
+		}
+		if err := ValidateSlideSpec(parsed, rules); err != nil {
+			return err
+		}
+		*spec = parsed
+		return nil
+	}
+}
+
+// AcceptOutline 返回供 Validated 使用的大纲校验，合格时写入 slides。
+// 多出的内容页直接裁掉，页数不足则要求模型重新生成
+func AcceptOutline(expected int, log *logger.Logger, slides *[]*SlideSpec) func(text string) error {
+	return func(text string) error {
+		parsed, err := ParseOutline(text)
+		if err != nil {
+			return err
+		}
+		if len(parsed) > expected {
+			log.Warn("outline has extra slides, trimming", "expected", expected, "actual", len(parsed))
+			parsed = TrimOutline(parsed, expected)
+		}
+		if err := ValidateOutline(parsed, expected); err != nil {
+			return err
+		}
+		*slides = parsed
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+const validSpec = `{"title":"季度回顾","bullets":["收入增长","成本下降"],"notes":"讲稿","image_prompt":"a bar chart"}`
+
+func testLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+// reply 一次模型输出，err 非空时与 text 一并返回
+type reply struct {
+	text string
+	err  error
+}
+
+// scripted 依次返回 replies，并记录每次调用收到的修复提示词
+func scripted(t *testing.T, replies ...reply) (SendFunc, *[]string) {
+	var repairs []string
+	send := func(ctx context.Context, repair string) (string, error) {
+		if len(repairs) >= len(replies) {
+			t.Fatalf("unexpected request #%d", len(repairs)+1)
+		}
+		r := replies[len(repairs)]
+		repairs = append(repairs, repair)
+		return r.text, r.err
+	}
+	return send, &repairs
+}
+
+func outline(slides int) string {
+	items := []string{`{"title":"封面","image_prompt":"cover"}`}
+	for i := 1; i < slides; i++ {
+		items = append(items, `{"title":"要点","bullets":["a"],"image_prompt":"p"}`)
+	}
+	return `{"slides":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestValidatedRepairs(t *testing.T) {
+	send, repairs := scripted(t, reply{text: `{"title":"季度回顾","bullets":[]}`}, reply{text: validSpec})
+
+	var spec *SlideSpec
+	if err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec)); err != nil {
+		t.Fatalf("Validated: %v", err)
+	}
+	if spec == nil || len(spec.Bullets) != 2 {
+		t.Fatalf("spec = %+v, want the repaired output", spec)
+	}
+	if len(*repairs) != 2 || (*repairs)[0] != "" {
+		t.Fatalf("repairs = %q", *repairs)
+	}
+	// 修复请求带上原输出与校验发现的问题
+	if repair := (*repairs)[1]; !strings.Contains(repair, `"bullets":[]`) || !strings.Contains(repair, "fewer than 1 bullets") || !strings.Contains(repair, "image_prompt is empty") {
+		t.Errorf("repair prompt = %s", repair)
+	}
+}
+
+func TestValidatedInvalidAfterRepair(t *testing.T) {
+	invalid := reply{text: `{"title":"","bullets":["a"],"image_prompt":"x"}`}
+	send, repairs := scripted(t, invalid, invalid, reply{text: "not json"})
+
+	var spec *SlideSpec
+	err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec))
+	if !errors.Is(err, errors.ErrCodeInvalidOutput) {
+		t.Fatalf("error = %v, want INVALID_MODEL_OUTPUT", err)
+	}
+	if len(*repairs) != 1+MaxRepairAttempts || spec != nil {
+		t.Errorf("requests = %d, spec = %+v", len(*repairs), spec)
+	}
+}
+
+func TestValidatedStopsOnSendError(t *testing.T) {
+	send, repairs := scripted(t, reply{text: "not json"}, reply{err: errors.New(errors.ErrCodeOpenAIAPI, "boom")})
+
+	var spec *SlideSpec
+	err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec))
+	if !errors.Is(err, errors.ErrCodeOpenAIAPI) || len(*repairs) != 2 {
+		t.Errorf("error = %v after %d requests, want the send error", err, len(*repairs))
+	}
+}
+
+func TestValidatedTruncated(t *testing.T) {
+	truncated := errors.New(errors.ErrCodeMaxTokens, "output truncated")
+
+	t.Run("closed", func(t *testing.T) {
+		// 截断在完整的要点之后，补全 JSON 即合格，不再请求模型
+		send, repairs := scripted(t, reply{text: `{"title":"季度回顾","image_prompt":"chart","bullets":["收入增长","成本`, err: truncated})
+		var spec *SlideSpec
+		if err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec)); err != nil {
+			t.Fatalf("Validated: %v", err)
+		}
+		if len(spec.Bullets) != 1 || len(*repairs) != 1 {
+			t.Errorf("spec = %+v after %d requests", spec, len(*repairs))
+		}
+	})
+
+	t.Run("repaired", func(t *testing.T) {
+		send, repairs := scripted(t, reply{text: `{"title":"季度`, err: truncated}, reply{text: validSpec})
+		var spec *SlideSpec
+		if err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec)); err != nil {
+			t.Fatalf("Validated: %v", err)
+		}
+		if len(*repairs) != 2 || !strings.Contains((*repairs)[1], "truncated at the token limit") {
+			t.Errorf("repairs = %q, want the truncation noted", *repairs)
+		}
+	})
+
+	t.Run("still truncated", func(t *testing.T) {
+		cut := reply{text: `{"title":"季度`, err: truncated}
+		send, repairs := scripted(t, cut, cut, cut)
+		var spec *SlideSpec
+		err := Validated(context.Background(), testLogger(t), send, AcceptSlideSpec(ContentRules, &spec))
+		if !errors.Is(err, errors.ErrCodeMaxTokens) || len(*repairs) != 1+MaxRepairAttempts {
+			t.Errorf("error = %v after %d requests, want MAX_TOKENS", err, len(*repairs))
+		}
+	})
+}
+
+func TestAcceptOutline(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		slides  int
+		problem string
+	}{
+		{"exact", outline(3), 3, ""},
+		{"extra slides trimmed", outline(5), 3, ""},
+		{"too few", outline(2), 0, "slides has 2 items, expected 3"},
+		{"empty", `{"slides":[]}`, 0, "slides is empty"},
+		{"invalid slide", `{"slides":[{"title":"封面"},{"title":"要点","bullets":["a"]},{"title":"总结","bullets":["b"],"image_prompt":"s"}]}`, 0, "slide 2: image_prompt is empty"},
+		{"not json", "not json", 0, "failed to parse outline JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slides []*SlideSpec
+			err := AcceptOutline(3, testLogger(t), &slides)(tt.text)
+			if tt.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.problem) || slides != nil {
+					t.Errorf("error = %v, slides = %d, want %q", err, len(slides), tt.problem)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("accept: %v", err)
+			}
+			if len(slides) != tt.slides || slides[0].Title != "封面" {
+				t.Errorf("slides = %+v", slides)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// Usage 一次模型调用的用量，由各实现在拿到上游响应后通过 RecordUsage 上报
+type Usage struct {
+	Model string `json:"model"`
+	// KeyID 所用 API Key 的指纹，见 KeyID
+	KeyID           string `json:"key_id,omitempty"`
+	PromptTokens    int    `json:"prompt_tokens"`
+	CandidateTokens int    `json:"candidate_tokens"`
+	TotalTokens     int    `json:"total_tokens"`
+	Images          int    `json:"images"`
+}
+
+type usageKey struct{}
+
+// UsageRecorder 收集一次生成过程中的全部模型调用，可被并发的配图任务共用
+type UsageRecorder struct {
+	mu    sync.Mutex
+	calls []Usage
+}
+
+// WithUsageRecorder 在 ctx 上挂载用量收集器
+func WithUsageRecorder(ctx context.Context) (context.Context, *UsageRecorder) {
+	recorder := &UsageRecorder{}
+	return context.WithValue(ctx, usageKey{}, recorder), recorder
+}
+
+// RecordUsage 上报一次调用的用量，ctx 上没有收集器时忽略
+func RecordUsage(ctx context.Context, usage Usage) {
+	recorder, ok := ctx.Value(usageKey{}).(*UsageRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.calls = append(recorder.calls, usage)
+}
+
+// Calls 返回已收集的调用用量
+func (r *UsageRecorder) Calls() []Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Usage(nil), r.calls...)
+}
+
+// KeyID API Key 的指纹（SHA-256 前 12 位十六进制），用于按 Key 汇总用量而不暴露 Key 本身
+func KeyID(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SlideSpec 校验规则
+const (
+	MaxTitleRunes = 80
+	MinBullets    = 1
+	MaxBullets    = 7
+)
+
+// SpecRules 不同页面的必填项：封面页没有要点，由标题生成的封面也没有配图描述
+type SpecRules struct {
+	RequireBullets     bool
+	RequireImagePrompt bool
+}
+
+var (
+	// ContentRules 内容页与单页分析结果
+	ContentRules = SpecRules{RequireBullets: true, RequireImagePrompt: true}
+	// OutlineCoverRules 大纲中的封面页
+	OutlineCoverRules = SpecRules{RequireImagePrompt: true}
+	// CoverRules 由各页标题生成的封面页
+	CoverRules = SpecRules{}
+)
+
+// RevisionRules 修改后的页面沿用原页面的必填项：原来有要点或配图描述的，修改后也不能为空
+func RevisionRules(spec *SlideSpec) SpecRules {
+	return SpecRules{
+		RequireBullets:     len(spec.Bullets) > 0,
+		RequireImagePrompt: strings.TrimSpace(spec.ImagePrompt) != "",
+	}
+}
+
+// ValidateSlideSpec 校验模型输出，返回的错误列出全部问题，可直接用于修复提示词
+func ValidateSlideSpec(spec *SlideSpec, rules SpecRules) error {
+	if spec == nil {
+		return errors.New("slide is null")
+	}
+
+	var problems []string
+	title := strings.TrimSpace(spec.Title)
+	switch {
+	case title == "":
+		problems = append(problems, "title is empty")
+	case utf8.RuneCountInString(title) > MaxTitleRunes:
+		problems = append(problems, fmt.Sprintf("title is longer than %d characters", MaxTitleRunes))
+	}
+
+	bullets := 0
+	for _, bullet := range spec.Bullets {
+		if strings.TrimSpace(bullet) != "" {
+			bullets++
+		}
+	}
+	if bullets != len(spec.Bullets) {
+		problems = append(problems, "bullets contain empty items")
+	}
+	if len(spec.Bullets) > MaxBullets {
+		problems = append(problems, fmt.Sprintf("more than %d bullets", MaxBullets))
+	}
+	if rules.RequireBullets && bullets < MinBullets {
+		problems = append(problems, fmt.Sprintf("fewer than %d bullets", MinBullets))
+	}
+	if rules.RequireImagePrompt && strings.TrimSpace(spec.ImagePrompt) == "" {
+		problems = append(problems, "image_prompt is empty")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateOutline 校验大纲：共 expected 页，第一页按封面页规则，其余按内容页规则
+func ValidateOutline(slides []*SlideSpec, expected int) error {
+	if len(slides) == 0 {
+		return errors.New("slides is empty")
+	}
+
+	var problems []string
+	if len(slides) != expected {
+		problems = append(problems, fmt.Sprintf("slides has %d items, expected %d", len(slides), expected))
+	}
+	for i, slide := range slides {
+		rules := ContentRules
+		if i == 0 {
+			rules = OutlineCoverRules
+		}
+		if err := ValidateSlideSpec(slide, rules); err != nil {
+			problems = append(problems, fmt.Sprintf("slide %d: %v", i+1, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// TrimOutline 页数多于 expected 时去掉多余的内容页，保留封面页与最后的总结页；页数不多于 expected 时原样返回，
+// 页数不足由 ValidateOutline 报告并要求模型重新生成
+func TrimOutline(slides []*SlideSpec, expected int) []*SlideSpec {
+	if expected < 2 || len(slides) <= expected {
+		return slides
+	}
+	trimmed := append([]*SlideSpec(nil), slides[:expected-1]...)
+	return append(trimmed, slides[len(slides)-1])
+}
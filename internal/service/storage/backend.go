@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+)
+
+// ObjectInfo 与对象一同保存的元数据，Checksum 为内容的 SHA-256（十六进制）
+type ObjectInfo struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Backend 存储后端，name 为不含路径的文件名；对象不存在时返回 NOT_FOUND
+type Backend interface {
+	// Put 保存对象并持久化 info 中的元数据
+	Put(ctx context.Context, info ObjectInfo, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	Delete(ctx context.Context, name string) error
+	// List 返回文件名以 prefix 开头的对象，按文件名排序
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// URL 返回有效期为 ttl 的下载地址
+	URL(name string, ttl time.Duration, now time.Time) (string, error)
+}
+
+// newBackend 按类型创建存储后端：local、s3 或 gcs，未知类型返回错误；client 用于访问对象存储
+func newBackend(opts Options, signer urlSigner, client *httpclient.Client) (Backend, error) {
+	switch opts.Type {
+	case "", "local":
+		return newLocalBackend(opts.BasePath, signer)
+	case "s3":
+		return newS3Client(opts.S3, client)
+	case "gcs":
+		return newGCSClient(opts.GCS, client)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", opts.Type)
+	}
+}
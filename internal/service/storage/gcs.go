@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// GCS 返回的文件地址类型
+const (
+	GCSURLPublic = "public"
+	GCSURLSigned = "signed"
+)
+
+const defaultGCSEndpoint = "https://storage.googleapis.com"
+
+// GCSOptions Google Cloud Storage 配置。Endpoint 为空时使用 storage.googleapis.com，
+// 指向 fake-gcs-server 等模拟服务时可不提供 CredentialsFile，以匿名方式访问
+type GCSOptions struct {
+	Bucket   string
+	Endpoint string
+	// CredentialsFile 服务账号 JSON 密钥文件路径
+	CredentialsFile string
+	// Prefix 对象名前缀，如 img2ppt/
+	Prefix string
+	// URLMode 为 signed（V4 签名的临时地址，需要服务账号）或 public（公开对象地址）；
+	// 为空时提供了服务账号则签名，否则返回公开地址
+	URLMode string
+}
+
+// gcsClient 基于 JSON API 的最小 GCS 客户端，只实现存储所需的对象读写与签名地址
+type gcsClient struct {
+	opts     GCSOptions
+	endpoint *url.URL
+	account  *serviceAccount
+	tokens   *tokenSource
+	http     *httpclient.Client
+}
+
+func newGCSClient(opts GCSOptions, client *httpclient.Client) (*gcsClient, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("gcs bucket is required")
+	}
+	switch opts.URLMode {
+	case "", GCSURLPublic, GCSURLSigned:
+	default:
+		return nil, fmt.Errorf("unknown gcs url mode %q", opts.URLMode)
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = defaultGCSEndpoint
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid gcs endpoint %q", endpoint)
+	}
+
+	c := &gcsClient{opts: opts, endpoint: u, http: client}
+	if opts.CredentialsFile != "" {
+		account, err := loadServiceAccount(opts.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		c.account = account
+		c.tokens = &tokenSource{account: account, http: client}
+	}
+	switch {
+	case opts.URLMode == GCSURLSigned && c.account == nil:
+		return nil, fmt.Errorf("gcs signed urls require a credentials file")
+	case opts.URLMode == "" && c.account != nil:
+		c.opts.URLMode = GCSURLSigned
+	case opts.URLMode == "":
+		c.opts.URLMode = GCSURLPublic
+	}
+	return c, nil
+}
+
+// GCS 自定义元数据的键，与对象一同保存
+const (
+	gcsMetaChecksum  = "sha256"
+	gcsMetaCreatedAt = "created_at"
+)
+
+// gcsObject JSON API 的对象资源中用到的字段
+type gcsObject struct {
+	Name        string            `json:"name"`
+	ContentType string            `json:"contentType,omitempty"`
+	Size        string            `json:"size,omitempty"`
+	TimeCreated string            `json:"timeCreated,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Put 以 multipart 方式上传，对象资源（含校验和与创建时间）与内容在同一请求中提交
+func (c *gcsClient) Put(ctx context.Context, info ObjectInfo, data []byte) error {
+	resource, err := json.Marshal(gcsObject{
+		Name:        c.opts.Prefix + info.Name,
+		ContentType: info.ContentType,
+		Metadata: map[string]string{
+			gcsMetaChecksum:  info.Checksum,
+			gcsMetaCreatedAt: info.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to encode gcs object resource")
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	part.Write(resource)
+	part, _ = w.CreatePart(textproto.MIMEHeader{"Content-Type": {info.ContentType}})
+	part.Write(data)
+	w.Close()
+
+	u := *c.endpoint
+	u.Path += "/upload/storage/v1/b/" + c.opts.Bucket + "/o"
+	u.RawQuery = "uploadType=multipart"
+
+	header := http.Header{}
+	header.Set("Content-Type", "multipart/related; boundary="+w.Boundary())
+	resp, err := c.do(ctx, http.MethodPost, u.String(), body.Bytes(), header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *gcsClient) Get(ctx context.Context, name string) ([]byte, error) {
+	u := c.objectURL(name)
+	u.RawQuery = "alt=media"
+
+	resp, err := c.do(ctx, http.MethodGet, u.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read gcs object")
+	}
+	return data, nil
+}
+
+func (c *gcsClient) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	u := c.objectURL(name)
+	resp, err := c.do(ctx, http.MethodGet, u.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var object gcsObject
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to decode gcs object")
+	}
+	info := c.objectInfo(object)
+	return &info, nil
+}
+
+func (c *gcsClient) Delete(ctx context.Context, name string) error {
+	u := c.objectURL(name)
+	resp, err := c.do(ctx, http.MethodDelete, u.String(), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List 按页列举对象，列举结果包含自定义元数据
+func (c *gcsClient) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var infos []ObjectInfo
+	token := ""
+	for {
+		u := *c.endpoint
+		u.Path += "/storage/v1/b/" + c.opts.Bucket + "/o"
+		query := url.Values{"prefix": {c.opts.Prefix + prefix}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		u.RawQuery = query.Encode()
+
+		resp, err := c.do(ctx, http.MethodGet, u.String(), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items         []gcsObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to decode gcs object list")
+		}
+
+		for _, object := range page.Items {
+			info := c.objectInfo(object)
+			if validName(info.Name) {
+				infos = append(infos, info)
+			}
+		}
+		if page.NextPageToken == "" {
+			return infos, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// objectInfo 由对象资源得到元数据，没有自定义元数据的对象创建时间取 timeCreated
+func (c *gcsClient) objectInfo(object gcsObject) ObjectInfo {
+	info := ObjectInfo{
+		Name:        strings.TrimPrefix(object.Name, c.opts.Prefix),
+		ContentType: object.ContentType,
+		Checksum:    object.Metadata[gcsMetaChecksum],
+	}
+	info.Size, _ = strconv.ParseInt(object.Size, 10, 64)
+	if t, err := time.Parse(time.RFC3339, object.Metadata[gcsMetaCreatedAt]); err == nil {
+		info.CreatedAt = t
+	} else if t, err := time.Parse(time.RFC3339, object.TimeCreated); err == nil {
+		info.CreatedAt = t.UTC()
+	}
+	return info
+}
+
+// objectURL JSON API 中对象资源的地址，对象名中的 / 需编码
+func (c *gcsClient) objectURL(name string) url.URL {
+	u := *c.endpoint
+	object := c.opts.Prefix + name
+	u.Path += "/storage/v1/b/" + c.opts.Bucket + "/o/" + object
+	u.RawPath = c.endpoint.EscapedPath() + "/storage/v1/b/" + url.PathEscape(c.opts.Bucket) + "/o/" + url.PathEscape(object)
+	return u
+}
+
+// URL 按配置返回对象的公开地址或有效期为 ttl 的签名地址
+func (c *gcsClient) URL(name string, ttl time.Duration, now time.Time) (string, error) {
+	if c.opts.URLMode == GCSURLSigned {
+		return c.signedURL(name, ttl, now)
+	}
+	u := *c.endpoint
+	u.Path += "/" + c.opts.Bucket + "/" + c.opts.Prefix + name
+	u.RawPath = uriEncode(u.Path, false)
+	return u.String(), nil
+}
+
+// signedURL 生成 GOOG4-RSA-SHA256 签名的 GET 地址，只签名 host 头
+func (c *gcsClient) signedURL(name string, ttl time.Duration, now time.Time) (string, error) {
+	now = now.UTC()
+	date := now.Format(sigV4DateFormat)
+	scope := date + "/auto/storage/goog4_request"
+
+	u := *c.endpoint
+	u.Path += "/" + c.opts.Bucket + "/" + c.opts.Prefix + name
+	u.RawPath = uriEncode(u.Path, false)
+	query := url.Values{
+		"X-Goog-Algorithm":     {"GOOG4-RSA-SHA256"},
+		"X-Goog-Credential":    {c.account.ClientEmail + "/" + scope},
+		"X-Goog-Date":          {now.Format(sigV4TimeFormat)},
+		"X-Goog-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Goog-SignedHeaders": {"host"},
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.RawPath,
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		now.Format(sigV4TimeFormat),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature, err := c.account.sign([]byte(stringToSign))
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeStorage, "failed to sign gcs url")
+	}
+	query.Set("X-Goog-Signature", hex.EncodeToString(signature))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// do 携带访问令牌发送请求，非 2xx 响应转换为 STORAGE_ERROR（对象不存在为 NOT_FOUND）
+func (c *gcsClient) do(ctx context.Context, method, rawURL string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to build gcs request")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "gcs request failed")
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound && method != http.MethodPost {
+		return nil, errors.New(errors.ErrCodeNotFound, "file not found")
+	}
+	if err := parseGCSError(respBody); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, fmt.Sprintf("gcs %s returned %d", method, resp.StatusCode))
+	}
+	return nil, errors.New(errors.ErrCodeStorage, fmt.Sprintf("gcs %s returned %d", method, resp.StatusCode))
+}
+
+// parseGCSError 解析 JSON API 的错误响应，响应体不是错误时返回 nil
+func parseGCSError(body []byte) error {
+	var e struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) != nil || e.Error.Message == "" {
+		return nil
+	}
+	return fmt.Errorf("%d: %s", e.Error.Code, e.Error.Message)
+}
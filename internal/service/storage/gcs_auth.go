@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+const (
+	gcsScope        = "https://www.googleapis.com/auth/devstorage.read_write"
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// serviceAccount 服务账号密钥文件中用到的字段
+type serviceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+// loadServiceAccount 读取服务账号 JSON 密钥文件并解析私钥
+func loadServiceAccount(path string) (*serviceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read gcs credentials file: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("parse gcs credentials file: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("gcs credentials file must contain client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = defaultTokenURI
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("gcs private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parse gcs private key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("gcs private key is not an RSA key")
+	}
+	account.key = key
+	return &account, nil
+}
+
+// sign 以服务账号私钥做 RSA-SHA256 签名
+func (a *serviceAccount) sign(data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+}
+
+// tokenSource 用服务账号签发的 JWT 换取 OAuth2 访问令牌，过期前复用
+type tokenSource struct {
+	account *serviceAccount
+	http    *httpclient.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token 返回有效的访问令牌，剩余有效期不足一分钟时重新获取
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(time.Minute).Before(s.expires) {
+		return s.token, nil
+	}
+
+	assertion, err := s.assertion(time.Now())
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeStorage, "failed to sign gcs token request")
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := s.http.Post(ctx, s.account.TokenURI, "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeStorage, "gcs token request failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeStorage, "failed to read gcs token response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(errors.ErrCodeStorage, fmt.Sprintf("gcs token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New(errors.ErrCodeStorage, "invalid gcs token response")
+	}
+
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// assertion 生成 RS256 签名的 JWT，有效期一小时
+func (s *tokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": s.account.PrivateKeyID,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.account.ClientEmail,
+		"scope": gcsScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature, err := s.account.sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+const testClientEmail = "img2ppt@example-project.iam.gserviceaccount.com"
+
+// writeServiceAccount 生成测试用 RSA 密钥并写出服务账号密钥文件，返回文件路径与公钥
+func writeServiceAccount(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   testClientEmail,
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, &key.PublicKey
+}
+
+func TestServiceAccountAssertion(t *testing.T) {
+	path, pub := writeServiceAccount(t, "https://oauth2.example.com/token")
+	account, err := loadServiceAccount(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	assertion, err := (&tokenSource{account: account}).assertion(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion has %d parts", len(parts))
+	}
+	var header map[string]string
+	var claims map[string]interface{}
+	decodeSegment(t, parts[0], &header)
+	decodeSegment(t, parts[1], &claims)
+	if header["alg"] != "RS256" || header["typ"] != "JWT" || header["kid"] != "key-1" {
+		t.Errorf("header = %v", header)
+	}
+	want := map[string]interface{}{
+		"iss":   testClientEmail,
+		"scope": gcsScope,
+		"aud":   "https://oauth2.example.com/token",
+		"iat":   float64(now.Unix()),
+		"exp":   float64(now.Add(time.Hour).Unix()),
+	}
+	for name, value := range want {
+		if claims[name] != value {
+			t.Errorf("claim %s = %v, want %v", name, claims[name], value)
+		}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], signature); err != nil {
+		t.Errorf("assertion signature does not verify: %v", err)
+	}
+}
+
+func decodeSegment(t *testing.T, segment string, v interface{}) {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeGCSAuth 令牌端点与上传接口：每次换取令牌返回新的 token-N，上传时记录使用的令牌
+type fakeGCSAuth struct {
+	t         *testing.T
+	expiresIn int
+	status    int
+	issued    atomic.Int32
+	used      []string
+}
+
+func (f *fakeGCSAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			f.t.Errorf("token request form = %v", r.Form)
+		}
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`)
+			return
+		}
+		n := f.issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"expires_in":   f.expiresIn,
+			"token_type":   "Bearer",
+		})
+	case "/upload/storage/v1/b/bucket/o":
+		f.used = append(f.used, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"name":"job-1.pptx"}`)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	}
+}
+
+func newAuthedGCSClient(t *testing.T, fake *fakeGCSAuth) *gcsClient {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	path, _ := writeServiceAccount(t, srv.URL+"/token")
+	c, err := newGCSClient(GCSOptions{Bucket: "bucket", Endpoint: srv.URL, CredentialsFile: path}, httpclient.New(httpclient.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTokenSourceReusesAndRefreshes(t *testing.T) {
+	info := ObjectInfo{Name: "job-1.pptx", ContentType: ContentType("job-1.pptx"), CreatedAt: time.Now()}
+
+	t.Run("reuse", func(t *testing.T) {
+		fake := &fakeGCSAuth{t: t, expiresIn: 3600}
+		c := newAuthedGCSClient(t, fake)
+		for i := 0; i < 3; i++ {
+			if err := c.Put(context.Background(), info, []byte("deck")); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+		if fake.issued.Load() != 1 || fake.used[2] != "Bearer token-1" {
+			t.Errorf("issued %d tokens, used %v, want one token reused", fake.issued.Load(), fake.used)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		// 剩余有效期不足一分钟的令牌在下次请求前重新获取
+		fake := &fakeGCSAuth{t: t, expiresIn: 30}
+		c := newAuthedGCSClient(t, fake)
+		for i := 0; i < 2; i++ {
+			if err := c.Put(context.Background(), info, []byte("deck")); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+		if fake.issued.Load() != 2 || fake.used[1] != "Bearer token-2" {
+			t.Errorf("issued %d tokens, used %v, want a refresh per request", fake.issued.Load(), fake.used)
+		}
+	})
+
+	t.Run("token error", func(t *testing.T) {
+		fake := &fakeGCSAuth{t: t, status: http.StatusBadRequest}
+		c := newAuthedGCSClient(t, fake)
+		err := c.Put(context.Background(), info, []byte("deck"))
+		if !errors.Is(err, errors.ErrCodeStorage) || !strings.Contains(err.Error(), "invalid_grant") || len(fake.used) != 0 {
+			t.Errorf("error = %v, uploads = %v", err, fake.used)
+		}
+	})
+}
+
+func TestSignedURL(t *testing.T) {
+	path, pub := writeServiceAccount(t, "")
+	c, err := newGCSClient(GCSOptions{Bucket: "example-bucket", Prefix: "img2ppt/", CredentialsFile: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	raw, err := c.URL("job 1.pptx", time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "storage.googleapis.com" || u.EscapedPath() != "/example-bucket/img2ppt/job%201.pptx" {
+		t.Errorf("signed url = %s", raw)
+	}
+
+	// 按 GCS V4 签名规范手工写出的规范请求与待签字符串
+	canonicalRequest := "GET\n" +
+		"/example-bucket/img2ppt/job%201.pptx\n" +
+		"X-Goog-Algorithm=GOOG4-RSA-SHA256" +
+		"&X-Goog-Credential=img2ppt%40example-project.iam.gserviceaccount.com%2F20261001%2Fauto%2Fstorage%2Fgoog4_request" +
+		"&X-Goog-Date=20261001T080000Z&X-Goog-Expires=3600&X-Goog-SignedHeaders=host\n" +
+		"host:storage.googleapis.com\n\n" +
+		"host\n" +
+		"UNSIGNED-PAYLOAD"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "GOOG4-RSA-SHA256\n20261001T080000Z\n20261001/auto/storage/goog4_request\n" + hex.EncodeToString(requestHash[:])
+
+	query := u.Query()
+	if query.Get("X-Goog-Expires") != "3600" || query.Get("X-Goog-Credential") != testClientEmail+"/20261001/auto/storage/goog4_request" {
+		t.Errorf("query = %v", query)
+	}
+	signature, err := hex.DecodeString(query.Get("X-Goog-Signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(stringToSign))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], signature); err != nil {
+		t.Errorf("signature does not verify against the expected string to sign: %v", err)
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	c, err := newGCSClient(GCSOptions{Bucket: "example-bucket", Endpoint: "http://localhost:4443"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.URL("job-1.pptx", time.Hour, time.Now())
+	if err != nil || got != "http://localhost:4443/example-bucket/job-1.pptx" {
+		t.Errorf("URL = %s, %v", got, err)
+	}
+	if _, err := newGCSClient(GCSOptions{Bucket: "b", URLMode: GCSURLSigned}, nil); err == nil {
+		t.Error("signed urls without credentials should be rejected")
+	}
+}
+
+// fakeGCSClient 连接 FAKE_GCS_TEST_ENDPOINT 指定的 fake-gcs-server，未设置时跳过。例如：
+//
+//	docker run -p 4443:4443 fsouza/fake-gcs-server -scheme http -public-host localhost:4443
+//	FAKE_GCS_TEST_ENDPOINT=http://localhost:4443 go test ./internal/service/storage/
+//
+// 存储桶不存在时自动创建，每个测试使用独立的对象名前缀
+func fakeGCSClient(t *testing.T) *gcsClient {
+	t.Helper()
+	endpoint := os.Getenv("FAKE_GCS_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("FAKE_GCS_TEST_ENDPOINT not set")
+	}
+	bucket := os.Getenv("FAKE_GCS_TEST_BUCKET")
+	if bucket == "" {
+		bucket = "img2ppt"
+	}
+
+	c, err := newGCSClient(GCSOptions{
+		Bucket:   bucket,
+		Endpoint: endpoint,
+		Prefix:   fmt.Sprintf("test-%d/", time.Now().UnixNano()),
+	}, httpclient.New(httpclient.Options{Timeout: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]string{"name": bucket})
+	resp, err := c.do(context.Background(), http.MethodPost, endpoint+"/storage/v1/b?project=test", body, http.Header{"Content-Type": {"application/json"}})
+	if err == nil {
+		resp.Body.Close()
+	}
+	return c
+}
+
+func TestFakeGCSRoundTrip(t *testing.T) {
+	c := fakeGCSClient(t)
+	ctx := context.Background()
+
+	data := []byte("deck contents")
+	info := ObjectInfo{
+		Name:        "job-1.pptx",
+		ContentType: ContentType("job-1.pptx"),
+		Size:        int64(len(data)),
+		Checksum:    hashHex(data),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	if err := c.Put(ctx, info, data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	t.Cleanup(func() { c.Delete(context.Background(), info.Name) })
+
+	got, err := c.Get(ctx, info.Name)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	stat, err := c.Stat(ctx, info.Name)
+	if err != nil || stat.Size != info.Size || stat.Checksum != info.Checksum || !stat.CreatedAt.Equal(info.CreatedAt) {
+		t.Errorf("Stat = %+v, %v, want %+v", stat, err, info)
+	}
+	infos, err := c.List(ctx, "job-1")
+	if err != nil || len(infos) != 1 || infos[0].Name != info.Name {
+		t.Errorf("List = %+v, %v", infos, err)
+	}
+
+	// 未配置服务账号时返回公开地址，fake-gcs-server 可直接下载
+	u, err := c.URL(info.Name, time.Hour, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Errorf("GET %s = %d %q", u, resp.StatusCode, body)
+	}
+
+	if err := c.Delete(ctx, info.Name); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Get(ctx, info.Name); !errors.Is(err, errors.ErrCodeNotFound) {
+		t.Errorf("Get after delete error = %v, want NOT_FOUND", err)
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// metaDir 本地元数据目录，以 . 开头的名称不是合法文件名，不会与对象冲突
+const metaDir = ".meta"
+
+// localBackend 本地目录存储，元数据以 JSON 保存在 .meta/<name>.json，下载地址由 signer 签名
+type localBackend struct {
+	dir    string
+	signer urlSigner
+}
+
+func newLocalBackend(dir string, signer urlSigner) (*localBackend, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage base path is required")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, metaDir), 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	return &localBackend{dir: dir, signer: signer}, nil
+}
+
+func (b *localBackend) Put(ctx context.Context, info ObjectInfo, data []byte) error {
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to encode file metadata")
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, info.Name), data, 0644); err != nil {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write file")
+	}
+	if err := os.WriteFile(b.metaPath(info.Name), meta, 0644); err != nil {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write file metadata")
+	}
+	return nil
+}
+
+func (b *localBackend) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrCodeNotFound, "file not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read file")
+	}
+	return data, nil
+}
+
+// Stat 读取元数据，没有元数据的旧文件按文件属性推断（不含 Checksum）
+func (b *localBackend) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	fi, err := os.Stat(filepath.Join(b.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrCodeNotFound, "file not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to stat file")
+	}
+
+	var info ObjectInfo
+	meta, err := os.ReadFile(b.metaPath(name))
+	if err == nil && json.Unmarshal(meta, &info) == nil && info.Size == fi.Size() {
+		return &info, nil
+	}
+	return &ObjectInfo{
+		Name:        name,
+		ContentType: ContentType(name),
+		Size:        fi.Size(),
+		CreatedAt:   fi.ModTime().UTC(),
+	}, nil
+}
+
+func (b *localBackend) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(filepath.Join(b.dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return errors.New(errors.ErrCodeNotFound, "file not found")
+		}
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to delete file")
+	}
+	if err := os.Remove(b.metaPath(name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to delete file metadata")
+	}
+	return nil
+}
+
+func (b *localBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to list files")
+	}
+
+	var infos []ObjectInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !validName(name) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		info, err := b.Stat(ctx, name)
+		if err != nil {
+			// 列举期间被删除
+			if errors.Is(err, errors.ErrCodeNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+func (b *localBackend) URL(name string, ttl time.Duration, now time.Time) (string, error) {
+	return b.signer.sign(name, now.Add(ttl)), nil
+}
+
+func (b *localBackend) metaPath(name string) string {
+	return filepath.Join(b.dir, metaDir, name+".json")
+}
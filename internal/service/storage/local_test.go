@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+func newTestLocalBackend(t *testing.T) (*localBackend, string) {
+	t.Helper()
+	dir := t.TempDir()
+	b, err := newLocalBackend(dir, urlSigner{baseURL: "/files", secret: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, dir
+}
+
+func localObject(name string, data []byte) ObjectInfo {
+	return ObjectInfo{
+		Name:        name,
+		ContentType: ContentType(name),
+		Size:        int64(len(data)),
+		Checksum:    hashHex(data),
+		CreatedAt:   time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestLocalPutWritesMetadata(t *testing.T) {
+	b, dir := newTestLocalBackend(t)
+	data := []byte("deck contents")
+	info := localObject("job-1.pptx", data)
+	if err := b.Put(context.Background(), info, data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if got, err := os.ReadFile(filepath.Join(dir, "job-1.pptx")); err != nil || string(got) != string(data) {
+		t.Errorf("file = %q, %v", got, err)
+	}
+	meta, err := os.ReadFile(filepath.Join(dir, ".meta", "job-1.pptx.json"))
+	if err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	var saved ObjectInfo
+	if err := json.Unmarshal(meta, &saved); err != nil || saved != info {
+		t.Errorf("metadata = %+v, %v, want %+v", saved, err, info)
+	}
+
+	stat, err := b.Stat(context.Background(), "job-1.pptx")
+	if err != nil || *stat != info {
+		t.Errorf("Stat = %+v, %v, want the saved metadata", stat, err)
+	}
+}
+
+func TestLocalStatFallsBackToFileInfo(t *testing.T) {
+	b, dir := newTestLocalBackend(t)
+	ctx := context.Background()
+	data := []byte("deck contents")
+	for _, name := range []string{"missing-meta.pptx", "resized.pptx"} {
+		if err := b.Put(ctx, localObject(name, data), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, ".meta", "missing-meta.pptx.json")); err != nil {
+		t.Fatal(err)
+	}
+	// 文件在保存后被改写，元数据中的大小不再一致
+	if err := os.WriteFile(filepath.Join(dir, "resized.pptx"), []byte("longer deck contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, size := range map[string]int64{"missing-meta.pptx": 13, "resized.pptx": 20} {
+		info, err := b.Stat(ctx, name)
+		if err != nil {
+			t.Fatalf("Stat %s: %v", name, err)
+		}
+		if info.Name != name || info.Size != size || info.Checksum != "" || info.ContentType != ContentType(name) || info.CreatedAt.IsZero() {
+			t.Errorf("Stat %s = %+v", name, info)
+		}
+	}
+
+	if _, err := b.Stat(ctx, "job-9.pptx"); !errors.Is(err, errors.ErrCodeNotFound) {
+		t.Errorf("Stat missing file error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestLocalListSkipsMetadata(t *testing.T) {
+	b, _ := newTestLocalBackend(t)
+	ctx := context.Background()
+	for _, name := range []string{"job-1.pptx", "job-1_slide1.png", "job-2.pptx"} {
+		if err := b.Put(ctx, localObject(name, []byte(name)), []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err := b.List(ctx, "")
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("List = %+v, %v, want the three objects without .meta", infos, err)
+	}
+	for _, info := range infos {
+		if info.Name == metaDir || info.Checksum == "" {
+			t.Errorf("listed %+v", info)
+		}
+	}
+	if infos, err := b.List(ctx, "job-1"); err != nil || len(infos) != 2 {
+		t.Errorf("List job-1 = %+v, %v", infos, err)
+	}
+}
+
+func TestLocalDeleteRemovesMetadata(t *testing.T) {
+	b, dir := newTestLocalBackend(t)
+	ctx := context.Background()
+	data := []byte("deck contents")
+	if err := b.Put(ctx, localObject("job-1.pptx", data), data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Delete(ctx, "job-1.pptx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, path := range []string{filepath.Join(dir, "job-1.pptx"), filepath.Join(dir, ".meta", "job-1.pptx.json")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", path, err)
+		}
+	}
+	if err := b.Delete(ctx, "job-1.pptx"); !errors.Is(err, errors.ErrCodeNotFound) {
+		t.Errorf("second Delete error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestServiceListAndDelete(t *testing.T) {
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(Options{Type: "local", BasePath: t.TempDir(), BaseURL: "/files", URLSecret: "secret"}, httpclient.New(httpclient.Options{}), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := s.SavePPT(ctx, "job-1", []byte("PK\x03\x04deck")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.SaveImage(ctx, "job-1_slide1", []byte("\x89PNG\r\n\x1a\n0000")); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := s.List(ctx, "job-1")
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("List = %+v, %v", infos, err)
+	}
+
+	for _, name := range []string{"../job-1.pptx", ".meta", ""} {
+		if err := s.Delete(ctx, name); !errors.Is(err, errors.ErrCodeInvalidReq) {
+			t.Errorf("Delete(%q) error = %v, want INVALID_REQUEST", name, err)
+		}
+	}
+	if err := s.Delete(ctx, "job-1.pptx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if infos, err := s.List(ctx, "job-1"); err != nil || len(infos) != 1 || infos[0].Name != "job-1_slide1.png" {
+		t.Errorf("List after delete = %+v, %v", infos, err)
+	}
+}
+
+func TestNewUnknownType(t *testing.T) {
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(Options{Type: "ftp", URLSecret: "secret"}, httpclient.New(httpclient.Options{}), log)
+	if err == nil || !strings.Contains(err.Error(), `unknown storage type "ftp"`) {
+		t.Errorf("error = %v, want an unknown storage type error", err)
+	}
+}
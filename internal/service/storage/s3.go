@@ -0,0 +1,357 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// S3 分片上传的分片大小限制
+const (
+	minPartSize     = 5 << 20
+	defaultPartSize = 8 << 20
+)
+
+// S3Options S3 兼容对象存储配置。Endpoint 为空时使用 AWS 区域端点；
+// MinIO 等自建服务需设置 Endpoint 并开启 PathStyle
+type S3Options struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Prefix 对象键前缀，如 img2ppt/
+	Prefix string
+	// PartSize 超过该大小的文件使用分片上传，不小于 5MB
+	PartSize int64
+}
+
+// s3Client 基于 REST API 与 SigV4 签名的最小 S3 客户端，只实现存储所需的对象读写与分片上传
+type s3Client struct {
+	opts     S3Options
+	endpoint *url.URL
+	creds    credentials
+	http     *httpclient.Client
+}
+
+func newS3Client(opts S3Options, client *httpclient.Client) (*s3Client, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.PartSize == 0 {
+		opts.PartSize = defaultPartSize
+	}
+	if opts.PartSize < minPartSize {
+		return nil, fmt.Errorf("s3 part size must be at least %d bytes", minPartSize)
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+
+	return &s3Client{
+		opts:     opts,
+		endpoint: u,
+		creds: credentials{
+			AccessKeyID:     opts.AccessKeyID,
+			SecretAccessKey: opts.SecretAccessKey,
+			SessionToken:    opts.SessionToken,
+		},
+		http: client,
+	}, nil
+}
+
+// S3 用户元数据头，与对象一同保存
+const (
+	s3MetaChecksum  = "X-Amz-Meta-Sha256"
+	s3MetaCreatedAt = "X-Amz-Meta-Created-At"
+)
+
+// Put 上传对象，校验和与创建时间保存为用户元数据；超过分片大小时改用分片上传
+func (c *s3Client) Put(ctx context.Context, info ObjectInfo, data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", info.ContentType)
+	header.Set(s3MetaChecksum, info.Checksum)
+	header.Set(s3MetaCreatedAt, info.CreatedAt.UTC().Format(time.RFC3339))
+
+	if int64(len(data)) > c.opts.PartSize {
+		return c.multipartUpload(ctx, c.key(info.Name), header, data)
+	}
+	resp, err := c.do(ctx, http.MethodPut, c.key(info.Name), nil, data, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *s3Client) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.key(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read s3 object")
+	}
+	return data, nil
+}
+
+// Stat 以 HEAD 请求读取对象的元数据，不是由本服务上传的对象没有校验和，创建时间取 Last-Modified
+func (c *s3Client) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	resp, err := c.do(ctx, http.MethodHead, c.key(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &ObjectInfo{
+		Name:        name,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+		Checksum:    resp.Header.Get(s3MetaChecksum),
+	}
+	if t, err := time.Parse(time.RFC3339, resp.Header.Get(s3MetaCreatedAt)); err == nil {
+		info.CreatedAt = t
+	} else if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.CreatedAt = t.UTC()
+	}
+	return info, nil
+}
+
+// Delete 删除对象；S3 删除不存在的对象同样返回成功
+func (c *s3Client) Delete(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.key(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List 以 ListObjectsV2 分页列举对象。列举结果不含用户元数据，因此没有校验和，创建时间取 LastModified
+func (c *s3Client) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var infos []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {c.key(prefix)},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to decode s3 object list")
+		}
+
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, c.opts.Prefix)
+			if !validName(name) {
+				continue
+			}
+			infos = append(infos, ObjectInfo{
+				Name:        name,
+				ContentType: ContentType(name),
+				Size:        object.Size,
+				CreatedAt:   object.LastModified.UTC(),
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return infos, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// URL 对象的预签名下载地址；未配置凭证（公开读的存储桶）时返回不带签名的对象地址
+func (c *s3Client) URL(name string, ttl time.Duration, now time.Time) (string, error) {
+	u, err := url.Parse(c.objectURL(c.key(name), nil))
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to build s3 url")
+	}
+	if c.creds.AccessKeyID == "" {
+		return u.String(), nil
+	}
+	return presignV4(u, c.creds, c.opts.Region, "s3", now, ttl), nil
+}
+
+// key 文件名加上配置的前缀
+func (c *s3Client) key(name string) string {
+	return c.opts.Prefix + name
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// multipartUpload 按 PartSize 分片顺序上传，header 在发起上传时提交；任一步失败时中止上传以释放已上传的分片
+func (c *s3Client) multipartUpload(ctx context.Context, key string, header http.Header, data []byte) error {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, header)
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return errors.New(errors.ErrCodeStorage, "failed to initiate s3 multipart upload")
+	}
+
+	var parts []completedPart
+	for offset, number := int64(0), 1; offset < int64(len(data)); offset, number = offset+c.opts.PartSize, number+1 {
+		end := offset + c.opts.PartSize
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		query := url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {initiated.UploadID},
+		}
+		resp, err := c.do(ctx, http.MethodPut, key, query, data[offset:end], nil)
+		if err != nil {
+			c.abortUpload(key, initiated.UploadID)
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		c.abortUpload(key, initiated.UploadID)
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal multipart upload")
+	}
+	resp, err = c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {initiated.UploadID}}, body, nil)
+	if err != nil {
+		c.abortUpload(key, initiated.UploadID)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// CompleteMultipartUpload 可能在返回 200 后于响应体中报告错误
+	respBody, _ := io.ReadAll(resp.Body)
+	if err := parseS3Error(respBody); err != nil {
+		c.abortUpload(key, initiated.UploadID)
+		return errors.Wrap(err, errors.ErrCodeStorage, "s3 multipart upload failed")
+	}
+	return nil
+}
+
+// abortUpload 中止分片上传，请求已取消时仍尝试执行
+func (c *s3Client) abortUpload(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// do 签名并发送请求，key 为空时请求存储桶本身；非 2xx 响应转换为 STORAGE_ERROR（对象不存在为 NOT_FOUND）
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to build s3 request")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	signV4(req, hashHex(body), c.creds, c.opts.Region, "s3", time.Now())
+
+	resp, err := c.http.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "s3 request failed")
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound && (method == http.MethodGet || method == http.MethodHead) {
+		return nil, errors.New(errors.ErrCodeNotFound, "file not found")
+	}
+	if err := parseS3Error(respBody); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, fmt.Sprintf("s3 %s returned %d", method, resp.StatusCode))
+	}
+	return nil, errors.New(errors.ErrCodeStorage, fmt.Sprintf("s3 %s returned %d", method, resp.StatusCode))
+}
+
+// objectURL 对象地址：PathStyle 为 endpoint/bucket/key，否则为 bucket.endpoint/key
+func (c *s3Client) objectURL(key string, query url.Values) string {
+	u := *c.endpoint
+	if c.opts.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.opts.Bucket + "/" + key
+	} else {
+		u.Host = c.opts.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return u.String()
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// parseS3Error 解析 S3 的 XML 错误响应，响应体不是错误时返回 nil
+func parseS3Error(body []byte) error {
+	var e s3Error
+	if xml.Unmarshal(body, &e) != nil || e.Code == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", e.Code, e.Message)
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// minioClient 连接 MINIO_TEST_ENDPOINT 指定的 MinIO（或其他 S3 兼容服务），未设置时跳过。例如：
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	MINIO_TEST_ENDPOINT=http://localhost:9000 MINIO_TEST_BUCKET=img2ppt \
+//	MINIO_TEST_ACCESS_KEY=minioadmin MINIO_TEST_SECRET_KEY=minioadmin go test ./internal/service/storage/
+//
+// 存储桶需预先创建，每个测试使用独立的对象键前缀并在结束时删除上传的对象
+func minioClient(t *testing.T, partSize int64) *s3Client {
+	t.Helper()
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT not set")
+	}
+	bucket := os.Getenv("MINIO_TEST_BUCKET")
+	if bucket == "" {
+		bucket = "img2ppt"
+	}
+
+	c, err := newS3Client(S3Options{
+		Bucket:          bucket,
+		Region:          os.Getenv("MINIO_TEST_REGION"),
+		Endpoint:        endpoint,
+		PathStyle:       true,
+		AccessKeyID:     os.Getenv("MINIO_TEST_ACCESS_KEY"),
+		SecretAccessKey: os.Getenv("MINIO_TEST_SECRET_KEY"),
+		Prefix:          fmt.Sprintf("test-%d/", time.Now().UnixNano()),
+		PartSize:        partSize,
+	}, httpclient.New(httpclient.Options{Timeout: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func putObject(t *testing.T, c *s3Client, name string, data []byte) ObjectInfo {
+	t.Helper()
+	info := ObjectInfo{
+		Name:        name,
+		ContentType: ContentType(name),
+		Size:        int64(len(data)),
+		Checksum:    hashHex(data),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	if err := c.Put(context.Background(), info, data); err != nil {
+		t.Fatalf("Put %s: %v", name, err)
+	}
+	t.Cleanup(func() { c.Delete(context.Background(), name) })
+	return info
+}
+
+func TestMinIOPutGetStatListDelete(t *testing.T) {
+	c := minioClient(t, 0)
+	ctx := context.Background()
+
+	data := []byte("deck contents")
+	info := putObject(t, c, "job-1.pptx", data)
+
+	got, err := c.Get(ctx, "job-1.pptx")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	stat, err := c.Stat(ctx, "job-1.pptx")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if stat.Size != info.Size || stat.Checksum != info.Checksum || !stat.CreatedAt.Equal(info.CreatedAt) || stat.ContentType != info.ContentType {
+		t.Errorf("stat = %+v, want %+v", stat, info)
+	}
+	infos, err := c.List(ctx, "job-1")
+	if err != nil || len(infos) != 1 || infos[0].Name != "job-1.pptx" {
+		t.Errorf("List = %+v, %v", infos, err)
+	}
+
+	if err := c.Delete(ctx, "job-1.pptx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Get(ctx, "job-1.pptx"); !errors.Is(err, errors.ErrCodeNotFound) {
+		t.Errorf("Get after delete error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestMinIOMultipartUpload(t *testing.T) {
+	c := minioClient(t, minPartSize)
+
+	// 两个完整分片加一个不足分片大小的末尾分片
+	data := make([]byte, 2*minPartSize+1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	putObject(t, c, "large.pptx", data)
+
+	got, err := c.Get(context.Background(), "large.pptx")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get returned %d bytes, %v, want the %d uploaded bytes", len(got), err, len(data))
+	}
+	stat, err := c.Stat(context.Background(), "large.pptx")
+	if err != nil || stat.Checksum != hashHex(data) {
+		t.Errorf("stat = %+v, %v, want the metadata sent when initiating the upload", stat, err)
+	}
+}
+
+func TestMinIOPresignedURL(t *testing.T) {
+	c := minioClient(t, 0)
+	data := []byte("presigned contents")
+	putObject(t, c, "shared.pptx", data)
+
+	u, err := c.URL("shared.pptx", time.Hour, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Errorf("GET presigned url = %d %q", resp.StatusCode, body)
+	}
+
+	// 已过期的地址被拒绝
+	expired, err := c.URL("shared.pptx", time.Minute, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET expired url = %d, want 403", resp.StatusCode)
+	}
+}
+
+// fakeS3 只实现分片上传相关接口的 S3 服务端，failPart 为返回 500 的分片号
+type fakeS3 struct {
+	t        *testing.T
+	failPart string
+	parts    map[string][]byte
+	requests []string
+	object   []byte
+	aborted  bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" ") {
+		f.t.Errorf("%s %s is not signed", r.Method, r.URL)
+	}
+	body, _ := io.ReadAll(r.Body)
+	if hashHex(body) != r.Header.Get("X-Amz-Content-Sha256") {
+		f.t.Errorf("%s %s payload hash mismatch", r.Method, r.URL)
+	}
+	query := r.URL.Query()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		if r.Header.Get(s3MetaChecksum) == "" {
+			f.t.Error("metadata should be sent when initiating the upload")
+		}
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Get("uploadId") == "upload-1":
+		if query.Get("partNumber") == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `<Error><Code>InternalError</Code><Message>boom</Message></Error>`)
+			return
+		}
+		f.parts[query.Get("partNumber")] = body
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && query.Get("uploadId") == "upload-1":
+		var complete completeMultipartUpload
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			f.t.Errorf("complete body: %v", err)
+		}
+		for i, part := range complete.Parts {
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf(`"etag-%d"`, i+1) {
+				f.t.Errorf("part %d = %+v", i, part)
+			}
+			f.object = append(f.object, f.parts[strconv.Itoa(part.PartNumber)]...)
+		}
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>k</Key></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "upload-1":
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestMultipartUploadRequests(t *testing.T) {
+	data := make([]byte, 2*minPartSize+1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, failPart := range map[string]string{"complete": "", "abort": "2"} {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeS3{t: t, failPart: failPart, parts: make(map[string][]byte)}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+			c, err := newS3Client(S3Options{
+				Bucket:          "bucket",
+				Endpoint:        srv.URL,
+				PathStyle:       true,
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "secret",
+				PartSize:        minPartSize,
+			}, httpclient.New(httpclient.Options{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.Put(context.Background(), ObjectInfo{Name: "large.pptx", ContentType: ContentType("large.pptx"), Checksum: hashHex(data)}, data)
+			if failPart != "" {
+				// 失败后中止上传，不再提交
+				if !errors.Is(err, errors.ErrCodeStorage) || !fake.aborted || fake.object != nil {
+					t.Errorf("error = %v, aborted = %v, requests = %v", err, fake.aborted, fake.requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if len(fake.parts) != 3 || !bytes.Equal(fake.object, data) || fake.aborted {
+				t.Errorf("parts = %d, object = %d bytes, requests = %v", len(fake.parts), len(fake.object), fake.requests)
+			}
+		})
+	}
+}
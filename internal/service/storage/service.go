@@ -3,60 +3,178 @@ package storage
 import (
 	"context"
 	"fmt"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
 	"github.com/ChaseRain/img2ppt/internal/infra/logger"
 	"github.com/ChaseRain/img2ppt/pkg/errors"
 )
 
+// Options 存储配置，Type 为 local、s3 或 gcs；S3、GCS 仅在对应类型时使用
+type Options struct {
+	Type     string
+	BasePath string
+	BaseURL  string
+	S3       S3Options
+	GCS      GCSOptions
+	// URLSecret 本地下载地址的签名密钥，为空时启动时随机生成
+	URLSecret string
+	// URLTTL 返回地址的有效期，默认一小时，最长 7 天
+	URLTTL time.Duration
+}
+
 type Service struct {
+	backend     Backend
 	storageType string
-	basePath    string
 	baseURL     string
+	signer      urlSigner
+	urlTTL      time.Duration
 	logger      *logger.Logger
 }
 
-func New(storageType, basePath, baseURL string, log *logger.Logger) *Service {
+// New 创建存储服务，未知的存储类型返回错误；client 用于访问对象存储
+func New(opts Options, client *httpclient.Client, log *logger.Logger) (*Service, error) {
+	urlTTL := opts.URLTTL
+	if urlTTL <= 0 {
+		urlTTL = defaultURLTTL
+	}
+	if urlTTL > maxURLTTL {
+		return nil, fmt.Errorf("storage url ttl must not exceed %s", maxURLTTL)
+	}
+
+	secret := []byte(opts.URLSecret)
+	if len(secret) == 0 {
+		var err error
+		if secret, err = randomSecret(); err != nil {
+			return nil, err
+		}
+		log.Warn("storage url secret not configured, signed urls will not survive restarts")
+	}
+	signer := urlSigner{baseURL: opts.BaseURL, secret: secret}
+
+	backend, err := newBackend(opts, signer, client)
+	if err != nil {
+		return nil, err
+	}
 	return &Service{
-		storageType: storageType,
-		basePath:    basePath,
-		baseURL:     baseURL,
+		backend:     backend,
+		storageType: opts.Type,
+		baseURL:     opts.BaseURL,
+		signer:      signer,
+		urlTTL:      urlTTL,
 		logger:      log,
-	}
+	}, nil
 }
 
+// SavePPT 保存文件，扩展名按内容检测，返回带有效期的下载地址
 func (s *Service) SavePPT(ctx context.Context, id string, data []byte) (string, error) {
-	switch s.storageType {
-	case "local":
-		return s.saveLocal(id, data)
-	case "s3":
-		return s.saveS3(ctx, id, data)
-	case "gcs":
-		return s.saveGCS(ctx, id, data)
-	default:
-		return s.saveLocal(id, data)
+	filename := id + s.detectExtension(data)
+	if !validName(filename) {
+		return "", errors.New(errors.ErrCodeInvalidReq, "invalid file id")
+	}
+
+	info := ObjectInfo{
+		Name:        filename,
+		ContentType: ContentType(filename),
+		Size:        int64(len(data)),
+		Checksum:    hashHex(data),
+		CreatedAt:   time.Now().UTC(),
 	}
+	if err := s.backend.Put(ctx, info, data); err != nil {
+		s.logger.Error("failed to save file", "storage", s.storageType, "name", filename, "error", err)
+		return "", err
+	}
+
+	url, err := s.URL(filename)
+	if err != nil {
+		return "", err
+	}
+	s.logger.Info("saved file", "storage", s.storageType, "name", filename, "size", len(data))
+
+	return url, nil
 }
 
-func (s *Service) saveLocal(id string, data []byte) (string, error) {
-	if err := os.MkdirAll(s.basePath, 0755); err != nil {
-		return "", errors.Wrap(err, errors.ErrCodeStorage, "failed to create output directory")
+// SaveImage 保存图片（如配图候选），返回访问地址与存储中的文件名，文件名可交给 GetFile 读回
+func (s *Service) SaveImage(ctx context.Context, id string, data []byte) (string, string, error) {
+	url, err := s.SavePPT(ctx, id, data)
+	if err != nil {
+		return "", "", err
 	}
+	return url, id + s.detectExtension(data), nil
+}
 
-	// 检测文件类型，根据内容决定扩展名
-	ext := s.detectExtension(data)
-	filename := fmt.Sprintf("%s%s", id, ext)
-	filePath := filepath.Join(s.basePath, filename)
+// GetFile 按文件名（含扩展名）读取已保存的文件，拒绝包含路径的名称
+func (s *Service) GetFile(ctx context.Context, name string) ([]byte, error) {
+	if !validName(name) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, "invalid file name")
+	}
+	return s.backend.Get(ctx, name)
+}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", errors.Wrap(err, errors.ErrCodeStorage, "failed to write file")
+// Stat 读取文件的元数据
+func (s *Service) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	if !validName(name) {
+		return nil, errors.New(errors.ErrCodeInvalidReq, "invalid file name")
 	}
+	return s.backend.Stat(ctx, name)
+}
 
-	url := fmt.Sprintf("%s/%s", s.baseURL, filename)
-	s.logger.Info("saved file locally", "path", filePath, "url", url, "size", len(data))
+// Delete 删除文件及其元数据
+func (s *Service) Delete(ctx context.Context, name string) error {
+	if !validName(name) {
+		return errors.New(errors.ErrCodeInvalidReq, "invalid file name")
+	}
+	return s.backend.Delete(ctx, name)
+}
 
-	return url, nil
+// List 列出文件名以 prefix 开头的文件，如某个任务的全部产物
+func (s *Service) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return s.backend.List(ctx, prefix)
+}
+
+// URL 返回文件带有效期的下载地址：本地存储为 HMAC 签名的 base_url 地址，
+// 对象存储为原生的预签名地址
+func (s *Service) URL(name string) (string, error) {
+	return s.backend.URL(name, s.urlTTL, time.Now())
+}
+
+// RefreshURL 为此前签发的地址按当前时间重新签名，地址末段不是合法文件名时原样返回
+func (s *Service) RefreshURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	name := path.Base(u.Path)
+	if !validName(name) {
+		return rawURL
+	}
+	refreshed, err := s.URL(name)
+	if err != nil {
+		s.logger.Warn("failed to refresh file url", "name", name, "error", err)
+		return rawURL
+	}
+	return refreshed
+}
+
+// Verify 校验 base_url 下载地址查询参数中的签名与有效期
+func (s *Service) Verify(name string, query url.Values) error {
+	return s.signer.verify(name, query, time.Now())
+}
+
+// URLPath base_url 的路径部分，本服务在该路径下提供文件下载；未配置路径时为 /files
+func (s *Service) URLPath() string {
+	u, err := url.Parse(s.baseURL)
+	if err != nil || strings.Trim(u.Path, "/") == "" {
+		return "/files"
+	}
+	return "/" + strings.Trim(u.Path, "/")
 }
 
 func (s *Service) detectExtension(data []byte) string {
@@ -76,7 +194,7 @@ func (s *Service) detectExtension(data []byte) string {
 		return ".gif"
 	}
 	// WebP
-	if len(data) >= 12 && data[0] == 0x52 && data[1] == 0x49 && data[2] == 0x46 && data[3] == 0x46 {
+	if len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
 		return ".webp"
 	}
 	// PPTX (ZIP)
@@ -86,38 +204,29 @@ func (s *Service) detectExtension(data []byte) string {
 	return ".bin"
 }
 
-func (s *Service) saveS3(ctx context.Context, id string, data []byte) (string, error) {
-	// TODO: Implement S3 storage
-	// This is a placeholder for S3 implementation using AWS SDK
-	return "", errors.New(errors.ErrCodeStorage, "S3 storage not implemented")
-}
-
-func (s *Service) saveGCS(ctx context.Context, id string, data []byte) (string, error) {
-	// TODO: Implement GCS storage
-	// This is a placeholder for GCS implementation using Google Cloud SDK
-	return "", errors.New(errors.ErrCodeStorage, "GCS storage not implemented")
+// contentTypes 按扩展名确定的 Content-Type，未列出的按二进制处理
+var contentTypes = map[string]string{
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
 }
 
-func (s *Service) GetFile(ctx context.Context, id string) ([]byte, error) {
-	switch s.storageType {
-	case "local":
-		return s.getLocal(id)
-	default:
-		return s.getLocal(id)
+// ContentType 按文件扩展名返回 Content-Type
+func ContentType(name string) string {
+	if contentType, ok := contentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return contentType
 	}
+	return "application/octet-stream"
 }
 
-func (s *Service) getLocal(id string) ([]byte, error) {
-	filename := fmt.Sprintf("%s.pptx", id)
-	filePath := filepath.Join(s.basePath, filename)
-
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, errors.New(errors.ErrCodeNotFound, "file not found")
-		}
-		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read file")
-	}
-
-	return data, nil
+// validName 文件名不能包含路径分隔符，也不能是 .、.. 或隐藏文件
+func validName(name string) bool {
+	return name != "" &&
+		name == filepath.Base(name) &&
+		!strings.HasPrefix(name, ".") &&
+		!strings.ContainsAny(name, `/\`)
 }
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+const (
+	defaultURLTTL = time.Hour
+	// maxURLTTL S3 与 GCS 预签名地址的最长有效期
+	maxURLTTL = 7 * 24 * time.Hour
+)
+
+// 本地下载地址的签名参数
+const (
+	paramExpires   = "expires"
+	paramSignature = "signature"
+)
+
+// urlSigner 为 base_url 下的下载地址签名：base_url/name?expires=<unix 秒>&signature=<hex>，
+// 签名为 HMAC-SHA256(name + "\n" + expires)
+type urlSigner struct {
+	baseURL string
+	secret  []byte
+}
+
+func (s urlSigner) sign(name string, deadline time.Time) string {
+	expires := strconv.FormatInt(deadline.Unix(), 10)
+	query := url.Values{
+		paramExpires:   {expires},
+		paramSignature: {s.signature(name, expires)},
+	}
+	return fmt.Sprintf("%s/%s?%s", s.baseURL, url.PathEscape(name), query.Encode())
+}
+
+// verify 校验查询参数中的签名与有效期
+func (s urlSigner) verify(name string, query url.Values, now time.Time) error {
+	expires, signature := query.Get(paramExpires), query.Get(paramSignature)
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New(errors.ErrCodeForbidden, "missing or malformed url signature")
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(name, expires))) {
+		return errors.New(errors.ErrCodeForbidden, "invalid url signature")
+	}
+	if now.Unix() > deadline {
+		return errors.New(errors.ErrCodeForbidden, "url expired")
+	}
+	return nil
+}
+
+func (s urlSigner) signature(name, expires string) string {
+	return hex.EncodeToString(hmacSHA256(s.secret, name+"\n"+expires))
+}
+
+// randomSecret 未配置签名密钥时使用的随机密钥，重启后此前签发的地址失效
+func randomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate url secret: %w", err)
+	}
+	return secret, nil
+}
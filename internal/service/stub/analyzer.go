@@ -0,0 +1,305 @@
+package stub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+// Analyzer 离线桩实现：根据图片尺寸与颜色分布生成确定的 SlideSpec，不访问网络，用于本地开发与 CI
+type Analyzer struct{}
+
+// NewAnalyzer 忽略所有配置项
+func NewAnalyzer(provider.Settings) (provider.Analyzer, error) {
+	return &Analyzer{}, nil
+}
+
+func (a *Analyzer) Model() string {
+	return "stub"
+}
+
+func (a *Analyzer) AnalyzeImage(ctx context.Context, imageBytes []byte, language, style string) (*provider.SlideSpec, error) {
+	stats := analyze(imageBytes)
+	t := texts(language)
+
+	return &provider.SlideSpec{
+		Title:       fmt.Sprintf(t.title, stats.colorName(language)),
+		Subtitle:    fmt.Sprintf(t.subtitle, stats.width, stats.height, stats.orientation(language)),
+		Bullets:     stats.bullets(t),
+		Notes:       fmt.Sprintf(t.notes, stats.digest),
+		ImagePrompt: stats.imagePrompt(style),
+		Style:       style,
+	}, nil
+}
+
+func (a *Analyzer) AnalyzeImageOutline(ctx context.Context, imageBytes []byte, language, style string, contentSlides int) ([]*provider.SlideSpec, error) {
+	stats := analyze(imageBytes)
+	t := texts(language)
+	bullets := stats.bullets(t)
+
+	slides := make([]*provider.SlideSpec, 0, contentSlides+2)
+	slides = append(slides, &provider.SlideSpec{
+		Title:       fmt.Sprintf(t.title, stats.colorName(language)),
+		Subtitle:    fmt.Sprintf(t.subtitle, stats.width, stats.height, stats.orientation(language)),
+		Bullets:     []string{},
+		Notes:       fmt.Sprintf(t.notes, stats.digest),
+		ImagePrompt: stats.imagePrompt(style),
+		Style:       style,
+	})
+	for i := 0; i < contentSlides; i++ {
+		slides = append(slides, &provider.SlideSpec{
+			Title:       fmt.Sprintf(t.section, i+1),
+			Bullets:     rotate(bullets, i),
+			Notes:       fmt.Sprintf(t.notes, stats.digest),
+			ImagePrompt: fmt.Sprintf("%s, variation %d", stats.imagePrompt(style), i+1),
+			Style:       style,
+		})
+	}
+	slides = append(slides, &provider.SlideSpec{
+		Title:       t.summary,
+		Bullets:     bullets[:1],
+		Notes:       fmt.Sprintf(t.notes, stats.digest),
+		ImagePrompt: stats.imagePrompt(style),
+		Style:       style,
+	})
+
+	return slides, nil
+}
+
+func (a *Analyzer) GenerateCover(ctx context.Context, titles []string, language, style string) (*provider.SlideSpec, error) {
+	t := texts(language)
+	title := t.summary
+	if len(titles) > 0 {
+		title = titles[0]
+	}
+	return &provider.SlideSpec{
+		Title:    title,
+		Subtitle: fmt.Sprintf(t.cover, len(titles)),
+		Bullets:  []string{},
+		Notes:    strings.Join(titles, "\n"),
+		Style:    style,
+	}, nil
+}
+
+// ReviseSlide 不理解修改意见：修改文字时把意见追加到备注，修改配图时追加到配图描述
+func (a *Analyzer) ReviseSlide(ctx context.Context, spec *provider.SlideSpec, instruction, target, language, style string) (*provider.SlideSpec, error) {
+	revised := *spec
+	revised.Bullets = append([]string(nil), spec.Bullets...)
+	if target == provider.ReviseImage {
+		revised.ImagePrompt = fmt.Sprintf("%s, revised: %s", spec.ImagePrompt, instruction)
+		return &revised, nil
+	}
+
+	note := fmt.Sprintf(texts(language).revised, instruction)
+	if revised.Notes == "" {
+		revised.Notes = note
+	} else {
+		revised.Notes += "\n" + note
+	}
+	return &revised, nil
+}
+
+// imageStats 图片的尺寸、平均色与色相分布
+type imageStats struct {
+	width, height int
+	average       [3]uint8
+	// hues 6 个色相区间（红、黄、绿、青、蓝、品红）的像素占比，灰色像素不计入
+	hues       [6]float64
+	brightness float64
+	digest     string
+}
+
+// analyze 统计图片特征，无法解码的格式（如 webp）只使用内容摘要
+func analyze(imageBytes []byte) imageStats {
+	sum := sha256.Sum256(imageBytes)
+	stats := imageStats{digest: hex.EncodeToString(sum[:4])}
+	stats.average = [3]uint8{sum[4], sum[5], sum[6]}
+
+	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return stats
+	}
+
+	bounds := img.Bounds()
+	stats.width, stats.height = bounds.Dx(), bounds.Dy()
+
+	// 按步长采样，避免大图逐像素统计
+	step := max(1, max(stats.width, stats.height)/200)
+	var r, g, b, n, colored float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			fr, fg, fb := float64(cr>>8), float64(cg>>8), float64(cb>>8)
+			r, g, b, n = r+fr, g+fg, b+fb, n+1
+
+			if hue, ok := hueOf(fr, fg, fb); ok {
+				stats.hues[int(hue/60)%6]++
+				colored++
+			}
+		}
+	}
+	if n == 0 {
+		return stats
+	}
+
+	stats.average = [3]uint8{uint8(r / n), uint8(g / n), uint8(b / n)}
+	stats.brightness = (0.299*r + 0.587*g + 0.114*b) / n / 255
+	if colored > 0 {
+		for i := range stats.hues {
+			stats.hues[i] /= colored
+		}
+	}
+	return stats
+}
+
+// hueOf 返回色相角度，饱和度过低的灰色像素返回 false
+func hueOf(r, g, b float64) (float64, bool) {
+	maxC := max(r, g, b)
+	minC := min(r, g, b)
+	delta := maxC - minC
+	if maxC == 0 || delta/maxC < 0.2 {
+		return 0, false
+	}
+
+	var hue float64
+	switch maxC {
+	case r:
+		hue = 60 * (g - b) / delta
+	case g:
+		hue = 60*(b-r)/delta + 120
+	default:
+		hue = 60*(r-g)/delta + 240
+	}
+	if hue < 0 {
+		hue += 360
+	}
+	return hue, true
+}
+
+var colorNames = map[bool][6]string{
+	true:  {"红色", "黄色", "绿色", "青色", "蓝色", "品红"},
+	false: {"red", "yellow", "green", "cyan", "blue", "magenta"},
+}
+
+func (s imageStats) dominantHue() (int, float64) {
+	best := 0
+	for i, share := range s.hues {
+		if share > s.hues[best] {
+			best = i
+		}
+	}
+	return best, s.hues[best]
+}
+
+func (s imageStats) colorName(language string) string {
+	zh := isChinese(language)
+	index, share := s.dominantHue()
+	if share == 0 {
+		if zh {
+			return "灰阶"
+		}
+		return "grayscale"
+	}
+	return colorNames[zh][index]
+}
+
+func (s imageStats) orientation(language string) string {
+	zh := isChinese(language)
+	switch {
+	case s.width > s.height && zh:
+		return "横向构图"
+	case s.width > s.height:
+		return "landscape"
+	case s.width < s.height && zh:
+		return "纵向构图"
+	case s.width < s.height:
+		return "portrait"
+	case zh:
+		return "方形构图"
+	default:
+		return "square"
+	}
+}
+
+func (s imageStats) bullets(t text) []string {
+	index, share := s.dominantHue()
+	return []string{
+		fmt.Sprintf(t.bulletSize, s.width, s.height),
+		fmt.Sprintf(t.bulletColor, s.average[0], s.average[1], s.average[2]),
+		fmt.Sprintf(t.bulletHue, colorNames[t.zh][index], share*100),
+		fmt.Sprintf(t.bulletBrightness, s.brightness*100),
+	}
+}
+
+func (s imageStats) imagePrompt(style string) string {
+	return fmt.Sprintf("abstract gradient based on #%02x%02x%02x, %s", s.average[0], s.average[1], s.average[2], style)
+}
+
+// rotate 循环移动要点顺序，使各内容页不完全相同
+func rotate(items []string, n int) []string {
+	out := make([]string, len(items))
+	for i := range items {
+		out[i] = items[(i+n)%len(items)]
+	}
+	return out
+}
+
+type text struct {
+	zh               bool
+	title            string
+	subtitle         string
+	section          string
+	summary          string
+	cover            string
+	notes            string
+	revised          string
+	bulletSize       string
+	bulletColor      string
+	bulletHue        string
+	bulletBrightness string
+}
+
+func texts(language string) text {
+	if isChinese(language) {
+		return text{
+			zh:               true,
+			title:            "%s主调的图片概览",
+			subtitle:         "%d×%d，%s",
+			section:          "要点 %d",
+			summary:          "总结",
+			cover:            "共 %d 页",
+			notes:            "由离线桩分析器生成（内容摘要 %s）",
+			revised:          "已按修改意见调整：%s",
+			bulletSize:       "尺寸 %d×%d 像素",
+			bulletColor:      "平均色 RGB(%d, %d, %d)",
+			bulletHue:        "主色相为%s，占彩色像素 %.0f%%",
+			bulletBrightness: "平均亮度 %.0f%%",
+		}
+	}
+	return text{
+		title:            "An image in %s tones",
+		subtitle:         "%d×%d, %s",
+		section:          "Key point %d",
+		summary:          "Summary",
+		cover:            "%d slides",
+		notes:            "Generated by the offline stub analyzer (digest %s)",
+		revised:          "Revised as requested: %s",
+		bulletSize:       "Size %d×%d pixels",
+		bulletColor:      "Average color RGB(%d, %d, %d)",
+		bulletHue:        "Dominant hue is %s, %.0f%% of colored pixels",
+		bulletBrightness: "Average brightness %.0f%%",
+	}
+}
+
+func isChinese(language string) bool {
+	return strings.HasPrefix(strings.ToLower(language), "zh")
+}
@@ -0,0 +1,122 @@
+package stub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// 桩配图尺寸，16:9
+const (
+	imageWidth  = 960
+	imageHeight = 540
+)
+
+// ImageGenerator 离线桩实现：由提示词摘要确定配色，绘制渐变背景与几何图形，不访问网络
+type ImageGenerator struct{}
+
+// NewImageGenerator 忽略所有配置项
+func NewImageGenerator(provider.Settings) (provider.ImageGenerator, error) {
+	return &ImageGenerator{}, nil
+}
+
+func (g *ImageGenerator) Model() string {
+	return "stub"
+}
+
+// GenerateSlideImage 相同的提示词、风格与参考图总是生成相同的图片；使用参考图时以其平均色作为起始色
+func (g *ImageGenerator) GenerateSlideImage(ctx context.Context, prompt string, refImage []byte, style, fidelity string) (*provider.GeneratedImage, error) {
+	seed := sha256.Sum256([]byte(style + "\x00" + prompt))
+
+	from := color.RGBA{seed[0], seed[1], seed[2], 255}
+	to := color.RGBA{seed[3], seed[4], seed[5], 255}
+	if len(refImage) > 0 && fidelity != "" && fidelity != provider.FidelityNone {
+		avg := analyze(refImage).average
+		from = color.RGBA{avg[0], avg[1], avg[2], 255}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	drawGradient(img, from, to)
+
+	// 3-5 个半透明圆形与矩形，位置和大小由摘要决定
+	shapes := 3 + int(seed[6])%3
+	for i := 0; i < shapes; i++ {
+		b := seed[7+i*4 : 11+i*4]
+		x := int(b[0]) * imageWidth / 256
+		y := int(b[1]) * imageHeight / 256
+		size := 40 + int(b[2])%160
+		fill := color.RGBA{255 - from.R, 255 - from.G, 255 - from.B, 96}
+		if b[3]%2 == 0 {
+			drawCircle(img, x, y, size/2, fill)
+		} else {
+			drawRect(img, image.Rect(x-size/2, y-size/2, x+size/2, y+size/2), fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeImageGenAPI, "failed to encode stub image")
+	}
+	return &provider.GeneratedImage{Bytes: buf.Bytes()}, nil
+}
+
+// drawGradient 从左上到右下的线性渐变
+func drawGradient(img *image.RGBA, from, to color.RGBA) {
+	bounds := img.Bounds()
+	span := bounds.Dx() + bounds.Dy()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			t := float64(x+y) / float64(span)
+			img.SetRGBA(x, y, color.RGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: 255,
+			})
+		}
+	}
+}
+
+func drawCircle(img *image.RGBA, cx, cy, r int, fill color.RGBA) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r {
+				blend(img, x, y, fill)
+			}
+		}
+	}
+}
+
+func drawRect(img *image.RGBA, rect image.Rectangle, fill color.RGBA) {
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			blend(img, x, y, fill)
+		}
+	}
+}
+
+// blend 按 fill 的透明度与底色混合，超出画布的点忽略
+func blend(img *image.RGBA, x, y int, fill color.RGBA) {
+	if !(image.Point{x, y}).In(img.Bounds()) {
+		return
+	}
+	base := img.RGBAAt(x, y)
+	t := float64(fill.A) / 255
+	img.SetRGBA(x, y, color.RGBA{
+		R: lerp(base.R, fill.R, t),
+		G: lerp(base.G, fill.G, t),
+		B: lerp(base.B, fill.B, t),
+		A: 255,
+	})
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
@@ -0,0 +1,253 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// DateLayout 账本与导出使用的日期格式（UTC）
+const DateLayout = "2006-01-02"
+
+// 账本记录的请求状态，与任务状态一致
+const (
+	StatusSucceeded = "SUCCEEDED"
+	StatusFailed    = "FAILED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Record 账本中的一条记录：一次请求在某个模型、某个 API Key 下的用量
+type Record struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	ClientID  string    `json:"client_id,omitempty"`
+	// Status 请求的最终状态，失败请求已产生的调用同样计费
+	Status string `json:"status"`
+	ModelUsage
+}
+
+// Ledger 用量账本，记录按 client_id 隔离，客户端只能查询自己的用量
+type Ledger interface {
+	Append(records []Record) error
+	// List 返回 clientID 在 [from, to) 区间内的记录，按时间排序；from/to 零值表示不限
+	List(clientID string, from, to time.Time) ([]Record, error)
+}
+
+// NewLedger 按类型创建账本：memory 或 file
+func NewLedger(ledgerType, path string) (Ledger, error) {
+	switch ledgerType {
+	case "", "memory":
+		return NewMemoryLedger(), nil
+	case "file":
+		return NewFileLedger(path)
+	default:
+		return nil, fmt.Errorf("unknown usage store type %q", ledgerType)
+	}
+}
+
+// MemoryLedger 进程内账本，重启后丢失
+type MemoryLedger struct {
+	mu      sync.RWMutex
+	records []Record
+}
+
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{}
+}
+
+func (l *MemoryLedger) Append(records []Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, records...)
+	return nil
+}
+
+func (l *MemoryLedger) List(clientID string, from, to time.Time) ([]Record, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var records []Record
+	for _, record := range l.records {
+		if record.ClientID == clientID && inRange(record.Time, from, to) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// FileLedger 按 UTC 日期每天一个 JSONL 文件（usage-2006-01-02.jsonl），只追加写入
+type FileLedger struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileLedger(dir string) (*FileLedger, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("usage store path is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create usage store directory: %w", err)
+	}
+	return &FileLedger{dir: dir}, nil
+}
+
+func (l *FileLedger) Append(records []Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	byDay := make(map[string][]Record)
+	for _, record := range records {
+		day := record.Time.UTC().Format(DateLayout)
+		byDay[day] = append(byDay[day], record)
+	}
+
+	for day, dayRecords := range byDay {
+		f, err := os.OpenFile(l.path(day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.Wrap(err, errors.ErrCodeStorage, "failed to open usage file")
+		}
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		for _, record := range dayRecords {
+			if err := enc.Encode(record); err != nil {
+				f.Close()
+				return errors.Wrap(err, errors.ErrCodeStorage, "failed to write usage record")
+			}
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return errors.Wrap(err, errors.ErrCodeStorage, "failed to write usage record")
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrap(err, errors.ErrCodeStorage, "failed to write usage record")
+		}
+	}
+	return nil
+}
+
+func (l *FileLedger) List(clientID string, from, to time.Time) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to list usage files")
+	}
+
+	var records []Record
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "usage-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.Parse(DateLayout, strings.TrimSuffix(strings.TrimPrefix(name, "usage-"), ".jsonl"))
+		if err != nil {
+			continue
+		}
+		// 跳过与查询区间不相交的日期文件
+		if (!to.IsZero() && !day.Before(to)) || (!from.IsZero() && !day.Add(24*time.Hour).After(from)) {
+			continue
+		}
+
+		dayRecords, err := l.read(filepath.Join(l.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range dayRecords {
+			if record.ClientID == clientID && inRange(record.Time, from, to) {
+				records = append(records, record)
+			}
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+func (l *FileLedger) read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to open usage file")
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		// 进程异常退出可能留下半行，跳过
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read usage file")
+	}
+	return records, nil
+}
+
+func (l *FileLedger) path(day string) string {
+	return filepath.Join(l.dir, "usage-"+day+".jsonl")
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+// csvHeader 每日导出的列，供财务对账
+var csvHeader = []string{
+	"date", "time", "request_id", "client_id", "status", "key_id", "model",
+	"calls", "prompt_tokens", "candidate_tokens", "total_tokens", "images", "estimated_cost_usd",
+}
+
+// WriteCSV 将记录按行导出为 CSV，时间为 UTC
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		t := r.Time.UTC()
+		if err := cw.Write([]string{
+			t.Format(DateLayout),
+			t.Format(time.RFC3339),
+			r.RequestID,
+			r.ClientID,
+			r.Status,
+			r.KeyID,
+			r.Model,
+			strconv.Itoa(r.Calls),
+			strconv.Itoa(r.PromptTokens),
+			strconv.Itoa(r.CandidateTokens),
+			strconv.Itoa(r.TotalTokens),
+			strconv.Itoa(r.Images),
+			strconv.FormatFloat(r.Cost, 'f', 6, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,161 @@
+package usage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func record(requestID, clientID string, at time.Time, cost float64) Record {
+	return Record{
+		Time:      at,
+		RequestID: requestID,
+		ClientID:  clientID,
+		Status:    StatusSucceeded,
+		ModelUsage: ModelUsage{
+			Model:  "gemini-pro",
+			KeyID:  "key-a",
+			Totals: Totals{Calls: 1, PromptTokens: 1000, CandidateTokens: 200, TotalTokens: 1200, Cost: cost},
+		},
+	}
+}
+
+func TestFileLedgerAcrossDays(t *testing.T) {
+	dir := t.TempDir()
+	ledger, err := NewFileLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 东八区 1 月 2 日 07:30 仍是 UTC 1 月 1 日
+	beijing := time.FixedZone("CST", 8*3600)
+	late := time.Date(2026, 1, 2, 7, 30, 0, 0, beijing)
+	early := time.Date(2026, 1, 2, 0, 30, 0, 0, time.UTC)
+	if err := ledger.Append([]Record{
+		record("req-2", "client-a", early, 0.002),
+		record("req-1", "client-a", late, 0.001),
+		record("req-3", "client-b", early, 0.003),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"usage-2026-01-01.jsonl", "usage-2026-01-02.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	records, err := ledger.List("client-a", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].RequestID != "req-1" || records[1].RequestID != "req-2" {
+		t.Fatalf("records = %+v, want both client-a records in time order", records)
+	}
+	if got := records[0]; !got.Time.Equal(late) || got.Cost != 0.001 || got.PromptTokens != 1000 || got.KeyID != "key-a" {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	// [from, to) 左闭右开
+	records, err = ledger.List("client-a", early, early.Add(time.Hour))
+	if err != nil || len(records) != 1 || records[0].RequestID != "req-2" {
+		t.Errorf("records from %s = %+v, %v", early, records, err)
+	}
+	records, err = ledger.List("client-a", time.Time{}, early)
+	if err != nil || len(records) != 1 || records[0].RequestID != "req-1" {
+		t.Errorf("records before %s = %+v, %v", early, records, err)
+	}
+}
+
+func TestFileLedgerSkipsDaysOutsideRange(t *testing.T) {
+	dir := t.TempDir()
+	ledger, err := NewFileLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	if err := ledger.Append([]Record{record("req-1", "client-a", at, 0.001)}); err != nil {
+		t.Fatal(err)
+	}
+	// 记在其他日期文件中的记录：只按文件名判断日期时不会被读取
+	misfiled := filepath.Join(dir, "usage-2026-03-01.jsonl")
+	data, err := os.ReadFile(filepath.Join(dir, "usage-2026-03-05.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(misfiled, bytes.ReplaceAll(data, []byte("req-1"), []byte("req-misfiled")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ledger.List("client-a", at.Add(-time.Hour), at.Add(time.Hour))
+	if err != nil || len(records) != 1 || records[0].RequestID != "req-1" {
+		t.Errorf("records = %+v, %v, want usage-2026-03-01.jsonl skipped", records, err)
+	}
+	// 不限区间时所有日期文件都会读取
+	if records, _ := ledger.List("client-a", time.Time{}, time.Time{}); len(records) != 2 {
+		t.Errorf("records without range = %d, want 2", len(records))
+	}
+}
+
+func TestFileLedgerSkipsHalfLine(t *testing.T) {
+	dir := t.TempDir()
+	ledger, err := NewFileLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	if err := ledger.Append([]Record{record("req-1", "client-a", at, 0.001)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟进程在写入途中退出，之后继续追加
+	f, err := os.OpenFile(filepath.Join(dir, "usage-2026-03-05.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-03-05T12:30:00Z","request_id":"req-torn","client_id":"client-a","sta` + "\n")
+	f.Close()
+	if err := ledger.Append([]Record{record("req-2", "client-a", at.Add(time.Hour), 0.002)}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ledger.List("client-a", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].RequestID != "req-1" || records[1].RequestID != "req-2" {
+		t.Errorf("records = %+v, want the half line skipped", records)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	beijing := time.FixedZone("CST", 8*3600)
+	records := []Record{
+		record("req-1", "client-a", time.Date(2026, 1, 2, 7, 30, 0, 0, beijing), 0.0123456789),
+		record("req-2", "client-a", time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC), 1.5),
+	}
+	records[1].Images = 2
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || len(rows[0]) != len(csvHeader) || rows[0][0] != "date" || rows[0][12] != "estimated_cost_usd" {
+		t.Fatalf("rows = %v", rows)
+	}
+	want := []string{"2026-01-01", "2026-01-01T23:30:00Z", "req-1", "client-a", StatusSucceeded, "key-a", "gemini-pro", "1", "1000", "200", "1200", "0", "0.012346"}
+	for i, v := range want {
+		if rows[1][i] != v {
+			t.Errorf("row 1 column %s = %q, want %q", csvHeader[i], rows[1][i], v)
+		}
+	}
+	if rows[2][11] != "2" || rows[2][12] != "1.500000" {
+		t.Errorf("row 2 = %v", rows[2])
+	}
+}
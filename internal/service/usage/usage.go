@@ -0,0 +1,194 @@
+package usage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+// Price 模型单价（美元），token 按每百万计价，配图按张计价
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+	PerImage         float64
+}
+
+// Pricing 以模型名为键的价格表，未配置的模型费用记为 0
+type Pricing map[string]Price
+
+// Cost 估算一次调用的费用
+func (p Pricing) Cost(u provider.Usage) float64 {
+	price, ok := p[u.Model]
+	if !ok {
+		return 0
+	}
+	return float64(u.PromptTokens)*price.InputPerMillion/1e6 +
+		float64(u.CandidateTokens)*price.OutputPerMillion/1e6 +
+		float64(u.Images)*price.PerImage
+}
+
+// Totals 累计用量与估算费用
+type Totals struct {
+	Calls           int     `json:"calls"`
+	PromptTokens    int     `json:"prompt_tokens"`
+	CandidateTokens int     `json:"candidate_tokens"`
+	TotalTokens     int     `json:"total_tokens"`
+	Images          int     `json:"images"`
+	Cost            float64 `json:"estimated_cost_usd"`
+}
+
+func (t *Totals) add(other Totals) {
+	t.Calls += other.Calls
+	t.PromptTokens += other.PromptTokens
+	t.CandidateTokens += other.CandidateTokens
+	t.TotalTokens += other.TotalTokens
+	t.Images += other.Images
+	t.Cost += other.Cost
+}
+
+// ModelUsage 同一模型、同一 API Key 下的累计用量
+type ModelUsage struct {
+	Model string `json:"model"`
+	KeyID string `json:"key_id,omitempty"`
+	Totals
+}
+
+// Summary 一次生成的用量汇总，Models 按模型与 API Key 拆分
+type Summary struct {
+	Totals
+	Models []ModelUsage `json:"models,omitempty"`
+}
+
+// Summarize 按模型与 API Key 合并调用用量并估算费用
+func Summarize(calls []provider.Usage, pricing Pricing) *Summary {
+	summary := &Summary{}
+	index := make(map[string]int)
+	for _, call := range calls {
+		totals := Totals{
+			Calls:           1,
+			PromptTokens:    call.PromptTokens,
+			CandidateTokens: call.CandidateTokens,
+			TotalTokens:     call.TotalTokens,
+			Images:          call.Images,
+			Cost:            pricing.Cost(call),
+		}
+		summary.add(totals)
+
+		key := call.Model + "\x00" + call.KeyID
+		if i, ok := index[key]; ok {
+			summary.Models[i].add(totals)
+			continue
+		}
+		index[key] = len(summary.Models)
+		summary.Models = append(summary.Models, ModelUsage{Model: call.Model, KeyID: call.KeyID, Totals: totals})
+	}
+	return summary
+}
+
+// Tracker 汇总每次生成的用量，写入账本并记录日志
+type Tracker struct {
+	pricing Pricing
+	ledger  Ledger
+	logger  *logger.Logger
+}
+
+func NewTracker(pricing Pricing, ledger Ledger, log *logger.Logger) *Tracker {
+	return &Tracker{
+		pricing: pricing,
+		ledger:  ledger,
+		logger:  log,
+	}
+}
+
+// Settle 结算一次生成：失败或取消的请求已产生的调用同样计入账本
+func (t *Tracker) Settle(requestID, clientID, status string, calls []provider.Usage) *Summary {
+	summary := Summarize(calls, t.pricing)
+	if summary.Calls == 0 {
+		return summary
+	}
+
+	t.logger.Info("request usage",
+		"request_id", requestID,
+		"client_id", clientID,
+		"status", status,
+		"calls", summary.Calls,
+		"prompt_tokens", summary.PromptTokens,
+		"candidate_tokens", summary.CandidateTokens,
+		"total_tokens", summary.TotalTokens,
+		"images", summary.Images,
+		"estimated_cost_usd", summary.Cost,
+	)
+
+	now := time.Now().UTC()
+	records := make([]Record, len(summary.Models))
+	for i, model := range summary.Models {
+		records[i] = Record{
+			Time:       now,
+			RequestID:  requestID,
+			ClientID:   clientID,
+			Status:     status,
+			ModelUsage: model,
+		}
+	}
+	if err := t.ledger.Append(records); err != nil {
+		t.logger.Error("failed to record usage", "request_id", requestID, "error", err)
+	}
+	return summary
+}
+
+// KeyUsage 单个 API Key 在时间段内的累计用量
+type KeyUsage struct {
+	KeyID    string `json:"key_id"`
+	Requests int    `json:"requests"`
+	Totals
+	Models []ModelUsage `json:"models"`
+}
+
+// AggregateByKey 按 API Key 汇总账本记录，每个 Key 下再按模型拆分
+func AggregateByKey(records []Record) []KeyUsage {
+	keys := make(map[string]*KeyUsage)
+	requests := make(map[string]map[string]bool)
+	for _, record := range records {
+		ku, ok := keys[record.KeyID]
+		if !ok {
+			ku = &KeyUsage{KeyID: record.KeyID}
+			keys[record.KeyID] = ku
+			requests[record.KeyID] = make(map[string]bool)
+		}
+		requests[record.KeyID][record.RequestID] = true
+		ku.add(record.Totals)
+
+		found := false
+		for i := range ku.Models {
+			if ku.Models[i].Model == record.Model {
+				ku.Models[i].add(record.Totals)
+				found = true
+				break
+			}
+		}
+		if !found {
+			ku.Models = append(ku.Models, record.ModelUsage)
+		}
+	}
+
+	result := make([]KeyUsage, 0, len(keys))
+	for keyID, ku := range keys {
+		ku.Requests = len(requests[keyID])
+		result = append(result, *ku)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].KeyID < result[j].KeyID
+	})
+	return result
+}
+
+// Sum 合计各 Key 的用量
+func Sum(keys []KeyUsage) Totals {
+	var total Totals
+	for _, key := range keys {
+		total.add(key.Totals)
+	}
+	return total
+}
@@ -0,0 +1,135 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/internal/service/provider"
+)
+
+var testPricing = Pricing{
+	"gemini-pro":  {InputPerMillion: 1.25, OutputPerMillion: 10},
+	"imagen":      {PerImage: 0.04},
+	"mixed-model": {InputPerMillion: 2, OutputPerMillion: 8, PerImage: 0.02},
+}
+
+func costEqual(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+func TestPricingCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage provider.Usage
+		want  float64
+	}{
+		{"tokens", provider.Usage{Model: "gemini-pro", PromptTokens: 2000, CandidateTokens: 500}, 0.0025 + 0.005},
+		{"images", provider.Usage{Model: "imagen", Images: 3}, 0.12},
+		{"tokens and images", provider.Usage{Model: "mixed-model", PromptTokens: 1_000_000, CandidateTokens: 250_000, Images: 2}, 2 + 2 + 0.04},
+		{"unpriced model", provider.Usage{Model: "unknown", PromptTokens: 1000, Images: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testPricing.Cost(tt.usage); !costEqual(got, tt.want) {
+				t.Errorf("Cost = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	calls := []provider.Usage{
+		{Model: "gemini-pro", KeyID: "key-a", PromptTokens: 1000, CandidateTokens: 200, TotalTokens: 1200},
+		{Model: "imagen", KeyID: "key-a", Images: 1},
+		{Model: "gemini-pro", KeyID: "key-a", PromptTokens: 3000, CandidateTokens: 300, TotalTokens: 3300},
+		{Model: "gemini-pro", KeyID: "key-b", PromptTokens: 1000, TotalTokens: 1000},
+	}
+	summary := Summarize(calls, testPricing)
+
+	if summary.Calls != 4 || summary.PromptTokens != 5000 || summary.TotalTokens != 5500 || summary.Images != 1 {
+		t.Errorf("totals = %+v", summary.Totals)
+	}
+	// 4000 输入 + 500 输出 + 1 张配图 + key-b 的 1000 输入
+	if want := 0.005 + 0.005 + 0.04 + 0.00125; !costEqual(summary.Cost, want) {
+		t.Errorf("cost = %v, want %v", summary.Cost, want)
+	}
+	if len(summary.Models) != 3 {
+		t.Fatalf("models = %+v", summary.Models)
+	}
+	if m := summary.Models[0]; m.Model != "gemini-pro" || m.KeyID != "key-a" || m.Calls != 2 || m.PromptTokens != 4000 || !costEqual(m.Cost, 0.01) {
+		t.Errorf("gemini-pro/key-a = %+v", m)
+	}
+	if m := summary.Models[2]; m.KeyID != "key-b" || m.Calls != 1 || !costEqual(m.Cost, 0.00125) {
+		t.Errorf("gemini-pro/key-b = %+v", m)
+	}
+}
+
+func TestTrackerSettle(t *testing.T) {
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := NewMemoryLedger()
+	tracker := NewTracker(testPricing, ledger, log)
+
+	summary := tracker.Settle("req-1", "client-a", StatusFailed, []provider.Usage{
+		{Model: "gemini-pro", KeyID: "key-a", PromptTokens: 2000, CandidateTokens: 500, TotalTokens: 2500},
+		{Model: "imagen", KeyID: "key-a", Images: 2},
+	})
+	if !costEqual(summary.Cost, 0.0075+0.08) {
+		t.Errorf("cost = %v", summary.Cost)
+	}
+
+	// 失败请求已产生的调用同样入账，每个模型一条记录
+	records, err := ledger.List("client-a", time.Time{}, time.Time{})
+	if err != nil || len(records) != 2 {
+		t.Fatalf("records = %+v, %v", records, err)
+	}
+	for _, r := range records {
+		if r.RequestID != "req-1" || r.Status != StatusFailed || r.Time.IsZero() {
+			t.Errorf("record = %+v", r)
+		}
+	}
+	if !costEqual(records[1].Cost, 0.08) {
+		t.Errorf("image record cost = %v", records[1].Cost)
+	}
+
+	// 没有调用时不写账本
+	tracker.Settle("req-2", "client-a", StatusCancelled, nil)
+	if records, _ := ledger.List("client-a", time.Time{}, time.Time{}); len(records) != 2 {
+		t.Errorf("records after empty settle = %d, want 2", len(records))
+	}
+}
+
+func TestAggregateByKey(t *testing.T) {
+	var records []Record
+	add := func(requestID string, call provider.Usage) {
+		summary := Summarize([]provider.Usage{call}, testPricing)
+		records = append(records, Record{RequestID: requestID, ModelUsage: summary.Models[0]})
+	}
+	add("req-1", provider.Usage{Model: "gemini-pro", KeyID: "key-b", PromptTokens: 2000})
+	add("req-1", provider.Usage{Model: "imagen", KeyID: "key-b", Images: 1})
+	add("req-2", provider.Usage{Model: "gemini-pro", KeyID: "key-b", CandidateTokens: 1000})
+	add("req-3", provider.Usage{Model: "imagen", KeyID: "key-a", Images: 3})
+
+	keys := AggregateByKey(records)
+	if len(keys) != 2 || keys[0].KeyID != "key-a" || keys[1].KeyID != "key-b" {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if a := keys[0]; a.Requests != 1 || a.Images != 3 || !costEqual(a.Cost, 0.12) || len(a.Models) != 1 {
+		t.Errorf("key-a = %+v", a)
+	}
+	b := keys[1]
+	if b.Requests != 2 || b.Calls != 3 || !costEqual(b.Cost, 0.0025+0.04+0.01) || len(b.Models) != 2 {
+		t.Errorf("key-b = %+v", b)
+	}
+	if m := b.Models[0]; m.Model != "gemini-pro" || m.Calls != 2 || !costEqual(m.Cost, 0.0125) {
+		t.Errorf("key-b gemini-pro = %+v", m)
+	}
+
+	if total := Sum(keys); total.Calls != 4 || !costEqual(total.Cost, 0.12+0.0525) {
+		t.Errorf("sum = %+v", total)
+	}
+}
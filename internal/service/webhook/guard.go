@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress 连接时发现回调地址位于内网，重试没有意义
+var errPrivateAddress = errors.New("callback address is private")
+
+// reservedPrefixes 除私有、回环、链路本地以外同样不允许回调的地址段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64，可映射到任意 IPv4 地址
+}
+
+// blockedAddr 回调地址解析到内部网络时拒绝，包括云厂商元数据地址 169.254.169.254
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost 解析主机名并确认所有地址都不在内部网络
+func checkHost(ctx context.Context, resolver *net.Resolver, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if blockedAddr(addr) {
+			return fmt.Errorf("callback host %s is a private address", host)
+		}
+		return nil
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve callback host %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("callback host %s has no addresses", host)
+	}
+	for _, addr := range addrs {
+		if blockedAddr(addr) {
+			return fmt.Errorf("callback host %s resolves to private address %s", host, addr)
+		}
+	}
+	return nil
+}
+
+// NewTransport 回调使用的 Transport：在建立连接时再次检查实际连接的地址，
+// 防止提交后 DNS 改指向内网或经重定向访问内网。allowPrivate 为 true 时不检查，仅用于本地开发
+func NewTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid callback address %s: %w", address, err)
+			}
+			if blockedAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+
+	return &http.Transport{
+		// 不经过代理，否则检查的是代理地址而不是回调地址
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
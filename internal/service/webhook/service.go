@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// 回调请求头
+const (
+	HeaderSignature = "X-Img2ppt-Signature"
+	HeaderTimestamp = "X-Img2ppt-Timestamp"
+	HeaderRequestID = "X-Img2ppt-Request-Id"
+)
+
+// Delivery 一次回调投递尝试的记录
+type Delivery struct {
+	JobID      string    `json:"job_id,omitempty"`
+	Attempt    int       `json:"attempt"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+type Options struct {
+	// MaxAttempts 单次回调最多尝试次数（含首次）
+	MaxAttempts int
+	// Backoff 首次重试前的等待时间，之后每次翻倍
+	Backoff       time.Duration
+	DefaultSecret string
+	// ClientSecrets 按 client_id 配置的签名密钥，优先于 DefaultSecret
+	ClientSecrets map[string]string
+	// AllowPrivateNetworks 允许回调内网地址，仅用于本地开发；
+	// 为 false 时 httpclient 需使用 NewTransport(false) 以便在连接时再次检查
+	AllowPrivateNetworks bool
+}
+
+type Service struct {
+	httpClient *httpclient.Client
+	store      Store
+	opts       Options
+	logger     *logger.Logger
+
+	// closing 关闭后不再等待重试
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func New(client *httpclient.Client, store Store, opts Options, log *logger.Logger) *Service {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	return &Service{
+		httpClient: client,
+		store:      store,
+		opts:       opts,
+		logger:     log,
+		closing:    make(chan struct{}),
+	}
+}
+
+// Validate 校验回调地址，拒绝解析到内网的主机，并确认该客户端配置了签名密钥
+func (s *Service) Validate(ctx context.Context, clientID, callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New(errors.ErrCodeInvalidReq, "callback_url must be an absolute http(s) URL")
+	}
+	if !s.opts.AllowPrivateNetworks {
+		if err := checkHost(ctx, net.DefaultResolver, u.Hostname()); err != nil {
+			return errors.Wrap(err, errors.ErrCodeInvalidReq, "callback_url must resolve to a public address")
+		}
+	}
+	if _, ok := s.secret(clientID); !ok {
+		return errors.New(errors.ErrCodeInvalidReq, "no webhook secret configured for client")
+	}
+	return nil
+}
+
+// Deliver 在后台投递回调，网络错误或 5xx 时按指数退避重试，每次尝试都会被记录
+func (s *Service) Deliver(jobID, requestID, clientID, callbackURL string, payload []byte) {
+	secret, ok := s.secret(clientID)
+	if !ok {
+		s.logger.Warn("skip webhook without secret", "request_id", requestID, "client_id", clientID)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.deliver(jobID, requestID, clientID, callbackURL, secret, payload)
+	}()
+}
+
+// Deliveries 返回该客户端某个请求的全部投递记录
+func (s *Service) Deliveries(clientID, requestID string) ([]Delivery, error) {
+	return s.store.List(clientID, requestID)
+}
+
+// Close 取消尚未开始的重试，并等待正在发送的请求结束
+func (s *Service) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	s.wg.Wait()
+}
+
+func (s *Service) deliver(jobID, requestID, clientID, callbackURL, secret string, payload []byte) {
+	backoff := s.opts.Backoff
+	for attempt := 1; attempt <= s.opts.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-s.closing:
+				s.logger.Warn("webhook retries abandoned on shutdown", "request_id", requestID, "attempt", attempt)
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		record, retryable := s.send(requestID, callbackURL, secret, payload)
+		record.JobID = jobID
+		record.Attempt = attempt
+		if err := s.store.Append(clientID, requestID, record); err != nil {
+			s.logger.Error("failed to record webhook delivery", "request_id", requestID, "error", err)
+		}
+
+		if record.Success {
+			s.logger.Info("webhook delivered",
+				"request_id", requestID,
+				"attempt", attempt,
+				"status", record.StatusCode,
+			)
+			return
+		}
+		s.logger.Warn("webhook delivery failed",
+			"request_id", requestID,
+			"attempt", attempt,
+			"status", record.StatusCode,
+			"error", record.Error,
+		)
+		// 4xx 说明接收方拒绝了这次回调，重试也不会成功
+		if !retryable {
+			return
+		}
+	}
+}
+
+// send 发送一次回调；retryable 表示失败原因为网络错误或 5xx，值得重试
+func (s *Service) send(requestID, callbackURL, secret string, payload []byte) (record Delivery, retryable bool) {
+	record = Delivery{
+		URL:    callbackURL,
+		SentAt: time.Now(),
+	}
+	defer func() {
+		record.DurationMs = time.Since(record.SentAt).Milliseconds()
+	}()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		record.Error = err.Error()
+		return record, false
+	}
+	timestamp := strconv.FormatInt(record.SentAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderRequestID, requestID)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(secret, timestamp, payload))
+
+	resp, err := s.httpClient.Do(ctx, req)
+	if err != nil {
+		var statusErr *httpclient.StatusError
+		if stderrors.As(err, &statusErr) {
+			record.StatusCode = statusErr.StatusCode
+			record.Error = fmt.Sprintf("callback returned %d", statusErr.StatusCode)
+			return record, true
+		}
+		record.Error = err.Error()
+		return record, !stderrors.Is(err, errPrivateAddress)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	record.StatusCode = resp.StatusCode
+	record.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !record.Success {
+		record.Error = fmt.Sprintf("callback returned %d", resp.StatusCode)
+	}
+	return record, resp.StatusCode >= 500
+}
+
+func (s *Service) secret(clientID string) (string, bool) {
+	if secret, ok := s.opts.ClientSecrets[clientID]; ok && secret != "" {
+		return secret, true
+	}
+	if s.opts.DefaultSecret != "" {
+		return s.opts.DefaultSecret, true
+	}
+	return "", false
+}
+
+// Sign 计算回调签名：HMAC-SHA256(secret, timestamp + "." + body)，十六进制编码
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChaseRain/img2ppt/internal/infra/httpclient"
+	"github.com/ChaseRain/img2ppt/internal/infra/logger"
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+func newTestService(t *testing.T, store Store, opts Options) *Service {
+	t.Helper()
+	log, err := logger.New("error", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := httpclient.New(httpclient.Options{
+		Timeout:   5 * time.Second,
+		Transport: NewTransport(opts.AllowPrivateNetworks),
+	})
+	if opts.DefaultSecret == "" {
+		opts.DefaultSecret = "secret"
+	}
+	s := New(client, store, opts, log)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// waitDeliveries 等待后台投递写入 n 条记录
+func waitDeliveries(t *testing.T, s *Service, clientID, requestID string, n int) []Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records, err := s.Deliveries(clientID, requestID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) >= n {
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d deliveries, want %d", len(records), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestValidateRejectsPrivateHosts(t *testing.T) {
+	s := newTestService(t, NewMemoryStore(), Options{})
+
+	for _, callbackURL := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fe80::1]/hook",
+		"ftp://example.com/hook",
+		"/relative",
+	} {
+		if err := s.Validate(context.Background(), "", callbackURL); !errors.Is(err, errors.ErrCodeInvalidReq) {
+			t.Errorf("Validate(%s) = %v, want INVALID_REQUEST", callbackURL, err)
+		}
+	}
+
+	if err := s.Validate(context.Background(), "", "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("Validate(public address) = %v", err)
+	}
+}
+
+func TestDeliverBlocksPrivateAddressAtDial(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// 绕过 Validate 直接投递，模拟提交后 DNS 改指向内网
+	s := newTestService(t, NewMemoryStore(), Options{MaxAttempts: 3, Backoff: time.Millisecond})
+	s.Deliver("job-1", "req-1", "", srv.URL, []byte(`{}`))
+
+	waitDeliveries(t, s, "", "req-1", 1)
+	s.Close()
+	records, _ := s.Deliveries("", "req-1")
+	if len(records) != 1 || records[0].Success {
+		t.Fatalf("deliveries = %+v, want one failed attempt without retries", records)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("private callback was reached %d times", hits.Load())
+	}
+}
+
+func TestDeliverRetriesOnlyServerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		success  bool
+	}{
+		{"5xx then success", []int{500, 503, 200}, 3, true},
+		{"4xx is final", []int{400, 200}, 1, false},
+		{"5xx exhausts attempts", []int{502, 502, 502, 502}, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				want := "sha256=" + Sign("secret", r.Header.Get(HeaderTimestamp), body)
+				if r.Header.Get(HeaderSignature) != want {
+					t.Errorf("signature = %s, want %s", r.Header.Get(HeaderSignature), want)
+				}
+				n := calls.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			s := newTestService(t, NewMemoryStore(), Options{
+				MaxAttempts:          3,
+				Backoff:              time.Millisecond,
+				AllowPrivateNetworks: true,
+			})
+			s.Deliver("job-1", "req-1", "client-a", srv.URL, []byte(`{"status":"SUCCEEDED"}`))
+
+			waitDeliveries(t, s, "client-a", "req-1", tt.attempts)
+			// 等待可能多出的重试
+			time.Sleep(50 * time.Millisecond)
+			records, _ := s.Deliveries("client-a", "req-1")
+			if len(records) != tt.attempts {
+				t.Fatalf("got %d attempts, want %d: %+v", len(records), tt.attempts, records)
+			}
+			last := records[len(records)-1]
+			if last.Success != tt.success || last.StatusCode != tt.statuses[tt.attempts-1] {
+				t.Errorf("last attempt = %+v", last)
+			}
+			if last.JobID != "job-1" || last.Attempt != tt.attempts {
+				t.Errorf("last attempt job/attempt = %s/%d", last.JobID, last.Attempt)
+			}
+		})
+	}
+}
+
+func TestCloseAbandonsBackoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s := newTestService(t, NewMemoryStore(), Options{
+		MaxAttempts:          3,
+		Backoff:              time.Hour,
+		AllowPrivateNetworks: true,
+	})
+	s.Deliver("job-1", "req-1", "", srv.URL, []byte(`{}`))
+	waitDeliveries(t, s, "", "req-1", 1)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close blocked on retry backoff")
+	}
+}
+
+func TestStoreScopesDeliveriesByClient(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() Store{
+		"memory": func() Store { return NewMemoryStore() },
+		"file": func() Store {
+			store, err := NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			if err := store.Append("client-a", "req-1", Delivery{JobID: "job-1", Attempt: 1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Append("client-a", "req-1", Delivery{JobID: "job-1", Attempt: 2, Success: true}); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := store.List("client-a", "req-1")
+			if err != nil || len(records) != 2 || records[1].Attempt != 2 {
+				t.Fatalf("owner sees %+v, %v", records, err)
+			}
+			records, err = store.List("client-b", "req-1")
+			if err != nil || len(records) != 0 {
+				t.Errorf("other client sees %+v, %v", records, err)
+			}
+		})
+	}
+
+	// 文件存储重启后仍可查询
+	reopened, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := reopened.List("client-a", "req-1"); len(records) != 2 {
+		t.Errorf("reopened file store has %d deliveries, want 2", len(records))
+	}
+}
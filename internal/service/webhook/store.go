@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ChaseRain/img2ppt/pkg/errors"
+)
+
+// Store 投递记录存储，记录按 (client_id, request_id) 隔离，客户端只能查询自己的记录
+type Store interface {
+	Append(clientID, requestID string, d Delivery) error
+	List(clientID, requestID string) ([]Delivery, error)
+}
+
+// NewStore 按类型创建投递记录存储：memory 或 file
+func NewStore(storeType, path string) (Store, error) {
+	switch storeType {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown webhook store type %q", storeType)
+	}
+}
+
+// storeKey 客户端与请求 ID 组合成存储键，不同客户端使用相同 request_id 时互不可见
+func storeKey(clientID, requestID string) string {
+	sum := sha256.Sum256([]byte(clientID + "\n" + requestID))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryStore 进程内存储，重启后丢失
+type MemoryStore struct {
+	mu         sync.RWMutex
+	deliveries map[string][]Delivery
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		deliveries: make(map[string][]Delivery),
+	}
+}
+
+func (s *MemoryStore) Append(clientID, requestID string, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := storeKey(clientID, requestID)
+	s.deliveries[key] = append(s.deliveries[key], d)
+	return nil
+}
+
+func (s *MemoryStore) List(clientID, requestID string) ([]Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := s.deliveries[storeKey(clientID, requestID)]
+	out := make([]Delivery, len(records))
+	copy(out, records)
+	return out, nil
+}
+
+// FileStore 每个 (client_id, request_id) 一个 JSONL 文件，只追加写入
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("webhook store path is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create webhook store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Append(clientID, requestID string, d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to encode delivery")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path(clientID, requestID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to open delivery file")
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write delivery")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, errors.ErrCodeStorage, "failed to write delivery")
+	}
+	return nil
+}
+
+func (s *FileStore) List(clientID, requestID string) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path(clientID, requestID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Delivery{}, nil
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to open delivery file")
+	}
+	defer f.Close()
+
+	records := []Delivery{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d Delivery
+		// 进程异常退出可能留下半行，跳过
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			continue
+		}
+		records = append(records, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeStorage, "failed to read delivery file")
+	}
+	return records, nil
+}
+
+func (s *FileStore) path(clientID, requestID string) string {
+	return filepath.Join(s.dir, storeKey(clientID, requestID)+".jsonl")
+}
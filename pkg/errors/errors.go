@@ -8,11 +8,26 @@ const (
 	ErrCodeInternal    = "INTERNAL_ERROR"
 	ErrCodeInvalidReq  = "INVALID_REQUEST"
 	ErrCodeGeminiAPI   = "GEMINI_API_ERROR"
+	ErrCodeOpenAIAPI   = "OPENAI_API_ERROR"
+	ErrCodeOllamaAPI   = "OLLAMA_API_ERROR"
 	ErrCodeImageGenAPI = "IMAGE_GEN_API_ERROR"
 	ErrCodePPTRender   = "PPT_RENDER_ERROR"
 	ErrCodeStorage     = "STORAGE_ERROR"
 	ErrCodeRateLimited = "RATE_LIMITED"
 	ErrCodeNotFound    = "NOT_FOUND"
+	ErrCodeConflict    = "CONFLICT"
+	ErrCodeCancelled   = "CANCELLED"
+
+	// ErrCodeInvalidOutput 模型输出经修复后仍不符合 SlideSpec 约定
+	ErrCodeInvalidOutput = "INVALID_MODEL_OUTPUT"
+	// ErrCodeContentBlocked 提示词或生成结果被模型的安全策略拦截
+	ErrCodeContentBlocked = "CONTENT_BLOCKED"
+	// ErrCodeMaxTokens 输出达到 maxOutputTokens 被截断
+	ErrCodeMaxTokens = "MAX_TOKENS"
+	// ErrCodeForbidden 下载地址签名无效或已过期，或请求体中的 client_id 与鉴权身份不符
+	ErrCodeForbidden = "FORBIDDEN"
+	// ErrCodeUnauthorized 缺少或无效的 API Key
+	ErrCodeUnauthorized = "UNAUTHORIZED"
 )
 
 type AppError struct {
@@ -10,3 +10,26 @@ func RandomString(n int) string {
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)[:n]
 }
+
+// DetectImageMimeType 根据文件头识别图片类型，无法识别时按 JPEG 处理
+func DetectImageMimeType(data []byte) string {
+	if len(data) < 4 {
+		return "application/octet-stream"
+	}
+
+	if data[0] == 0xFF && data[1] == 0xD8 {
+		return "image/jpeg"
+	}
+	if data[0] == 0x89 && data[1] == 0x50 && data[2] == 0x4E && data[3] == 0x47 {
+		return "image/png"
+	}
+	if data[0] == 0x47 && data[1] == 0x49 && data[2] == 0x46 {
+		return "image/gif"
+	}
+	// RIFF 容器第 8-12 字节为 WEBP 才是 WebP
+	if len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		return "image/webp"
+	}
+
+	return "image/jpeg"
+}